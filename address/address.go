@@ -0,0 +1,163 @@
+// Copyright 2019 Spacemesh Authors
+// bech32 account addresses
+
+// Package address derives account addresses from Ed25519 public keys and
+// encodes them as bech32 or bech32m strings with a network specific
+// human-readable prefix.
+//
+// An address is the first Length bytes of the SHA-256 digest of the public
+// key. Since ed25519.ExtractPublicKey recovers the signer's key from a Sign2
+// signature, ExtractAddress identifies the signer of a message without the
+// key being sent.
+package address
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/bech32"
+)
+
+// Length is the size, in bytes, of an address.
+const Length = 20
+
+// Address identifies an account by a truncated hash of its public key.
+type Address [Length]byte
+
+// Variant selects the checksum of the string encoding.
+type Variant = bech32.Variant
+
+const (
+	// Bech32 selects the BIP-173 checksum.
+	Bech32 = bech32.Bech32
+	// Bech32m selects the BIP-350 checksum.
+	Bech32m = bech32.Bech32m
+)
+
+// Network holds the string encoding parameters of a network's addresses.
+type Network struct {
+	// HRP is the lower case human-readable prefix of addresses.
+	HRP string
+	// Variant is the checksum used to encode and required to decode.
+	Variant Variant
+}
+
+var (
+	// Mainnet is the address encoding of the main network.
+	Mainnet = Network{HRP: "sm", Variant: Bech32m}
+	// Testnet is the address encoding of test networks.
+	Testnet = Network{HRP: "stest", Variant: Bech32m}
+)
+
+var (
+	// ErrInvalidChecksum is returned when an address checksum is wrong.
+	ErrInvalidChecksum = bech32.ErrInvalidChecksum
+	// ErrMixedCase is returned for addresses mixing upper and lower case.
+	ErrMixedCase = bech32.ErrMixedCase
+	// ErrMissingSeparator is returned when an address has no '1' separator.
+	ErrMissingSeparator = bech32.ErrMissingSeparator
+	// ErrInvalidPrefix is returned for an empty human-readable prefix.
+	ErrInvalidPrefix = bech32.ErrInvalidHRP
+	// ErrInvalidPadding is returned when the data part does not convert to
+	// whole bytes.
+	ErrInvalidPadding = bech32.ErrInvalidPadding
+)
+
+// CharacterError is returned for characters outside the bech32 alphabet.
+type CharacterError = bech32.CharacterError
+
+// StringLengthError is returned for strings too short or too long to be
+// bech32 encoded.
+type StringLengthError = bech32.LengthError
+
+// NetworkError is returned when an address belongs to another network.
+type NetworkError struct {
+	Expected, Actual string
+}
+
+func (e *NetworkError) Error() string {
+	return "address: wrong network prefix " + strconv.Quote(e.Actual) + ", expected " + strconv.Quote(e.Expected)
+}
+
+// VariantError is returned when an address carries a valid checksum of the
+// wrong variant.
+type VariantError struct {
+	Expected, Actual Variant
+}
+
+func (e *VariantError) Error() string {
+	return "address: " + e.Actual.String() + " checksum, expected " + e.Expected.String()
+}
+
+// LengthError is returned when an address does not decode to Length bytes.
+type LengthError struct {
+	Length int
+}
+
+func (e *LengthError) Error() string {
+	return "address: invalid length " + strconv.Itoa(e.Length) + ", expected " + strconv.Itoa(Length)
+}
+
+// FromPublicKey returns the address of publicKey. It will panic if
+// len(publicKey) is not ed25519.PublicKeySize.
+func FromPublicKey(publicKey ed25519.PublicKey) Address {
+	if l := len(publicKey); l != ed25519.PublicKeySize {
+		panic("address: bad public key length: " + strconv.Itoa(l))
+	}
+	digest := sha256.Sum256(publicKey)
+	var a Address
+	copy(a[:], digest[:Length])
+	return a
+}
+
+// ExtractAddress returns the address of the signer of a Sign2 signature.
+// Like ed25519.ExtractPublicKey it always yields some address for a well
+// formed signature; callers must check it against the expected account.
+func ExtractAddress(message, sig []byte) (Address, error) {
+	publicKey, err := ed25519.ExtractPublicKey(message, sig)
+	if err != nil {
+		return Address{}, err
+	}
+	return FromPublicKey(publicKey), nil
+}
+
+// String returns the hex encoding of the address.
+func (a Address) String() string {
+	return hex.EncodeToString(a[:])
+}
+
+// Encode returns the string encoding of a on network n.
+func (n Network) Encode(a Address) string {
+	s, err := bech32.Encode(n.HRP, a[:], n.Variant)
+	if err != nil {
+		panic(fmt.Sprintf("address: invalid network prefix %q", n.HRP))
+	}
+	return s
+}
+
+// Decode parses an address of network n. Upper case addresses are accepted.
+// Errors identify the first problem found: a *StringLengthError, a
+// *CharacterError, ErrMixedCase, ErrMissingSeparator, ErrInvalidPrefix,
+// ErrInvalidChecksum, ErrInvalidPadding, a *NetworkError, a *VariantError or
+// a *LengthError.
+func (n Network) Decode(s string) (Address, error) {
+	hrp, data, variant, err := bech32.Decode(s, bech32.MaxLength)
+	if err != nil {
+		return Address{}, err
+	}
+	if hrp != n.HRP {
+		return Address{}, &NetworkError{Expected: n.HRP, Actual: hrp}
+	}
+	if variant != n.Variant {
+		return Address{}, &VariantError{Expected: n.Variant, Actual: variant}
+	}
+	if len(data) != Length {
+		return Address{}, &LengthError{Length: len(data)}
+	}
+	var a Address
+	copy(a[:], data)
+	return a, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 Spacemesh Authors
+// bech32 account addresses unit tests
+
+package address
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/bech32"
+)
+
+func testKey(index uint64) ed25519.PrivateKey {
+	seed := make([]byte, ed25519.SeedSize)
+	return ed25519.NewDerivedKeyFromSeed(seed, index, []byte("address"))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	a := FromPublicKey(testKey(1).Public().(ed25519.PublicKey))
+	for _, n := range []Network{Mainnet, Testnet, {HRP: "custom", Variant: Bech32}} {
+		s := n.Encode(a)
+		assert.True(t, strings.HasPrefix(s, n.HRP+"1"), s)
+
+		decoded, err := n.Decode(s)
+		require.NoError(t, err)
+		assert.Equal(t, a, decoded)
+
+		decoded, err = n.Decode(strings.ToUpper(s))
+		require.NoError(t, err)
+		assert.Equal(t, a, decoded)
+	}
+}
+
+func TestFixedAddress(t *testing.T) {
+	var publicKey [ed25519.PublicKeySize]byte
+	a := FromPublicKey(publicKey[:])
+	// the first 20 bytes of SHA-256 of 32 zero bytes
+	assert.Equal(t, "66687aadf862bd776c8fc18b8e9f8e20089714856ee233b3902a591d0d5f2925"[:2*Length], a.String())
+}
+
+func TestExtractAddress(t *testing.T) {
+	privateKey := testKey(2)
+	message := []byte("test message")
+	sig := ed25519.Sign2(privateKey, message)
+
+	a, err := ExtractAddress(message, sig)
+	require.NoError(t, err)
+	assert.Equal(t, FromPublicKey(privateKey.Public().(ed25519.PublicKey)), a)
+
+	other, err := ExtractAddress([]byte("wrong message"), sig)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, other)
+
+	_, err = ExtractAddress(message, sig[:10])
+	assert.Error(t, err)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	a := FromPublicKey(testKey(1).Public().(ed25519.PublicKey))
+	s := Mainnet.Encode(a)
+
+	_, err := Testnet.Decode(s)
+	assert.Equal(t, &NetworkError{Expected: "stest", Actual: "sm"}, err)
+	assert.EqualError(t, err, `address: wrong network prefix "sm", expected "stest"`)
+
+	legacy := Network{HRP: "sm", Variant: Bech32}
+	_, err = legacy.Decode(s)
+	assert.Equal(t, &VariantError{Expected: Bech32, Actual: Bech32m}, err)
+	assert.EqualError(t, err, "address: bech32m checksum, expected bech32")
+
+	// flip the last character of the checksum
+	flipped := byte('q')
+	if s[len(s)-1] == 'q' {
+		flipped = 'p'
+	}
+	_, err = Mainnet.Decode(s[:len(s)-1] + string(flipped))
+	assert.Equal(t, ErrInvalidChecksum, err)
+
+	_, err = Mainnet.Decode(s[:5] + "b" + s[6:])
+	assert.Equal(t, &CharacterError{Pos: 5, Char: 'b'}, err)
+
+	_, err = Mainnet.Decode(strings.ToUpper(s[:10]) + s[10:])
+	assert.Equal(t, ErrMixedCase, err)
+
+	_, err = Mainnet.Decode("smqqqqqqqqqqqqq")
+	assert.Equal(t, ErrMissingSeparator, err)
+
+	short, err := bech32.Encode("sm", a[:Length-1], Bech32m)
+	require.NoError(t, err)
+	_, err = Mainnet.Decode(short)
+	assert.Equal(t, &LengthError{Length: Length - 1}, err)
+
+	_, err = Mainnet.Decode("sm1" + strings.Repeat("q", 100))
+	assert.Equal(t, &StringLengthError{Length: 103, Max: bech32.MaxLength}, err)
+}
@@ -0,0 +1,359 @@
+// Copyright 2019 Spacemesh Authors
+// SSH agent protocol server
+
+// Package agent implements an SSH agent (draft-miller-ssh-agent) holding
+// ssh-ed25519 identities, typically derived from a single seed with
+// ed25519.NewDerivedKeyFromSeed. Signatures are made with ed25519.Sign.
+//
+// The agent supports listing identities, sign requests, adding and removing
+// identities, and locking. Constrained identities, smartcard keys and
+// extensions are answered with a failure. Serve it on a Unix socket and point
+// SSH_AUTH_SOCK at the socket to use it with ssh and ssh-add.
+package agent
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/sshwire"
+)
+
+// Agent protocol message numbers.
+const (
+	msgFailure             = 5
+	msgSuccess             = 6
+	msgRequestIdentities   = 11
+	msgIdentitiesAnswer    = 12
+	msgSignRequest         = 13
+	msgSignResponse        = 14
+	msgAddIdentity         = 17
+	msgRemoveIdentity      = 18
+	msgRemoveAllIdentities = 19
+	msgLock                = 22
+	msgUnlock              = 23
+)
+
+// maxMessageSize bounds the size of agent messages.
+const maxMessageSize = 256 << 10
+
+var (
+	// ErrLocked is returned by operations on a locked agent.
+	ErrLocked = errors.New("agent: locked")
+	// ErrNotLocked is returned when unlocking an agent that is not locked.
+	ErrNotLocked = errors.New("agent: not locked")
+	// ErrAlreadyLocked is returned when locking a locked agent.
+	ErrAlreadyLocked = errors.New("agent: already locked")
+	// ErrBadPassphrase is returned when unlocking with the wrong passphrase.
+	ErrBadPassphrase = errors.New("agent: incorrect passphrase")
+	// ErrKeyNotFound is returned for keys the agent does not hold.
+	ErrKeyNotFound = errors.New("agent: key not found")
+)
+
+// Identity is a public key held by an agent and its comment.
+type Identity struct {
+	PublicKey ed25519.PublicKey
+	Comment   string
+}
+
+type key struct {
+	privateKey ed25519.PrivateKey
+	comment    string
+}
+
+// Agent is an in-memory keyring served over the SSH agent protocol. It is
+// safe for concurrent use.
+type Agent struct {
+	mu         sync.Mutex
+	keys       []key
+	locked     bool
+	passphrase [sha512.Size]byte
+}
+
+// New returns an empty agent.
+func New() *Agent {
+	return &Agent{}
+}
+
+// Add adds privateKey with comment, replacing the comment of a key the agent
+// already holds.
+func (a *Agent) Add(privateKey ed25519.PrivateKey, comment string) error {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return errors.New("agent: bad private key length")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	if i := a.find(ed25519.PublicKey(privateKey[32:])); i >= 0 {
+		a.keys[i].comment = comment
+		return nil
+	}
+	a.keys = append(a.keys, key{privateKey: append(ed25519.PrivateKey(nil), privateKey...), comment: comment})
+	return nil
+}
+
+// AddDerived adds the key derived from seed, index and salt with
+// ed25519.NewDerivedKeyFromSeed and returns its public key.
+func (a *Agent) AddDerived(seed []byte, index uint64, salt []byte, comment string) (ed25519.PublicKey, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("agent: bad seed length")
+	}
+	privateKey := ed25519.NewDerivedKeyFromSeed(seed, index, salt)
+	if err := a.Add(privateKey, comment); err != nil {
+		return nil, err
+	}
+	return ed25519.PublicKey(privateKey[32:]), nil
+}
+
+// find returns the index of the key with publicKey, or -1.
+func (a *Agent) find(publicKey ed25519.PublicKey) int {
+	for i, k := range a.keys {
+		if bytes.Equal(k.privateKey[32:], publicKey) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove removes the key with publicKey.
+func (a *Agent) Remove(publicKey ed25519.PublicKey) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	i := a.find(publicKey)
+	if i < 0 {
+		return ErrKeyNotFound
+	}
+	a.keys = append(a.keys[:i], a.keys[i+1:]...)
+	return nil
+}
+
+// RemoveAll removes all keys.
+func (a *Agent) RemoveAll() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	a.keys = nil
+	return nil
+}
+
+// List returns the identities of the agent in the order they were added. A
+// locked agent has no identities.
+func (a *Agent) List() []Identity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return nil
+	}
+	ids := make([]Identity, len(a.keys))
+	for i, k := range a.keys {
+		ids[i] = Identity{PublicKey: ed25519.PublicKey(k.privateKey[32:]), Comment: k.comment}
+	}
+	return ids
+}
+
+// Sign signs data with the key of publicKey.
+func (a *Agent) Sign(publicKey ed25519.PublicKey, data []byte) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return nil, ErrLocked
+	}
+	i := a.find(publicKey)
+	if i < 0 {
+		return nil, ErrKeyNotFound
+	}
+	return ed25519.Sign(a.keys[i].privateKey, data), nil
+}
+
+// Lock locks the agent with passphrase. A locked agent lists no keys and
+// refuses all requests but Unlock.
+func (a *Agent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrAlreadyLocked
+	}
+	a.locked = true
+	a.passphrase = sha512.Sum512(passphrase)
+	return nil
+}
+
+// Unlock unlocks an agent locked with passphrase.
+func (a *Agent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.locked {
+		return ErrNotLocked
+	}
+	digest := sha512.Sum512(passphrase)
+	if subtle.ConstantTimeCompare(digest[:], a.passphrase[:]) != 1 {
+		return ErrBadPassphrase
+	}
+	a.locked = false
+	a.passphrase = [sha512.Size]byte{}
+	return nil
+}
+
+// Serve accepts connections on l and serves each in its own goroutine until
+// Accept fails.
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = a.ServeConn(conn)
+		}()
+	}
+}
+
+// ServeConn serves agent requests read from rw until it is closed. It
+// returns nil at the end of the input.
+func (a *Agent) ServeConn(rw io.ReadWriter) error {
+	for {
+		req, err := readMessage(rw)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeMessage(rw, a.handle(req)); err != nil {
+			return err
+		}
+	}
+}
+
+func readMessage(r io.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n == 0 || n > maxMessageSize {
+		return nil, errors.New("agent: bad message length")
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeMessage(w io.Writer, msg []byte) error {
+	_, err := w.Write(sshwire.AppendString(nil, msg))
+	return err
+}
+
+var (
+	failure = []byte{msgFailure}
+	success = []byte{msgSuccess}
+)
+
+func status(err error) []byte {
+	if err != nil {
+		return failure
+	}
+	return success
+}
+
+// handle answers a single request.
+func (a *Agent) handle(req []byte) []byte {
+	r := sshwire.NewReader(req[1:])
+	switch req[0] {
+	case msgRequestIdentities:
+		if !r.Empty() {
+			return failure
+		}
+		ids := a.List()
+		resp := sshwire.AppendUint32([]byte{msgIdentitiesAnswer}, uint32(len(ids)))
+		for _, id := range ids {
+			resp = sshwire.AppendString(resp, sshwire.MarshalED25519PublicKey(id.PublicKey))
+			resp = sshwire.AppendString(resp, []byte(id.Comment))
+		}
+		return resp
+
+	case msgSignRequest:
+		blob := r.String()
+		data := r.String()
+		_ = r.Uint32() // flags only select RSA signature hashes
+		if !r.Empty() {
+			return failure
+		}
+		publicKey, ok := sshwire.ParseED25519PublicKey(blob)
+		if !ok {
+			return failure
+		}
+		sig, err := a.Sign(publicKey, data)
+		if err != nil {
+			return failure
+		}
+		sigBlob := sshwire.AppendString(nil, []byte(sshwire.KeyAlgoED25519))
+		sigBlob = sshwire.AppendString(sigBlob, sig)
+		return sshwire.AppendString([]byte{msgSignResponse}, sigBlob)
+
+	case msgAddIdentity:
+		algo := r.String()
+		publicKey := r.String()
+		privateKey := r.String()
+		comment := r.String()
+		if !r.Empty() || string(algo) != sshwire.KeyAlgoED25519 ||
+			len(publicKey) != ed25519.PublicKeySize || len(privateKey) != ed25519.PrivateKeySize {
+			return failure
+		}
+		// the private key is seed || public key; reject inconsistent halves
+		derived := ed25519.NewKeyFromSeed(privateKey[:ed25519.SeedSize])
+		if !bytes.Equal(derived, privateKey) || !bytes.Equal(derived[32:], publicKey) {
+			return failure
+		}
+		return status(a.Add(derived, string(comment)))
+
+	case msgRemoveIdentity:
+		blob := r.String()
+		if !r.Empty() {
+			return failure
+		}
+		publicKey, ok := sshwire.ParseED25519PublicKey(blob)
+		if !ok {
+			return failure
+		}
+		return status(a.Remove(publicKey))
+
+	case msgRemoveAllIdentities:
+		if !r.Empty() {
+			return failure
+		}
+		return status(a.RemoveAll())
+
+	case msgLock, msgUnlock:
+		passphrase := r.String()
+		if !r.Empty() {
+			return failure
+		}
+		if req[0] == msgLock {
+			return status(a.Lock(passphrase))
+		}
+		return status(a.Unlock(passphrase))
+
+	default:
+		return failure
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 Spacemesh Authors
+// SSH agent protocol server unit tests
+
+package agent
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+var testSeed = bytes.Repeat([]byte{0x5e}, ed25519.SeedSize)
+
+// pipe returns a client connected to a in-process agent.
+func pipe(t *testing.T, a *Agent) *Client {
+	c1, c2 := net.Pipe()
+	go a.ServeConn(c2)
+	t.Cleanup(func() { c1.Close() })
+	return NewClient(c1)
+}
+
+func TestDerivedIdentities(t *testing.T) {
+	a := New()
+	var pubs []ed25519.PublicKey
+	for i := uint64(0); i < 3; i++ {
+		pub, err := a.AddDerived(testSeed, i, []byte("ssh"), "derived-"+string(rune('0'+i)))
+		require.NoError(t, err)
+		require.Equal(t, ed25519.NewDerivedKeyFromSeed(testSeed, i, []byte("ssh")).Public(), pub)
+		pubs = append(pubs, pub)
+	}
+
+	c := pipe(t, a)
+	ids, err := c.List()
+	require.NoError(t, err)
+	require.Len(t, ids, 3)
+	for i, id := range ids {
+		require.Equal(t, pubs[i], id.PublicKey)
+		require.Equal(t, "derived-"+string(rune('0'+i)), id.Comment)
+	}
+
+	data := []byte("session identifier and userauth request")
+	sig, err := c.Sign(pubs[1], data)
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(pubs[1], data, sig))
+	require.Equal(t, ed25519.Sign(ed25519.NewDerivedKeyFromSeed(testSeed, 1, []byte("ssh")), data), sig)
+
+	_, other, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, err = c.Sign(other.Public().(ed25519.PublicKey), data)
+	require.ErrorIs(t, err, ErrFailure)
+}
+
+func TestAddRemove(t *testing.T) {
+	c := pipe(t, New())
+	k1 := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, 32))
+	k2 := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, 32))
+	pub1 := k1.Public().(ed25519.PublicKey)
+	pub2 := k2.Public().(ed25519.PublicKey)
+
+	require.NoError(t, c.Add(k1, "one"))
+	require.NoError(t, c.Add(k2, "two"))
+	require.NoError(t, c.Add(k1, "uno"))
+	ids, err := c.List()
+	require.NoError(t, err)
+	require.Equal(t, []Identity{{pub1, "uno"}, {pub2, "two"}}, ids)
+
+	require.NoError(t, c.Remove(pub1))
+	require.ErrorIs(t, c.Remove(pub1), ErrFailure)
+	ids, err = c.List()
+	require.NoError(t, err)
+	require.Equal(t, []Identity{{pub2, "two"}}, ids)
+
+	require.NoError(t, c.RemoveAll())
+	ids, err = c.List()
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	// a private key whose public half does not match its seed
+	bad := append(ed25519.PrivateKey(nil), k1[:32]...)
+	bad = append(bad, pub2...)
+	require.ErrorIs(t, c.Add(bad, "bad"), ErrFailure)
+}
+
+func TestLock(t *testing.T) {
+	a := New()
+	pub, err := a.AddDerived(testSeed, 0, nil, "")
+	require.NoError(t, err)
+	c := pipe(t, a)
+
+	require.ErrorIs(t, c.Unlock([]byte("secret")), ErrFailure)
+	require.NoError(t, c.Lock([]byte("secret")))
+	require.ErrorIs(t, c.Lock([]byte("secret")), ErrFailure)
+
+	ids, err := c.List()
+	require.NoError(t, err)
+	require.Empty(t, ids)
+	_, err = c.Sign(pub, []byte("data"))
+	require.ErrorIs(t, err, ErrFailure)
+	require.ErrorIs(t, c.Add(ed25519.NewKeyFromSeed(testSeed), ""), ErrFailure)
+	require.ErrorIs(t, c.RemoveAll(), ErrFailure)
+
+	require.ErrorIs(t, c.Unlock([]byte("wrong")), ErrFailure)
+	require.NoError(t, c.Unlock([]byte("secret")))
+	ids, err = c.List()
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	_, err = c.Sign(pub, []byte("data"))
+	require.NoError(t, err)
+}
+
+func TestMalformedRequests(t *testing.T) {
+	a := New()
+	_, err := a.AddDerived(testSeed, 0, nil, "")
+	require.NoError(t, err)
+	for _, req := range [][]byte{
+		{msgRequestIdentities, 0},
+		{msgSignRequest},
+		{msgSignRequest, 0, 0, 0, 9, 1},
+		{msgAddIdentity, 0, 0, 0, 0},
+		{msgRemoveIdentity, 0, 0, 0, 1},
+		{msgRemoveAllIdentities, 1},
+		{msgLock},
+		{25}, // add constrained identity
+		{27}, // extension
+		{200},
+	} {
+		require.Equal(t, failure, a.handle(req), "%x", req)
+	}
+
+	// oversized and empty messages end the connection
+	for _, msg := range [][]byte{{0, 0, 0, 0}, {0xff, 0xff, 0xff, 0xff}} {
+		var out bytes.Buffer
+		require.Error(t, a.ServeConn(struct {
+			io.Reader
+			io.Writer
+		}{bytes.NewReader(msg), &out}))
+	}
+}
+
+// TestSSHAdd checks interoperability with OpenSSH's ssh-add when it is
+// installed.
+func TestSSHAdd(t *testing.T) {
+	sshAdd, err := exec.LookPath("ssh-add")
+	if err != nil {
+		t.Skip("ssh-add not found")
+	}
+	a := New()
+	pub, err := a.AddDerived(testSeed, 7, nil, "derived@spacemesh")
+	require.NoError(t, err)
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer l.Close()
+	go a.Serve(l)
+
+	cmd := exec.Command(sshAdd, "-L")
+	cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+sock)
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	require.Equal(t, string(ed25519.MarshalAuthorizedKey(pub, "derived@spacemesh")), string(out))
+}
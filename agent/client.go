@@ -0,0 +1,148 @@
+// Copyright 2019 Spacemesh Authors
+// SSH agent protocol client
+
+package agent
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/sshwire"
+)
+
+// ErrFailure is returned when the agent answers a request with a failure.
+var ErrFailure = errors.New("agent: request failed")
+
+// Client speaks the SSH agent protocol over a connection to an agent, such as
+// a Unix socket named by SSH_AUTH_SOCK. It only handles ssh-ed25519 keys and
+// skips identities of other types. It is safe for concurrent use.
+type Client struct {
+	mu sync.Mutex
+	rw io.ReadWriter
+}
+
+// NewClient returns a client talking to the agent on rw.
+func NewClient(rw io.ReadWriter) *Client {
+	return &Client{rw: rw}
+}
+
+// call sends req and returns the reply.
+func (c *Client) call(req []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := writeMessage(c.rw, req); err != nil {
+		return nil, err
+	}
+	resp, err := readMessage(c.rw)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return resp, err
+}
+
+// callStatus sends a request answered by success or failure.
+func (c *Client) callStatus(req []byte) error {
+	resp, err := c.call(req)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(resp) == 1 && resp[0] == msgSuccess:
+		return nil
+	case len(resp) == 1 && resp[0] == msgFailure:
+		return ErrFailure
+	default:
+		return errors.New("agent: unexpected reply")
+	}
+}
+
+// List returns the ssh-ed25519 identities of the agent.
+func (c *Client) List() ([]Identity, error) {
+	resp, err := c.call([]byte{msgRequestIdentities})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 1 && resp[0] == msgFailure {
+		return nil, ErrFailure
+	}
+	if resp[0] != msgIdentitiesAnswer {
+		return nil, errors.New("agent: unexpected reply")
+	}
+	r := sshwire.NewReader(resp[1:])
+	n := r.Uint32()
+	var ids []Identity
+	for i := uint32(0); i < n && r.Ok(); i++ {
+		blob := r.String()
+		comment := r.String()
+		if publicKey, ok := sshwire.ParseED25519PublicKey(blob); ok {
+			ids = append(ids, Identity{
+				PublicKey: append(ed25519.PublicKey(nil), publicKey...),
+				Comment:   string(comment),
+			})
+		}
+	}
+	if !r.Empty() {
+		return nil, errors.New("agent: malformed identities answer")
+	}
+	return ids, nil
+}
+
+// Sign asks the agent to sign data with the key of publicKey and returns the
+// raw 64-byte signature.
+func (c *Client) Sign(publicKey ed25519.PublicKey, data []byte) ([]byte, error) {
+	req := sshwire.AppendString([]byte{msgSignRequest}, sshwire.MarshalED25519PublicKey(publicKey))
+	req = sshwire.AppendString(req, data)
+	req = sshwire.AppendUint32(req, 0)
+	resp, err := c.call(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 1 && resp[0] == msgFailure {
+		return nil, ErrFailure
+	}
+	if resp[0] != msgSignResponse {
+		return nil, errors.New("agent: unexpected reply")
+	}
+	r := sshwire.NewReader(resp[1:])
+	sr := sshwire.NewReader(r.String())
+	algo := sr.String()
+	sig := sr.String()
+	if !r.Empty() || !sr.Empty() || string(algo) != sshwire.KeyAlgoED25519 || len(sig) != ed25519.SignatureSize {
+		return nil, errors.New("agent: malformed signature")
+	}
+	return append([]byte(nil), sig...), nil
+}
+
+// Add adds privateKey to the agent with comment.
+func (c *Client) Add(privateKey ed25519.PrivateKey, comment string) error {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return errors.New("agent: bad private key length")
+	}
+	req := sshwire.AppendString([]byte{msgAddIdentity}, []byte(sshwire.KeyAlgoED25519))
+	req = sshwire.AppendString(req, privateKey[32:])
+	req = sshwire.AppendString(req, privateKey)
+	req = sshwire.AppendString(req, []byte(comment))
+	return c.callStatus(req)
+}
+
+// Remove removes the key of publicKey from the agent.
+func (c *Client) Remove(publicKey ed25519.PublicKey) error {
+	return c.callStatus(sshwire.AppendString([]byte{msgRemoveIdentity}, sshwire.MarshalED25519PublicKey(publicKey)))
+}
+
+// RemoveAll removes all keys from the agent.
+func (c *Client) RemoveAll() error {
+	return c.callStatus([]byte{msgRemoveAllIdentities})
+}
+
+// Lock locks the agent with passphrase.
+func (c *Client) Lock(passphrase []byte) error {
+	return c.callStatus(sshwire.AppendString([]byte{msgLock}, passphrase))
+}
+
+// Unlock unlocks the agent with passphrase.
+func (c *Client) Unlock(passphrase []byte) error {
+	return c.callStatus(sshwire.AppendString([]byte{msgUnlock}, passphrase))
+}
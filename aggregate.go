@@ -0,0 +1,198 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 half-aggregation of Sign2 signatures
+
+package ed25519
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+)
+
+// Half-aggregation, after Chalkias, Garillot, Kondi and Nikolaenko,
+// "Non-interactive half-aggregation of EdDSA and variants of Schnorr
+// signatures" (https://eprint.iacr.org/2021/350), compresses n signatures
+// (R_i, s_i) into R_1 || ... || R_n || s with s = Σ z_i·s_i mod l. The
+// coefficients z_i hash all the R_i, public keys and messages, and the
+// aggregate is valid if
+//
+//	s·B = Σ z_i·(R_i + k_i·A_i)
+//
+// where k_i = H(R_i || M_i) is the Sign2 challenge.
+//
+// The coefficients must bind the public keys: otherwise a signer could pick
+// its key after the coefficients, as a function of an honest signer's key,
+// and forge an aggregate that includes a message the honest signer never
+// signed. Sign2 challenges do not hash the key, so Aggregate2 extracts the
+// keys from the signatures to derive the coefficients.
+//
+// Aggregates cannot be verified without the public keys, unlike single Sign2
+// signatures. A key is extracted from s_i, which the aggregate no longer has:
+// one equation in n unknown keys does not determine them, and the verifier
+// cannot even compute the coefficients without the keys.
+
+// aggregate2Domain separates the coefficient hash from other uses of SHA-512.
+const aggregate2Domain = "spacemesh ed25519 half-aggregation of Sign2 v1"
+
+// ErrAggregateInput is returned by Aggregate2 for mismatched or empty
+// message and signature lists.
+var ErrAggregateInput = errors.New("ed25519: bad aggregate input")
+
+// Aggregate2SizeOf returns the size, in bytes, of the aggregate of n Sign2
+// signatures.
+func Aggregate2SizeOf(n int) int {
+	return 32 * (n + 1)
+}
+
+// Aggregate2 half-aggregates Sign2 signatures of msgs into
+// Aggregate2SizeOf(len(sigs)) bytes, which VerifyAggregate2 checks against
+// the signers' public keys. It does not check the signatures, whose keys are
+// only known to the verifier. It costs about one ExtractPublicKey per
+// signature.
+func Aggregate2(msgs, sigs [][]byte) ([]byte, error) {
+	n := len(sigs)
+	if n == 0 || len(msgs) != n {
+		return nil, ErrAggregateInput
+	}
+
+	rs := make([][32]byte, n)
+	keys := make([][32]byte, n)
+	for i, sig := range sigs {
+		publicKey, err := ExtractPublicKey(msgs[i], sig)
+		if err != nil {
+			return nil, fmt.Errorf("ed25519: signature %d: %w", i, err)
+		}
+		copy(rs[i][:], sig[:32])
+		copy(keys[i][:], publicKey)
+	}
+
+	z := aggregate2Coefficients(rs, keys, msgs)
+	var s, si [32]byte
+	for i, sig := range sigs {
+		copy(si[:], sig[32:])
+		edwards25519.ScMulAdd(&s, &z[i], &si, &s)
+	}
+
+	agg := make([]byte, 0, Aggregate2SizeOf(n))
+	for i := range rs {
+		agg = append(agg, rs[i][:]...)
+	}
+	return append(agg, s[:]...), nil
+}
+
+// VerifyAggregate2 reports whether agg, from Aggregate2, aggregates Sign2
+// signatures of msgs by publicKeys, in order. It will panic if the length of
+// a public key is not PublicKeySize.
+//
+// VerifyAggregate2 does not always agree with Verify2 on the signatures it
+// aggregates. Verify2 checks its equation exactly, while the random z_i can
+// cancel a small-order term: a signer whose public key or R has a component
+// of order 8 can make a signature that Verify2 rejects, then try messages
+// until its aggregate is accepted, which takes about 8 attempts. Honest
+// keys and signatures have no such components. Where aggregates and single
+// signatures must agree, as in consensus rules, reject public keys and R
+// values of small order or with a torsion component before aggregating.
+func VerifyAggregate2(publicKeys []PublicKey, msgs [][]byte, agg []byte) bool {
+	for _, publicKey := range publicKeys {
+		if l := len(publicKey); l != PublicKeySize {
+			panic("ed25519: bad public key length: " + strconv.Itoa(l))
+		}
+	}
+
+	n := len(publicKeys)
+	if n == 0 || len(msgs) != n || len(agg) != Aggregate2SizeOf(n) {
+		return false
+	}
+
+	var s [32]byte
+	copy(s[:], agg[32*n:])
+	// https://tools.ietf.org/html/rfc8032#section-5.1.7 requires that s be in
+	// the range [0, order) in order to prevent signature malleability.
+	if !edwards25519.ScMinimal(&s) {
+		return false
+	}
+
+	// check s·B - Σ z_i·R_i - Σ z_i·k_i·A_i = 0, with the R_i and A_i negated
+	rs := make([][32]byte, n)
+	keys := make([][32]byte, n)
+	scalars := make([][32]byte, 2*n+1)
+	points := make([]edwards25519.ExtendedGroupElement, 2*n+1)
+	for i := 0; i < n; i++ {
+		copy(rs[i][:], agg[32*i:])
+		copy(keys[i][:], publicKeys[i])
+		R, A := &points[2*i], &points[2*i+1]
+		if !R.FromBytes(&rs[i]) || !A.FromBytes(&keys[i]) {
+			return false
+		}
+		// Verify2 only accepts R in canonical encoding
+		var encodedR [32]byte
+		R.ToBytes(&encodedR)
+		if !bytes.Equal(encodedR[:], rs[i][:]) {
+			return false
+		}
+		edwards25519.FeNeg(&R.X, &R.X)
+		edwards25519.FeNeg(&R.T, &R.T)
+		edwards25519.FeNeg(&A.X, &A.X)
+		edwards25519.FeNeg(&A.T, &A.T)
+	}
+
+	z := aggregate2Coefficients(rs, keys, msgs)
+	var zero [32]byte
+	for i := 0; i < n; i++ {
+		h := sha512.New()
+		h.Write(rs[i][:])
+		h.Write(msgs[i])
+		var digest [64]byte
+		h.Sum(digest[:0])
+		var k [32]byte
+		edwards25519.ScReduce(&k, &digest)
+
+		scalars[2*i] = z[i]
+		edwards25519.ScMulAdd(&scalars[2*i+1], &z[i], &k, &zero)
+	}
+	scalars[2*n] = s
+	edwards25519.GeScalarMultBase(&points[2*n], &[32]byte{1})
+
+	var sum edwards25519.ExtendedGroupElement
+	edwards25519.GeMultiScalarMultVartime(&sum, scalars, points)
+	var encoded, identity [32]byte
+	sum.ToBytes(&encoded)
+	identity[0] = 1
+	return encoded == identity
+}
+
+// aggregate2Coefficients returns z_i = H(T || i) mod l, where T is the hash
+// of all the R_i, public keys and messages.
+func aggregate2Coefficients(rs, keys [][32]byte, msgs [][]byte) [][32]byte {
+	var buf [8]byte
+	h := sha512.New()
+	h.Write([]byte(aggregate2Domain))
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(rs)))
+	h.Write(buf[:])
+	for i := range rs {
+		h.Write(rs[i][:])
+		h.Write(keys[i][:])
+		binary.LittleEndian.PutUint64(buf[:], uint64(len(msgs[i])))
+		h.Write(buf[:])
+		h.Write(msgs[i])
+	}
+	var transcript [64]byte
+	h.Sum(transcript[:0])
+
+	z := make([][32]byte, len(rs))
+	for i := range z {
+		h.Reset()
+		h.Write(transcript[:])
+		binary.LittleEndian.PutUint64(buf[:], uint64(i))
+		h.Write(buf[:])
+		var digest [64]byte
+		h.Sum(digest[:0])
+		edwards25519.ScReduce(&z[i], &digest)
+	}
+	return z
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 half-aggregation unit tests
+
+package ed25519
+
+import (
+	"crypto/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func aggregate2Input(t testing.TB, n int) ([]PublicKey, [][]byte, [][]byte) {
+	publicKeys := make([]PublicKey, n)
+	msgs := make([][]byte, n)
+	sigs := make([][]byte, n)
+	for i := range sigs {
+		public, private, err := GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		publicKeys[i] = public
+		msgs[i] = []byte("message " + strconv.Itoa(i))
+		sigs[i] = Sign2(private, msgs[i])
+	}
+	return publicKeys, msgs, sigs
+}
+
+func TestAggregate2(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 40} {
+		publicKeys, msgs, sigs := aggregate2Input(t, n)
+		agg, err := Aggregate2(msgs, sigs)
+		require.NoError(t, err)
+		require.Len(t, agg, Aggregate2SizeOf(n))
+		assert.True(t, VerifyAggregate2(publicKeys, msgs, agg), "n = %d", n)
+
+		// the R values are kept as they are
+		for i := range sigs {
+			assert.Equal(t, sigs[i][:32], agg[32*i:32*i+32])
+		}
+	}
+}
+
+func TestVerifyAggregate2Rejects(t *testing.T) {
+	publicKeys, msgs, sigs := aggregate2Input(t, 4)
+	agg, err := Aggregate2(msgs, sigs)
+	require.NoError(t, err)
+	require.True(t, VerifyAggregate2(publicKeys, msgs, agg))
+
+	// a different message
+	bad := append([][]byte{}, msgs...)
+	bad[2] = []byte("another message")
+	assert.False(t, VerifyAggregate2(publicKeys, bad, agg))
+
+	// a different or missing key
+	other, _, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := append([]PublicKey{}, publicKeys...)
+	keys[1] = other
+	assert.False(t, VerifyAggregate2(keys, msgs, agg))
+	assert.False(t, VerifyAggregate2(publicKeys[:3], msgs[:3], agg))
+	assert.False(t, VerifyAggregate2(publicKeys[:3], msgs, agg))
+
+	// swapped signers and messages
+	keys = append([]PublicKey{publicKeys[1], publicKeys[0]}, publicKeys[2:]...)
+	bad = append([][]byte{msgs[1], msgs[0]}, msgs[2:]...)
+	assert.False(t, VerifyAggregate2(keys, bad, agg))
+
+	// any flipped bit, in the R values or in s
+	for _, bit := range []int{0, 200, 511, 1024, 1100, 1279} {
+		b := append([]byte{}, agg...)
+		b[bit/8] ^= 1 << (bit % 8)
+		assert.False(t, VerifyAggregate2(publicKeys, msgs, b), "bit %d", bit)
+	}
+
+	// s + l
+	b := append([]byte{}, agg...)
+	var c uint16
+	for i := range scalarOrder {
+		c += uint16(b[128+i]) + uint16(scalarOrder[i])
+		b[128+i] = byte(c)
+		c >>= 8
+	}
+	assert.False(t, VerifyAggregate2(publicKeys, msgs, b))
+
+	assert.False(t, VerifyAggregate2(nil, nil, agg[128:]))
+	assert.False(t, VerifyAggregate2(publicKeys, msgs, agg[:159]))
+	assert.Panics(t, func() { VerifyAggregate2([]PublicKey{other[:31]}, msgs[:1], agg[:64]) })
+}
+
+// TestAggregate2Forged checks that an aggregate that includes a signature
+// which does not verify under its key is rejected.
+func TestAggregate2Forged(t *testing.T) {
+	publicKeys, msgs, sigs := aggregate2Input(t, 3)
+
+	// a Sign2 signature of another message: its extracted key is not the
+	// signer's
+	_, private, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sigs[1] = Sign2(private, msgs[1])
+	agg, err := Aggregate2(msgs, sigs)
+	require.NoError(t, err)
+	assert.False(t, VerifyAggregate2(publicKeys, msgs, agg))
+}
+
+func TestAggregate2Errors(t *testing.T) {
+	_, msgs, sigs := aggregate2Input(t, 2)
+	_, err := Aggregate2(nil, nil)
+	assert.Equal(t, ErrAggregateInput, err)
+	_, err = Aggregate2(msgs[:1], sigs)
+	assert.Equal(t, ErrAggregateInput, err)
+
+	sigs[1] = sigs[1][:63]
+	_, err = Aggregate2(msgs, sigs)
+	assert.Error(t, err)
+}
+
+func BenchmarkAggregate2(b *testing.B) {
+	for _, n := range []int{16, 256} {
+		_, msgs, sigs := aggregate2Input(b, n)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = Aggregate2(msgs, sigs)
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyAggregate2(b *testing.B) {
+	for _, n := range []int{16, 256} {
+		publicKeys, msgs, sigs := aggregate2Input(b, n)
+		agg, err := Aggregate2(msgs, sigs)
+		require.NoError(b, err)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				VerifyAggregate2(publicKeys, msgs, agg)
+			}
+		})
+	}
+}
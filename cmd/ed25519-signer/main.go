@@ -0,0 +1,85 @@
+// Copyright 2019 Spacemesh Authors
+// remote signer daemon
+
+// Command ed25519-signer serves the keys of a configuration file over the
+// HTTP/JSON API of package signer.
+//
+// Usage:
+//
+//	ed25519-signer -config signer.json [-listen unix:/run/ed25519-signer.sock]
+//
+// The listen address is "unix:<path>" or a loopback "host:port". See
+// signer.Config for the configuration format.
+//
+// Only the Unix socket is access-controlled, by its file permissions. A
+// loopback TCP port is open to every local user and process, so without a
+// token_file in the configuration, any of them can sign with every key.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spacemeshos/ed25519/signer"
+)
+
+func main() {
+	config := flag.String("config", "", "key configuration `file`")
+	listen := flag.String("listen", "unix:ed25519-signer.sock", "listen `address`: unix:<path>, or a loopback host:port that any local user can sign through unless the configuration has a token_file")
+	flag.Parse()
+	if *config == "" || flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*config, *listen); err != nil {
+		fmt.Fprintln(os.Stderr, "ed25519-signer:", err)
+		os.Exit(1)
+	}
+}
+
+func run(config, listen string) error {
+	keys, token, err := signer.LoadConfig(config)
+	if err != nil {
+		return err
+	}
+	s, err := signer.NewServer(keys...)
+	if err != nil {
+		return err
+	}
+	s.RequireToken(token)
+	l, err := signer.Listen(listen)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		log.Printf("serving key %q (%x) for %v", k.Name, k.PrivateKey[32:], k.Allow)
+	}
+	log.Printf("listening on %s", listen)
+	if token == "" && !strings.HasPrefix(listen, "unix:") {
+		log.Printf("warning: no token_file, so any local user can sign over TCP")
+	}
+
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan error, 1)
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- srv.Shutdown(ctx)
+	}()
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-done
+}
@@ -0,0 +1,542 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 command-line tool subcommands
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+)
+
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	rand           io.Reader
+	stdinUsed      bool
+}
+
+type command struct {
+	summary string
+	run     func(c *cli, fs *flag.FlagSet, o *options, args []string) (*result, error)
+}
+
+var commands = map[string]command{
+	"keygen":  {"generate a random key pair", (*cli).keygen},
+	"derive":  {"derive a key pair with NewDerivedKeyFromSeed", (*cli).derive},
+	"sign":    {"sign a message with Sign", (*cli).sign},
+	"sign2":   {"sign a message with Sign2", (*cli).sign2},
+	"verify":  {"verify a Sign signature", (*cli).verify},
+	"verify2": {"verify a Sign2 signature", (*cli).verify2},
+	"extract": {"extract the public key of a Sign2 signature", (*cli).extract},
+	"inspect": {"describe a key or signature", (*cli).inspect},
+}
+
+// usageError marks errors caused by bad command lines.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
+
+// errInvalid is returned by the verification commands for bad signatures.
+var errInvalid = errors.New("signature is invalid")
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "usage: ed25519 <command> [flags]")
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  %-8s  %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Run 'ed25519 <command> -h' for the flags of a command.")
+}
+
+// run executes the command line args and returns the process exit code.
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return exitUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		c.usage()
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(c.stderr, "ed25519: unknown command %q\n", args[0])
+		c.usage()
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("ed25519 "+args[0], flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	o := &options{}
+	fs.BoolVar(&o.json, "json", false, "print a JSON object")
+	fs.StringVar(&o.encoding, "encoding", "hex", "output `encoding` of keys and signatures: hex or base64")
+
+	res, err := cmd.run(c, fs, o, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	var uerr *usageError
+	if errors.As(err, &uerr) {
+		fmt.Fprintf(c.stderr, "ed25519 %s: %v\n", args[0], err)
+		fs.Usage()
+		return exitUsage
+	}
+	if err != nil && err != errInvalid {
+		fmt.Fprintf(c.stderr, "ed25519 %s: %v\n", args[0], err)
+		return exitError
+	}
+	if res != nil {
+		if werr := res.write(c.stdout, o); werr != nil {
+			fmt.Fprintf(c.stderr, "ed25519 %s: %v\n", args[0], werr)
+			return exitError
+		}
+	}
+	if err == errInvalid {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// parse parses the command flags, rejecting positional arguments and an
+// unknown output encoding.
+func parse(fs *flag.FlagSet, o *options, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return &usageError{"unexpected argument " + fs.Arg(0)}
+	}
+	if o.encoding != "hex" && o.encoding != "base64" {
+		return &usageError{"unknown encoding " + o.encoding}
+	}
+	return nil
+}
+
+func required(name, value string) error {
+	if value == "" {
+		return &usageError{"missing -" + name}
+	}
+	return nil
+}
+
+func keyPairResult(privateKey ed25519.PrivateKey) *result {
+	return &result{fields: []field{
+		{"seed", privateKey.Seed()},
+		{"private_key", privateKey},
+		{"public_key", privateKey[32:]},
+	}}
+}
+
+func (c *cli) keygen(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	if err := parse(fs, o, args); err != nil {
+		return nil, err
+	}
+	_, privateKey, err := ed25519.GenerateKey(c.rand)
+	if err != nil {
+		return nil, err
+	}
+	return keyPairResult(privateKey), nil
+}
+
+func (c *cli) derive(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	seedArg := fs.String("seed", "", "32-byte `seed`")
+	index := fs.Uint64("index", 0, "key `index`")
+	salt := fs.String("salt", "", "`salt` string")
+	saltHex := fs.String("salt-hex", "", "hex encoded `salt`, instead of -salt")
+	if err := parse(fs, o, args); err != nil {
+		return nil, err
+	}
+	if err := required("seed", *seedArg); err != nil {
+		return nil, err
+	}
+	seed, err := c.value(*seedArg, ed25519.SeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("seed: got %d bytes, want %d", len(seed), ed25519.SeedSize)
+	}
+	saltBytes := []byte(*salt)
+	if *saltHex != "" {
+		if *salt != "" {
+			return nil, &usageError{"-salt and -salt-hex are exclusive"}
+		}
+		if saltBytes, err = hex.DecodeString(*saltHex); err != nil {
+			return nil, fmt.Errorf("salt: %w", err)
+		}
+	}
+	res := keyPairResult(ed25519.NewDerivedKeyFromSeed(seed, *index, saltBytes))
+	res.fields = append(res.fields, field{"index", *index})
+	return res, nil
+}
+
+// privateKey decodes a private key, accepting a 32-byte seed as well.
+func (c *cli) privateKey(arg string) (ed25519.PrivateKey, error) {
+	b, err := c.value(arg, ed25519.PrivateKeySize, ed25519.SeedSize)
+	if err != nil {
+		return nil, fmt.Errorf("key: %w", err)
+	}
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case ed25519.PrivateKeySize:
+		privateKey := ed25519.NewKeyFromSeed(b[:ed25519.SeedSize])
+		if !bytes.Equal(privateKey, b) {
+			return nil, errors.New("key: public half does not match the seed")
+		}
+		return privateKey, nil
+	default:
+		return nil, fmt.Errorf("key: got %d bytes, want %d or %d", len(b), ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+}
+
+func (c *cli) signature(arg string) ([]byte, error) {
+	sig, err := c.value(arg, ed25519.SignatureSize)
+	if err != nil {
+		return nil, fmt.Errorf("sig: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("sig: got %d bytes, want %d", len(sig), ed25519.SignatureSize)
+	}
+	return sig, nil
+}
+
+func (c *cli) publicKey(arg string) (ed25519.PublicKey, error) {
+	pub, err := c.value(arg, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("pub: %w", err)
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("pub: got %d bytes, want %d", len(pub), ed25519.PublicKeySize)
+	}
+	return pub, nil
+}
+
+func (c *cli) doSign(fs *flag.FlagSet, o *options, args []string, sign func(ed25519.PrivateKey, []byte) []byte) (*result, error) {
+	keyArg := fs.String("key", "", "private `key` or seed")
+	msg := messageFlags(fs)
+	if err := parse(fs, o, args); err != nil {
+		return nil, err
+	}
+	if err := required("key", *keyArg); err != nil {
+		return nil, err
+	}
+	privateKey, err := c.privateKey(*keyArg)
+	if err != nil {
+		return nil, err
+	}
+	message, err := c.message(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &result{fields: []field{
+		{"signature", sign(privateKey, message)},
+		{"public_key", privateKey[32:]},
+	}, plain: 1}, nil
+}
+
+func (c *cli) sign(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	return c.doSign(fs, o, args, ed25519.Sign)
+}
+
+func (c *cli) sign2(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	return c.doSign(fs, o, args, ed25519.Sign2)
+}
+
+func (c *cli) doVerify(fs *flag.FlagSet, o *options, args []string, verify func(ed25519.PublicKey, []byte, []byte) bool) (*result, error) {
+	pubArg := fs.String("pub", "", "signer's public `key`")
+	sigArg := fs.String("sig", "", "`signature`")
+	msg := messageFlags(fs)
+	if err := parse(fs, o, args); err != nil {
+		return nil, err
+	}
+	if err := required("pub", *pubArg); err != nil {
+		return nil, err
+	}
+	if err := required("sig", *sigArg); err != nil {
+		return nil, err
+	}
+	publicKey, err := c.publicKey(*pubArg)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := c.signature(*sigArg)
+	if err != nil {
+		return nil, err
+	}
+	message, err := c.message(msg)
+	if err != nil {
+		return nil, err
+	}
+	valid := verify(publicKey, message, sig)
+	res := &result{fields: []field{{"valid", valid}}}
+	if !valid {
+		return res, errInvalid
+	}
+	return res, nil
+}
+
+func (c *cli) verify(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	return c.doVerify(fs, o, args, ed25519.Verify)
+}
+
+func (c *cli) verify2(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	return c.doVerify(fs, o, args, ed25519.Verify2)
+}
+
+func (c *cli) extract(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	sigArg := fs.String("sig", "", "Sign2 `signature`")
+	msg := messageFlags(fs)
+	if err := parse(fs, o, args); err != nil {
+		return nil, err
+	}
+	if err := required("sig", *sigArg); err != nil {
+		return nil, err
+	}
+	sig, err := c.signature(*sigArg)
+	if err != nil {
+		return nil, err
+	}
+	message, err := c.message(msg)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := ed25519.ExtractPublicKey(message, sig)
+	if err != nil {
+		return nil, err
+	}
+	return &result{fields: []field{{"public_key", publicKey}}}, nil
+}
+
+func (c *cli) inspect(fs *flag.FlagSet, o *options, args []string) (*result, error) {
+	valueArg := fs.String("value", "", "key or signature to describe")
+	if err := parse(fs, o, args); err != nil {
+		return nil, err
+	}
+	if err := required("value", *valueArg); err != nil {
+		return nil, err
+	}
+	b, err := c.value(*valueArg, ed25519.PrivateKeySize, ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &result{fields: []field{{"length", len(b)}}}
+	switch len(b) {
+	case 32:
+		// either a seed or a public key
+		var point [32]byte
+		copy(point[:], b)
+		var A edwards25519.ExtendedGroupElement
+		onCurve := A.FromBytes(&point)
+		res.fields = append(res.fields,
+			field{"type", "seed or public key"},
+			field{"valid_point", onCurve},
+			field{"seed_public_key", ed25519.NewKeyFromSeed(b)[32:]},
+		)
+	case 64:
+		// either a private key or a signature
+		if privateKey := ed25519.NewKeyFromSeed(b[:32]); bytes.Equal(privateKey, b) {
+			res.fields = append(res.fields,
+				field{"type", "private key"},
+				field{"seed", b[:32]},
+				field{"public_key", b[32:]},
+			)
+			break
+		}
+		var r, s [32]byte
+		copy(r[:], b[:32])
+		copy(s[:], b[32:])
+		var R edwards25519.ExtendedGroupElement
+		res.fields = append(res.fields,
+			field{"type", "signature"},
+			field{"r", b[:32]},
+			field{"s", b[32:]},
+			field{"valid_r", R.FromBytes(&r)},
+			field{"canonical_s", edwards25519.ScMinimal(&s)},
+		)
+	default:
+		res.fields = append(res.fields, field{"type", "unknown"})
+	}
+	return res, nil
+}
+
+type options struct {
+	json     bool
+	encoding string
+}
+
+type field struct {
+	name  string
+	value interface{}
+}
+
+// result is the output of a command: an ordered list of named values.
+type result struct {
+	fields []field
+	// plain is the number of leading fields printed without -json; all
+	// fields are printed when it is 0.
+	plain int
+}
+
+// write prints the result. Without -json a single value is printed alone and
+// several values as "name: value" lines.
+func (r *result) write(w io.Writer, o *options) error {
+	encode := hex.EncodeToString
+	if o.encoding == "base64" {
+		encode = base64.StdEncoding.EncodeToString
+	}
+	format := func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case []byte:
+			return encode(v)
+		case ed25519.PublicKey:
+			return encode(v)
+		case ed25519.PrivateKey:
+			return encode(v)
+		default:
+			return v
+		}
+	}
+
+	var buf bytes.Buffer
+	if o.json {
+		buf.WriteByte('{')
+		for i, f := range r.fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(f.name)
+			value, err := json.Marshal(format(f.value))
+			if err != nil {
+				return err
+			}
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteString("}\n")
+	} else {
+		fields := r.fields
+		if r.plain > 0 {
+			fields = fields[:r.plain]
+		}
+		if len(fields) == 1 {
+			fmt.Fprintln(&buf, format(fields[0].value))
+		} else {
+			for _, f := range fields {
+				fmt.Fprintf(&buf, "%s: %v\n", f.name, format(f.value))
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type messageOptions struct {
+	in, encoding string
+}
+
+func messageFlags(fs *flag.FlagSet) *messageOptions {
+	m := &messageOptions{}
+	fs.StringVar(&m.in, "in", "-", "message `file`, - for standard input")
+	fs.StringVar(&m.encoding, "in-encoding", "raw", "message `encoding`: raw, hex or base64")
+	return m
+}
+
+func (c *cli) readSource(name string) ([]byte, error) {
+	if name == "-" {
+		if c.stdinUsed {
+			return nil, errors.New("standard input used twice")
+		}
+		c.stdinUsed = true
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+func (c *cli) message(m *messageOptions) ([]byte, error) {
+	b, err := c.readSource(m.in)
+	if err != nil {
+		return nil, fmt.Errorf("message: %w", err)
+	}
+	switch m.encoding {
+	case "raw":
+		return b, nil
+	case "hex":
+		b, err = hex.DecodeString(strings.TrimSpace(string(b)))
+	case "base64":
+		b, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	default:
+		return nil, &usageError{"unknown message encoding " + m.encoding}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("message: %w", err)
+	}
+	return b, nil
+}
+
+// value decodes a key or signature argument: "hex:<hex>", "base64:<base64>",
+// "@<file>" ("@-" for standard input) or bare hex. File contents are decoded
+// as hex or base64, and taken as raw bytes only if they are not text that
+// decodes to one of the rawSizes but are themselves of one of them.
+func (c *cli) value(arg string, rawSizes ...int) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(arg, "hex:"):
+		return hex.DecodeString(arg[len("hex:"):])
+	case strings.HasPrefix(arg, "base64:"):
+		return base64.StdEncoding.DecodeString(arg[len("base64:"):])
+	case strings.HasPrefix(arg, "@"):
+		b, err := c.readSource(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		text := strings.TrimSpace(string(b))
+		decoded, err := hex.DecodeString(text)
+		if err != nil {
+			decoded, err = base64.StdEncoding.DecodeString(text)
+		}
+		switch {
+		case err == nil && (len(rawSizes) == 0 || hasSize(rawSizes, len(decoded))):
+			return decoded, nil
+		case hasSize(rawSizes, len(b)):
+			return b, nil
+		case err == nil:
+			return decoded, nil
+		}
+		return nil, errors.New("file holds neither raw bytes, hex nor base64")
+	default:
+		return hex.DecodeString(arg)
+	}
+}
+
+func hasSize(sizes []int, n int) bool {
+	for _, size := range sizes {
+		if n == size {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 command-line tool
+
+// Command ed25519 generates and derives keys, signs and verifies messages
+// with Sign/Verify and Sign2/Verify2, and extracts signers' public keys.
+//
+// Usage:
+//
+//	ed25519 <command> [flags]
+//
+// Commands:
+//
+//	keygen    generate a random key pair
+//	derive    derive a key pair with NewDerivedKeyFromSeed
+//	sign      sign a message with Sign
+//	sign2     sign a message with Sign2
+//	verify    verify a Sign signature
+//	verify2   verify a Sign2 signature
+//	extract   extract the public key of a Sign2 signature
+//	inspect   describe a key or signature
+//
+// Keys and signatures are given as hex, "hex:<hex>", "base64:<base64>" or
+// "@<file>"; files may hold raw bytes, hex or base64. Messages are read from
+// the file named by -in, standard input by default. Output is hex, base64 or,
+// with -json, a JSON object.
+//
+// The exit code is 0 on success, 1 if a signature does not verify, 2 on
+// usage errors and 3 on malformed input or I/O errors.
+package main
+
+import (
+	"crypto/rand"
+	"os"
+)
+
+const (
+	exitOK      = 0
+	exitInvalid = 1
+	exitUsage   = 2
+	exitError   = 3
+)
+
+func main() {
+	c := &cli{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		rand:   rand.Reader,
+	}
+	os.Exit(c.run(os.Args[1:]))
+}
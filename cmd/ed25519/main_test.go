@@ -0,0 +1,133 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 command-line tool unit tests
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+type zeroReader struct{}
+
+func (zeroReader) Read(buf []byte) (int, error) {
+	for i := range buf {
+		buf[i] = 0
+	}
+	return len(buf), nil
+}
+
+const (
+	// RFC 8032 section 7.1, test 1
+	testSeed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+	testPub  = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	testSig  = "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+
+	// Sign2 signature of testdata/message.txt by testSeed
+	testSig2 = "f7a8b9e17aa4e56e79fc85e44e97a9d5b534679c0ce7fbda623e542116622e93b84c533eeddce294e18231633fe7e94a83a945e511d2c4ebd13321bad36baa00"
+)
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		exit  int
+	}{
+		{"usage", nil, "", exitUsage},
+		{"unknown_command", []string{"frobnicate"}, "", exitUsage},
+		{"keygen", []string{"keygen"}, "", exitOK},
+		{"keygen_json", []string{"keygen", "-json", "-encoding", "base64"}, "", exitOK},
+		{"keygen_bad_encoding", []string{"keygen", "-encoding", "base32"}, "", exitUsage},
+		{"derive", []string{"derive", "-seed", testSeed, "-index", "7", "-salt", "spacemesh"}, "", exitOK},
+		{"derive_json", []string{"derive", "-seed", "@testdata/seed.hex", "-salt-hex", "00ff", "-json"}, "", exitOK},
+		{"derive_missing_seed", []string{"derive"}, "", exitUsage},
+		{"derive_short_seed", []string{"derive", "-seed", "hex:0011"}, "", exitError},
+		{"sign", []string{"sign", "-key", testSeed}, "", exitOK},
+		{"sign_json", []string{"sign", "-key", "@testdata/seed.hex", "-in", "testdata/message.txt", "-json"}, "", exitOK},
+		{"sign_bad_hex", []string{"sign", "-key", "xyz"}, "", exitError},
+		{"sign2", []string{"sign2", "-key", testSeed, "-in", "testdata/message.txt"}, "", exitOK},
+		{"sign2_hex_message", []string{"sign2", "-key", testSeed, "-in-encoding", "hex"}, "68656c6c6f2c20776f726c64\n", exitOK},
+		{"verify", []string{"verify", "-pub", testPub, "-sig", testSig}, "", exitOK},
+		{"verify_invalid", []string{"verify", "-pub", testPub, "-sig", testSig, "-json"}, "tampered", exitInvalid},
+		{"verify2", []string{"verify2", "-pub", testPub, "-sig", testSig2, "-in", "testdata/message.txt"}, "", exitOK},
+		{"verify2_invalid", []string{"verify2", "-pub", testPub, "-sig", testSig2}, "hello, world!", exitInvalid},
+		{"extract", []string{"extract", "-sig", "hex:" + testSig2}, "hello, world", exitOK},
+		{"extract_json", []string{"extract", "-sig", testSig2, "-in", "testdata/message.txt", "-json", "-encoding", "base64"}, "", exitOK},
+		{"extract_missing_sig", []string{"extract"}, "", exitUsage},
+		{"inspect_public_key", []string{"inspect", "-value", testPub}, "", exitOK},
+		{"inspect_private_key", []string{"inspect", "-value", testSeed + testPub, "-json"}, "", exitOK},
+		{"inspect_signature", []string{"inspect", "-value", testSig2}, "", exitOK},
+		{"inspect_unknown", []string{"inspect", "-value", "base64:AAEC"}, "", exitOK},
+		{"extra_argument", []string{"inspect", "-value", testPub, "extra"}, "", exitUsage},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			c := &cli{
+				stdin:  strings.NewReader(tc.stdin),
+				stdout: &stdout,
+				stderr: &stderr,
+				rand:   zeroReader{},
+			}
+			exit := c.run(tc.args)
+			require.Equal(t, tc.exit, exit, stderr.String())
+
+			got := fmt.Sprintf("exit: %d\n-- stdout --\n%s-- stderr --\n%s", exit, stdout.String(), stderr.String())
+			golden := filepath.Join("testdata", tc.name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(got), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(want), got)
+		})
+	}
+}
+
+func TestValueFromFile(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw")
+	b64 := filepath.Join(dir, "b64")
+	seed := bytes.Repeat([]byte{0x42}, 32)
+	require.NoError(t, os.WriteFile(raw, seed, 0o600))
+	require.NoError(t, os.WriteFile(b64, []byte("QkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkJCQkI=\n"), 0o600))
+
+	c := &cli{}
+	v, err := c.value("@"+raw, 32)
+	require.NoError(t, err)
+	require.Equal(t, seed, v)
+	v, err = c.value("@"+b64, 32)
+	require.NoError(t, err)
+	require.Equal(t, seed, v)
+
+	// a hex seed without a trailing newline is as long as a raw private key
+	seedHex := filepath.Join(dir, "seed.hex")
+	require.NoError(t, os.WriteFile(seedHex, []byte(testSeed), 0o600))
+	v, err = c.value("@"+seedHex, 64, 32)
+	require.NoError(t, err)
+	require.Equal(t, testSeed, hex.EncodeToString(v))
+	var fromFile, fromArg bytes.Buffer
+	c = &cli{stdin: strings.NewReader("hello"), stdout: &fromFile, stderr: &fromFile}
+	require.Equal(t, exitOK, c.run([]string{"sign", "-key", "@" + seedHex}), fromFile.String())
+	c = &cli{stdin: strings.NewReader("hello"), stdout: &fromArg, stderr: &fromArg}
+	require.Equal(t, exitOK, c.run([]string{"sign", "-key", testSeed}))
+	require.Equal(t, fromArg.String(), fromFile.String())
+
+	c = &cli{stdin: strings.NewReader(testSeed)}
+	_, err = c.value("@-", 32)
+	require.NoError(t, err)
+	_, err = c.message(&messageOptions{in: "-", encoding: "raw"})
+	require.Error(t, err, "standard input read twice")
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 Spacemesh Authors
+// base58 encoding unit tests
+
+package converters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBase58(t *testing.T) {
+	for _, tc := range []struct{ hex, b58 string }{
+		{"", ""},
+		{"00", "1"},
+		{"0000", "11"},
+		{"61", "2g"},
+		{"626262", "a3gV"},
+		{"636363", "aPEr"},
+		{"48656c6c6f20576f726c6421", "2NEpo7TZRRrLZSi2U"},
+		{"00000000000000000000", "1111111111"},
+		{"516b6fcd0f", "ABnLTmg"},
+	} {
+		b := mustHex(t, tc.hex)
+		require.Equal(t, tc.b58, base58Encode(b), tc.hex)
+		decoded, err := base58Decode(tc.b58)
+		require.NoError(t, err)
+		require.Equal(t, b, decoded)
+	}
+	for _, s := range []string{"0", "O", "I", "l", "abc+"} {
+		_, err := base58Decode(s)
+		require.Error(t, err, s)
+	}
+}
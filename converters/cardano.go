@@ -0,0 +1,191 @@
+// Copyright 2019 Spacemesh Authors
+// Cardano bech32 and extended keys
+
+package converters
+
+import (
+	"crypto/sha512"
+	"errors"
+	"strings"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/bech32"
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+)
+
+// Cardano human-readable parts (CIP-5).
+const (
+	CardanoPublicKeyHRP          = "ed25519_pk"
+	CardanoSigningKeyHRP         = "ed25519_sk"
+	CardanoExtendedSigningKeyHRP = "ed25519e_sk"
+)
+
+// cardanoMaxLength allows bech32 strings of extended keys with chain codes,
+// beyond the BIP-173 limit.
+const cardanoMaxLength = 1023
+
+// ExtendedPrivateKey is an expanded Ed25519 private key: the secret scalar
+// kL and the nonce prefix kR, as used by Cardano's BIP32-Ed25519 keys, with
+// an optional chain code. Such keys need not come from a seed, so they
+// cannot always be represented as an ed25519.PrivateKey.
+type ExtendedPrivateKey struct {
+	Scalar    [32]byte
+	Prefix    [32]byte
+	ChainCode []byte
+}
+
+// NewExtendedPrivateKey expands privateKey: kL is the clamped first half of
+// SHA-512(seed) and kR the second half. Its signatures are those of
+// ed25519.Sign.
+func NewExtendedPrivateKey(privateKey ed25519.PrivateKey) *ExtendedPrivateKey {
+	digest := sha512.Sum512(privateKey.Seed())
+	digest[0] &= 248
+	digest[31] &= 63
+	digest[31] |= 64
+	k := &ExtendedPrivateKey{}
+	copy(k.Scalar[:], digest[:32])
+	copy(k.Prefix[:], digest[32:])
+	return k
+}
+
+// reducedScalar returns kL mod l, which BIP32-Ed25519 derivation may leave
+// above 2^255.
+func (k *ExtendedPrivateKey) reducedScalar() [32]byte {
+	var wide [64]byte
+	copy(wide[:], k.Scalar[:])
+	var s [32]byte
+	edwards25519.ScReduce(&s, &wide)
+	return s
+}
+
+// Public returns the public key kL·B.
+func (k *ExtendedPrivateKey) Public() ed25519.PublicKey {
+	a := k.reducedScalar()
+	var A edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&A, &a)
+	var publicKey [32]byte
+	A.ToBytes(&publicKey)
+	return publicKey[:]
+}
+
+// Sign signs message with standard Ed25519, verifiable with ed25519.Verify.
+func (k *ExtendedPrivateKey) Sign(message []byte) []byte {
+	a := k.reducedScalar()
+	publicKey := k.Public()
+
+	h := sha512.New()
+	h.Write(k.Prefix[:])
+	h.Write(message)
+	var digest [64]byte
+	h.Sum(digest[:0])
+	var r [32]byte
+	edwards25519.ScReduce(&r, &digest)
+
+	var R edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&R, &r)
+	var encodedR [32]byte
+	R.ToBytes(&encodedR)
+
+	h.Reset()
+	h.Write(encodedR[:])
+	h.Write(publicKey)
+	h.Write(message)
+	h.Sum(digest[:0])
+	var hram [32]byte
+	edwards25519.ScReduce(&hram, &digest)
+
+	var s [32]byte
+	edwards25519.ScMulAdd(&s, &hram, &a, &r)
+
+	signature := make([]byte, ed25519.SignatureSize)
+	copy(signature, encodedR[:])
+	copy(signature[32:], s[:])
+	return signature
+}
+
+// EncodeCardano returns the bech32 encoding of the key under hrp: kL || kR
+// for keys without a chain code, such as "ed25519e_sk", and
+// kL || kR || chain code otherwise, such as "root_xsk" or "addr_xsk".
+func (k *ExtendedPrivateKey) EncodeCardano(hrp string) (string, error) {
+	b := append(k.Scalar[:], k.Prefix[:]...)
+	if k.ChainCode != nil {
+		if len(k.ChainCode) != 32 {
+			return "", errors.New("converters: bad chain code length")
+		}
+		b = append(b, k.ChainCode...)
+	}
+	return bech32.Encode(hrp, b, bech32.Bech32)
+}
+
+func decodeCardano(s string) (string, []byte, error) {
+	hrp, data, v, err := bech32.Decode(s, cardanoMaxLength)
+	if err != nil {
+		return "", nil, err
+	}
+	if v != bech32.Bech32 {
+		return "", nil, errors.New("converters: Cardano keys use bech32, not bech32m")
+	}
+	return hrp, data, nil
+}
+
+// ParseCardanoSigningKey parses a Cardano signing key: a seed under
+// "ed25519_sk", an extended key under "ed25519e_sk", or an extended key with
+// chain code under a prefix ending in "_xsk". The scalar must be a multiple
+// of the cofactor 8, as clamping and BIP32-Ed25519 derivation ensure.
+func ParseCardanoSigningKey(s string) (*ExtendedPrivateKey, error) {
+	hrp, data, err := decodeCardano(s)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hrp == CardanoSigningKeyHRP:
+		if len(data) != ed25519.SeedSize {
+			return nil, errBadSeedLength
+		}
+		return NewExtendedPrivateKey(ed25519.NewKeyFromSeed(data)), nil
+	case hrp == CardanoExtendedSigningKeyHRP && len(data) == 64,
+		strings.HasSuffix(hrp, "_xsk") && len(data) == 96:
+	default:
+		return nil, errors.New("converters: unsupported Cardano key " + hrp)
+	}
+	k := &ExtendedPrivateKey{}
+	copy(k.Scalar[:], data[:32])
+	copy(k.Prefix[:], data[32:64])
+	if len(data) == 96 {
+		k.ChainCode = append([]byte(nil), data[64:]...)
+	}
+	if k.Scalar[0]&7 != 0 {
+		return nil, errors.New("converters: extended key scalar is not a multiple of 8")
+	}
+	return k, nil
+}
+
+// EncodeCardanoSigningKey returns the "ed25519_sk" encoding of the seed of
+// privateKey.
+func EncodeCardanoSigningKey(privateKey ed25519.PrivateKey) string {
+	s, _ := bech32.Encode(CardanoSigningKeyHRP, privateKey.Seed(), bech32.Bech32)
+	return s
+}
+
+// EncodeCardanoPublicKey returns the "ed25519_pk" encoding of publicKey.
+func EncodeCardanoPublicKey(publicKey ed25519.PublicKey) string {
+	s, _ := bech32.Encode(CardanoPublicKeyHRP, publicKey, bech32.Bech32)
+	return s
+}
+
+// ParseCardanoPublicKey parses a Cardano public key: "ed25519_pk" or a
+// prefix ending in "_vk", or a prefix ending in "_xvk" whose chain code is
+// dropped.
+func ParseCardanoPublicKey(s string) (ed25519.PublicKey, error) {
+	hrp, data, err := decodeCardano(s)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case (hrp == CardanoPublicKeyHRP || strings.HasSuffix(hrp, "_vk")) && len(data) == 32,
+		strings.HasSuffix(hrp, "_xvk") && len(data) == 64:
+		return data[:32], nil
+	default:
+		return nil, errors.New("converters: unsupported Cardano key " + hrp)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 Spacemesh Authors
+// Cardano bech32 and extended keys unit tests
+
+package converters
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/bech32"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardanoExtendedKey(t *testing.T) {
+	k := testKey(t)
+	xk := NewExtendedPrivateKey(k)
+	require.Equal(t, k.Public(), xk.Public())
+	for _, message := range []string{"", "r", "cardano transaction body hash"} {
+		require.Equal(t, ed25519.Sign(k, []byte(message)), xk.Sign([]byte(message)))
+	}
+
+	s, err := xk.EncodeCardano(CardanoExtendedSigningKeyHRP)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(s, "ed25519e_sk1"))
+	parsed, err := ParseCardanoSigningKey(s)
+	require.NoError(t, err)
+	require.Equal(t, xk, parsed)
+
+	xk.ChainCode = make([]byte, 32)
+	xk.ChainCode[0] = 1
+	s, err = xk.EncodeCardano("root_xsk")
+	require.NoError(t, err)
+	require.Greater(t, len(s), bech32.MaxLength)
+	parsed, err = ParseCardanoSigningKey(s)
+	require.NoError(t, err)
+	require.Equal(t, xk, parsed)
+
+	// a scalar above 2^255, as BIP32-Ed25519 derivation may produce
+	big := &ExtendedPrivateKey{Scalar: xk.Scalar, Prefix: xk.Prefix}
+	big.Scalar[31] |= 0x80
+	message := []byte("derived key")
+	require.True(t, ed25519.Verify(big.Public(), message, big.Sign(message)))
+
+	bad := *xk
+	bad.Scalar[0] |= 1
+	s, err = bad.EncodeCardano("addr_xsk")
+	require.NoError(t, err)
+	_, err = ParseCardanoSigningKey(s)
+	require.Error(t, err)
+}
+
+// TestCardanoVectors pins the bech32 strings of fixed keys. The root_xsk is
+// the example in the cardano-addresses README. The others were computed
+// independently of this package, from the layouts of CIP-5 and
+// BIP32-Ed25519 (kL || kR || chain code, public key || chain code).
+func TestCardanoVectors(t *testing.T) {
+	const (
+		signingKey  = "ed25519_sk1n4smr800l4dxpw5yft6f9mpvc3zyn3tf0vexjxts8wkqx89w0asq6u85wh"
+		publicKey   = "ed25519_pk16adfsqvzky9t042tlmfujeq88g8wzuhnm2nzxfd0qgdx3ac82ydqarpvg0"
+		extendedKey = "ed25519e_sk1xp7g8pj09qeuksn69mcuqzsp8n7l7fmgmxqvpga9yrcqdyzda98eknc2lc5qkar2w7rgfe65gfgzq4ahguaq8uy0jm668r5jslsplrc55lmx0"
+		rootXsk     = "root_xsk1hqzfzrgskgnpwskxxrv5khs7ess82ecy8za9l5ef7e0afd2849p3zryje8chk39nxtva0sww5me3pzkej4rvd5cae3q3v8eu7556n6pdrp4fdu8nsglynpmcppxxvfdyzdz5gfq3fefjepxhvqspmuyvmvqg8983"
+		rootXvk     = "root_xvk1tendnep7l6yp35h4amr09de3eydu2vqans9hsl7832ysn8k748uz6xr2jmc08q37fxrhszzvvcj6gy69gsjpznjn9jzdwcpqrhcgekcgrmpnf"
+	)
+
+	// the RFC 8032 test key
+	k := testKey(t)
+	require.Equal(t, signingKey, EncodeCardanoSigningKey(k))
+	require.Equal(t, publicKey, EncodeCardanoPublicKey(k.Public().(ed25519.PublicKey)))
+	pub, err := ParseCardanoPublicKey(publicKey)
+	require.NoError(t, err)
+	require.Equal(t, mustHex(t, testPub), []byte(pub))
+
+	xk, err := ParseCardanoSigningKey(signingKey)
+	require.NoError(t, err)
+	require.Equal(t, "307c83864f2833cb427a2ef1c00a013cfdff2768d980c0a3a520f006904de94f", hex.EncodeToString(xk.Scalar[:]))
+	require.Equal(t, "9b4f0afe280b746a778684e75442502057b7473a03f08f96f5a38e9287e01f8f", hex.EncodeToString(xk.Prefix[:]))
+	s, err := xk.EncodeCardano(CardanoExtendedSigningKeyHRP)
+	require.NoError(t, err)
+	require.Equal(t, extendedKey, s)
+	parsed, err := ParseCardanoSigningKey(extendedKey)
+	require.NoError(t, err)
+	require.Equal(t, xk, parsed)
+	require.Equal(t, mustHex(t, testPub), []byte(parsed.Public()))
+
+	// a root key of cardano-addresses, with its chain code
+	root, err := ParseCardanoSigningKey(rootXsk)
+	require.NoError(t, err)
+	require.Equal(t, "b804910d10b2261742c630d94b5e1ecc2075670438ba5fd329f65fd4b547a943", hex.EncodeToString(root.Scalar[:]))
+	require.Equal(t, "110c92c9f17b44b332d9d7c1cea6f3108ad99546c6d31dcc41161f3cf529a9e8", hex.EncodeToString(root.Prefix[:]))
+	require.Equal(t, "2d186a96f0f3823e498778084c6625a413454424114e532c84d760201df08cdb", hex.EncodeToString(root.ChainCode))
+	s, err = root.EncodeCardano("root_xsk")
+	require.NoError(t, err)
+	require.Equal(t, rootXsk, s)
+	rootPub, err := ParseCardanoPublicKey(rootXvk)
+	require.NoError(t, err)
+	require.Equal(t, "5e66d9e43efe8818d2f5eec6f2b731c91bc5301d9c0b787fc78a89099edea9f8", hex.EncodeToString(rootPub))
+	require.Equal(t, rootPub, root.Public())
+}
+
+func TestCardanoBech32(t *testing.T) {
+	k := testKey(t)
+	pub := k.Public().(ed25519.PublicKey)
+
+	s := EncodeCardanoSigningKey(k)
+	require.True(t, strings.HasPrefix(s, "ed25519_sk1"))
+	xk, err := ParseCardanoSigningKey(s)
+	require.NoError(t, err)
+	require.Equal(t, NewExtendedPrivateKey(k), xk)
+
+	s = EncodeCardanoPublicKey(pub)
+	require.True(t, strings.HasPrefix(s, "ed25519_pk1"))
+	parsed, err := ParseCardanoPublicKey(s)
+	require.NoError(t, err)
+	require.Equal(t, pub, parsed)
+
+	// an extended verification key carries a chain code
+	xvk, err := bech32.Encode("acct_xvk", append(append([]byte(nil), pub...), make([]byte, 32)...), bech32.Bech32)
+	require.NoError(t, err)
+	parsed, err = ParseCardanoPublicKey(xvk)
+	require.NoError(t, err)
+	require.Equal(t, pub, parsed)
+
+	for _, s := range []string{
+		EncodeCardanoSigningKey(k), // not a public key
+		s[:len(s)-1] + "q",         // bad checksum
+	} {
+		_, err := ParseCardanoPublicKey(s)
+		require.Error(t, err, s)
+	}
+	_, err = ParseCardanoSigningKey(EncodeCardanoPublicKey(pub))
+	require.Error(t, err)
+}
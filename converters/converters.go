@@ -0,0 +1,38 @@
+// Copyright 2019 Spacemesh Authors
+// Ed25519 key encodings of other ecosystems
+
+// Package converters translates Ed25519 keys between this package's
+// PublicKey and PrivateKey and the encodings of other ecosystems: Solana
+// base58 keys and keypairs, Stellar StrKeys, Cardano bech32 keys including
+// BIP32-Ed25519 extended keys, and libsodium crypto_sign secret keys.
+//
+// Parsers validate checksums, lengths and, where a format carries both key
+// halves, that the public key matches the private key.
+package converters
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+var (
+	// ErrChecksum is returned when a checksum does not match.
+	ErrChecksum = errors.New("converters: invalid checksum")
+
+	errBadSeedLength = errors.New("converters: bad seed length")
+)
+
+// keyPair returns the private key of a 64-byte seed || public key encoding,
+// checking that the public half matches the seed.
+func keyPair(b []byte) (ed25519.PrivateKey, error) {
+	if len(b) != ed25519.PrivateKeySize {
+		return nil, errors.New("converters: bad key pair length")
+	}
+	privateKey := ed25519.NewKeyFromSeed(b[:ed25519.SeedSize])
+	if !bytes.Equal(privateKey, b) {
+		return nil, errors.New("converters: public key does not match the seed")
+	}
+	return privateKey, nil
+}
@@ -0,0 +1,28 @@
+// Copyright 2019 Spacemesh Authors
+// Ed25519 key encodings of other ecosystems unit tests
+
+package converters
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 8032 section 7.1, test 1
+const (
+	testSeed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+	testPub  = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(mustHex(t, testSeed))
+}
@@ -0,0 +1,29 @@
+// Copyright 2019 Spacemesh Authors
+// libsodium crypto_sign key encodings
+
+package converters
+
+import "github.com/spacemeshos/ed25519"
+
+// LibsodiumSecretKey returns the crypto_sign_SECRETKEYBYTES secret key of
+// privateKey. libsodium and this package share the seed || public key
+// layout, so the result is a copy of privateKey.
+func LibsodiumSecretKey(privateKey ed25519.PrivateKey) []byte {
+	return append([]byte(nil), privateKey...)
+}
+
+// ParseLibsodiumSecretKey parses a 64-byte crypto_sign secret key, checking
+// that its public half matches its seed. libsodium itself does not check
+// this, and signs with the stored public key.
+func ParseLibsodiumSecretKey(sk []byte) (ed25519.PrivateKey, error) {
+	return keyPair(sk)
+}
+
+// ParseLibsodiumSeed returns the private key of a crypto_sign_SEEDBYTES
+// seed, as used by crypto_sign_seed_keypair.
+func ParseLibsodiumSeed(seed []byte) (ed25519.PrivateKey, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, errBadSeedLength
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2019 Spacemesh Authors
+// libsodium crypto_sign key encodings unit tests
+
+package converters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibsodium(t *testing.T) {
+	// the RFC 8032 test key in libsodium's layout
+	sk := mustHex(t, testSeed+testPub)
+	k, err := ParseLibsodiumSecretKey(sk)
+	require.NoError(t, err)
+	require.Equal(t, testKey(t), k)
+	require.Equal(t, sk, LibsodiumSecretKey(k))
+
+	sk[40] ^= 1
+	_, err = ParseLibsodiumSecretKey(sk)
+	require.Error(t, err)
+	_, err = ParseLibsodiumSecretKey(sk[:32])
+	require.Error(t, err)
+
+	k, err = ParseLibsodiumSeed(mustHex(t, testSeed))
+	require.NoError(t, err)
+	require.Equal(t, testKey(t), k)
+}
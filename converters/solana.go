@@ -0,0 +1,73 @@
+// Copyright 2019 Spacemesh Authors
+// Solana key encodings
+
+package converters
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+// EncodeSolanaPublicKey returns the base58 Solana address of publicKey.
+func EncodeSolanaPublicKey(publicKey ed25519.PublicKey) string {
+	return base58Encode(publicKey)
+}
+
+// ParseSolanaPublicKey parses a base58 Solana address.
+func ParseSolanaPublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base58Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("converters: bad Solana public key length")
+	}
+	return b, nil
+}
+
+// EncodeSolanaKeypair returns the base58 encoding of the 64-byte keypair,
+// the secret key format of Solana wallets.
+func EncodeSolanaKeypair(privateKey ed25519.PrivateKey) string {
+	return base58Encode(privateKey)
+}
+
+// MarshalSolanaKeypairJSON returns the keypair as a JSON array of 64 byte
+// values, the format of solana-keygen key files.
+func MarshalSolanaKeypairJSON(privateKey ed25519.PrivateKey) []byte {
+	values := make([]int, len(privateKey))
+	for i, b := range privateKey {
+		values[i] = int(b)
+	}
+	data, _ := json.Marshal(values)
+	return data
+}
+
+// ParseSolanaKeypair parses a keypair encoded in base58 or as a
+// solana-keygen JSON array.
+func ParseSolanaKeypair(s string) (ed25519.PrivateKey, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") {
+		b, err := base58Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		return keyPair(b)
+	}
+
+	// a []int, as a []byte would be decoded from base64
+	var values []int
+	if err := json.Unmarshal([]byte(s), &values); err != nil {
+		return nil, err
+	}
+	b := make([]byte, len(values))
+	for i, v := range values {
+		if v < 0 || v > 255 {
+			return nil, errors.New("converters: keypair value out of range")
+		}
+		b[i] = byte(v)
+	}
+	return keyPair(b)
+}
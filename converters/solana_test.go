@@ -0,0 +1,43 @@
+// Copyright 2019 Spacemesh Authors
+// Solana key encodings unit tests
+
+package converters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSolana(t *testing.T) {
+	// the system program address is the all-zero key
+	pub, err := ParseSolanaPublicKey("11111111111111111111111111111111")
+	require.NoError(t, err)
+	require.Equal(t, ed25519.PublicKey(make([]byte, 32)), pub)
+	_, err = ParseSolanaPublicKey("Vote111111111111111111111111111111111111111")
+	require.NoError(t, err)
+	_, err = ParseSolanaPublicKey("1111")
+	require.Error(t, err)
+
+	k := testKey(t)
+	require.Equal(t, "FVen3X669xLzsi6N2V91DoiyzHzg1uAgqiT8jZ9nS96Z", EncodeSolanaPublicKey(k.Public().(ed25519.PublicKey)))
+
+	parsed, err := ParseSolanaKeypair(EncodeSolanaKeypair(k))
+	require.NoError(t, err)
+	require.Equal(t, k, parsed)
+
+	data := MarshalSolanaKeypairJSON(k)
+	require.True(t, strings.HasPrefix(string(data), "[157,97,177,"))
+	parsed, err = ParseSolanaKeypair(string(data) + "\n")
+	require.NoError(t, err)
+	require.Equal(t, k, parsed)
+
+	mismatched := append(ed25519.PrivateKey(nil), k...)
+	mismatched[63] ^= 1
+	_, err = ParseSolanaKeypair(EncodeSolanaKeypair(mismatched))
+	require.Error(t, err)
+	_, err = ParseSolanaKeypair("[1,2,256]")
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 Spacemesh Authors
+// Stellar StrKey encodings
+
+package converters
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+// StrKey version bytes, which make public keys start with 'G' and seeds
+// with 'S'.
+const (
+	stellarVersionPublicKey = 6 << 3
+	stellarVersionSeed      = 18 << 3
+)
+
+var stellarEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// crc16XModem returns the CRC-16/XMODEM checksum of data.
+func crc16XModem(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func encodeStrKey(version byte, payload []byte) string {
+	b := append([]byte{version}, payload...)
+	b = binary.LittleEndian.AppendUint16(b, crc16XModem(b))
+	return stellarEncoding.EncodeToString(b)
+}
+
+func decodeStrKey(version byte, s string) ([]byte, error) {
+	b, err := stellarEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 1+32+2 {
+		return nil, errors.New("converters: bad StrKey length")
+	}
+	if b[0] != version {
+		return nil, errors.New("converters: unexpected StrKey version")
+	}
+	if binary.LittleEndian.Uint16(b[33:]) != crc16XModem(b[:33]) {
+		return nil, ErrChecksum
+	}
+	return b[1:33], nil
+}
+
+// EncodeStellarPublicKey returns the "G..." StrKey of publicKey.
+func EncodeStellarPublicKey(publicKey ed25519.PublicKey) string {
+	return encodeStrKey(stellarVersionPublicKey, publicKey)
+}
+
+// ParseStellarPublicKey parses a "G..." StrKey.
+func ParseStellarPublicKey(s string) (ed25519.PublicKey, error) {
+	return decodeStrKey(stellarVersionPublicKey, s)
+}
+
+// EncodeStellarSeed returns the "S..." StrKey of the seed of privateKey.
+func EncodeStellarSeed(privateKey ed25519.PrivateKey) string {
+	return encodeStrKey(stellarVersionSeed, privateKey.Seed())
+}
+
+// ParseStellarSeed parses an "S..." StrKey.
+func ParseStellarSeed(s string) (ed25519.PrivateKey, error) {
+	seed, err := decodeStrKey(stellarVersionSeed, s)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
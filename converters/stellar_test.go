@@ -0,0 +1,63 @@
+// Copyright 2019 Spacemesh Authors
+// Stellar StrKey encodings unit tests
+
+package converters
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRC16XModem(t *testing.T) {
+	require.Equal(t, uint16(0x31c3), crc16XModem([]byte("123456789")))
+}
+
+func TestStellar(t *testing.T) {
+	zero := "GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF"
+	require.Equal(t, zero, EncodeStellarPublicKey(make([]byte, 32)))
+	pub, err := ParseStellarPublicKey(zero)
+	require.NoError(t, err)
+	require.Equal(t, ed25519.PublicKey(make([]byte, 32)), pub)
+
+	// StrKeys of the Stellar SDKs' tests, and of the RFC 8032 test key,
+	// computed independently of this package
+	for _, tc := range []struct{ strKey, hex string }{
+		{"GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ", "3f0c34bf93ad0d9971d04ccc90f705511c838aad9734a4a2fb0d7a03fc7fe89a"},
+		{"GDLVVGABQKYQVN6VJP7NHSLEA45A5YLS6PNKMIZFV4BBU2HXA5IRVHUR", testPub},
+	} {
+		pub, err := ParseStellarPublicKey(tc.strKey)
+		require.NoError(t, err)
+		require.Equal(t, mustHex(t, tc.hex), []byte(pub))
+		require.Equal(t, tc.strKey, EncodeStellarPublicKey(pub))
+	}
+	for _, tc := range []struct{ strKey, hex string }{
+		{"SBU2RRGLXH3E5CQHTD3ODLDF2BWDCYUSSBLLZ5GNW7JXHDIYKXZWHOKR", "69a8c4cbb9f64e8a0798f6e1ac65d06c3162929056bcf4cdb7d3738d1855f363"},
+		{"SCOWDMM5576VUYF2QRFPJEXMFTCEISOFNF5TE2IZOA52YAY4VZ7WBQNO", testSeed},
+	} {
+		k, err := ParseStellarSeed(tc.strKey)
+		require.NoError(t, err)
+		require.Equal(t, mustHex(t, tc.hex), k.Seed())
+		require.Equal(t, tc.strKey, EncodeStellarSeed(k))
+	}
+	require.Equal(t, "GDLVVGABQKYQVN6VJP7NHSLEA45A5YLS6PNKMIZFV4BBU2HXA5IRVHUR", EncodeStellarPublicKey(testKey(t).Public().(ed25519.PublicKey)))
+
+	k := testKey(t)
+	seed := EncodeStellarSeed(k)
+	require.Equal(t, byte('S'), seed[0])
+	parsed, err := ParseStellarSeed(seed)
+	require.NoError(t, err)
+	require.Equal(t, k, parsed)
+	address := EncodeStellarPublicKey(k.Public().(ed25519.PublicKey))
+	require.Equal(t, byte('G'), address[0])
+
+	// a seed is not a public key
+	_, err = ParseStellarPublicKey(seed)
+	require.Error(t, err)
+	// corrupt the checksum
+	_, err = ParseStellarPublicKey(zero[:len(zero)-1] + "G")
+	require.ErrorIs(t, err, ErrChecksum)
+	_, err = ParseStellarPublicKey(zero[:len(zero)-8])
+	require.Error(t, err)
+}
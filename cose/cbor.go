@@ -0,0 +1,263 @@
+// Copyright 2019 Spacemesh Authors
+// minimal deterministic CBOR
+
+package cose
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+)
+
+// This file implements the subset of CBOR (RFC 8949) that COSE structures
+// need: integers, byte and text strings, arrays, maps, tags and the simple
+// values false, true and null. Values decode to int64, []byte, string,
+// []interface{}, map[interface{}]interface{}, cborTag, bool and nil.
+// Encoding follows the core deterministic encoding requirements of RFC 8949
+// section 4.2.1: shortest argument encoding, definite lengths and map keys
+// sorted by their encoded bytes.
+
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+
+	simpleFalse = 20
+	simpleTrue  = 21
+	simpleNull  = 22
+
+	// maxNesting bounds the depth of decoded arrays, maps and tags.
+	maxNesting = 16
+)
+
+var errCBOR = errors.New("cose: malformed CBOR")
+
+type cborTag struct {
+	Number  uint64
+	Content interface{}
+}
+
+func appendHead(b []byte, major byte, arg uint64) []byte {
+	m := major << 5
+	switch {
+	case arg < 24:
+		return append(b, m|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, m|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return append(b, m|25, byte(arg>>8), byte(arg))
+	case arg <= math.MaxUint32:
+		return append(b, m|26, byte(arg>>24), byte(arg>>16), byte(arg>>8), byte(arg))
+	default:
+		return append(b, m|27, byte(arg>>56), byte(arg>>48), byte(arg>>40), byte(arg>>32),
+			byte(arg>>24), byte(arg>>16), byte(arg>>8), byte(arg))
+	}
+}
+
+// cborMarshal returns the deterministic encoding of v.
+func cborMarshal(v interface{}) ([]byte, error) {
+	return cborAppend(nil, v)
+}
+
+func cborAppend(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, majorSimple<<5|simpleNull), nil
+	case bool:
+		if v {
+			return append(b, majorSimple<<5|simpleTrue), nil
+		}
+		return append(b, majorSimple<<5|simpleFalse), nil
+	case int:
+		return cborAppend(b, int64(v))
+	case int64:
+		if v < 0 {
+			return appendHead(b, majorNegInt, uint64(-(v + 1))), nil
+		}
+		return appendHead(b, majorUint, uint64(v)), nil
+	case []byte:
+		return append(appendHead(b, majorBytes, uint64(len(v))), v...), nil
+	case string:
+		return append(appendHead(b, majorText, uint64(len(v))), v...), nil
+	case []interface{}:
+		b = appendHead(b, majorArray, uint64(len(v)))
+		for _, e := range v {
+			var err error
+			if b, err = cborAppend(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[interface{}]interface{}:
+		type entry struct{ key, value []byte }
+		entries := make([]entry, 0, len(v))
+		for k, e := range v {
+			key, err := cborMarshal(k)
+			if err != nil {
+				return nil, err
+			}
+			value, err := cborMarshal(e)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry{key, value})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+		b = appendHead(b, majorMap, uint64(len(entries)))
+		for _, e := range entries {
+			b = append(b, e.key...)
+			b = append(b, e.value...)
+		}
+		return b, nil
+	case cborTag:
+		return cborAppend(appendHead(b, majorTag, v.Number), v.Content)
+	default:
+		return nil, errors.New("cose: unsupported CBOR value")
+	}
+}
+
+// cborUnmarshal decodes a single data item that must span all of data.
+func cborUnmarshal(data []byte) (interface{}, error) {
+	d := cborDecoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.data) {
+		return nil, errors.New("cose: trailing data after CBOR item")
+	}
+	return v, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	off  int
+}
+
+// head reads the initial byte and argument of a data item. Indefinite
+// lengths and non-shortest arguments are rejected.
+func (d *cborDecoder) head() (major byte, arg uint64, err error) {
+	if d.off >= len(d.data) {
+		return 0, 0, errCBOR
+	}
+	ib := d.data[d.off]
+	d.off++
+	major, info := ib>>5, ib&0x1f
+
+	var n int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	default:
+		return 0, 0, errCBOR
+	}
+	if len(d.data)-d.off < n {
+		return 0, 0, errCBOR
+	}
+	for _, c := range d.data[d.off : d.off+n] {
+		arg = arg<<8 | uint64(c)
+	}
+	d.off += n
+	if (n == 1 && arg < 24) || (n > 1 && arg>>(4*n) == 0) {
+		return 0, 0, errors.New("cose: non-deterministic CBOR integer encoding")
+	}
+	return major, arg, nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, errCBOR
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return int64(arg), nil
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return nil, errCBOR
+		}
+		return -int64(arg) - 1, nil
+	case majorBytes, majorText:
+		if uint64(len(d.data)-d.off) < arg {
+			return nil, errCBOR
+		}
+		s := d.data[d.off : d.off+int(arg)]
+		d.off += int(arg)
+		if major == majorText {
+			return string(s), nil
+		}
+		return append([]byte{}, s...), nil
+	case majorArray:
+		if uint64(len(d.data)-d.off) < arg {
+			return nil, errCBOR
+		}
+		a := make([]interface{}, arg)
+		for i := range a {
+			if a[i], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case majorMap:
+		if uint64(len(d.data)-d.off)/2 < arg {
+			return nil, errCBOR
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errors.New("cose: unsupported CBOR map key")
+			}
+			if _, dup := m[k]; dup {
+				return nil, errors.New("cose: duplicate CBOR map key")
+			}
+			if m[k], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case majorTag:
+		content, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{Number: arg, Content: content}, nil
+	default:
+		switch arg {
+		case simpleFalse:
+			return false, nil
+		case simpleTrue:
+			return true, nil
+		case simpleNull:
+			return nil, nil
+		}
+		return nil, errors.New("cose: unsupported CBOR simple value")
+	}
+}
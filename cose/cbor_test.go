@@ -0,0 +1,90 @@
+// Copyright 2019 Spacemesh Authors
+// minimal deterministic CBOR unit tests
+
+package cose
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Examples from RFC 8949, appendix A
+func TestCBORExamples(t *testing.T) {
+	for _, tc := range []struct {
+		value   interface{}
+		encoded string
+	}{
+		{int64(0), "00"},
+		{int64(23), "17"},
+		{int64(24), "1818"},
+		{int64(1000), "1903e8"},
+		{int64(1000000), "1a000f4240"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{int64(-1), "20"},
+		{int64(-1000), "3903e7"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{}, "40"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]interface{}{}, "80"},
+		{[]interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, "8301820203820405"},
+		{map[interface{}]interface{}{}, "a0"},
+		{map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}, "a26161016162820203"},
+		{cborTag{Number: 1, Content: int64(1363896240)}, "c11a514b67b0"},
+	} {
+		encoded, err := cborMarshal(tc.value)
+		require.NoError(t, err)
+		assert.Equal(t, tc.encoded, hex.EncodeToString(encoded))
+
+		decoded, err := cborUnmarshal(encoded)
+		require.NoError(t, err)
+		if b, ok := tc.value.([]byte); ok && len(b) == 0 {
+			assert.Empty(t, decoded)
+			continue
+		}
+		assert.Equal(t, tc.value, decoded)
+	}
+}
+
+func TestCBORDeterministicMapOrder(t *testing.T) {
+	m := map[interface{}]interface{}{
+		"z": int64(0), int64(-1): int64(0), int64(10): int64(0), int64(100): int64(0), "aa": int64(0),
+	}
+	for i := 0; i < 10; i++ {
+		encoded, err := cborMarshal(m)
+		require.NoError(t, err)
+		// keys sorted by encoded bytes: 10, 100, -1, "z", "aa"
+		assert.Equal(t, "a50a001864002000617a0062616100", hex.EncodeToString(encoded))
+	}
+}
+
+func TestCBORMalformed(t *testing.T) {
+	for _, h := range []string{
+		"",
+		"18",                      // missing argument
+		"1817",                    // non-shortest
+		"190017",                  // non-shortest
+		"1b7f",                    // truncated argument
+		"1bffffffffffffffff",      // integer overflow
+		"5f",                      // indefinite length
+		"44010203",                // truncated
+		"a1010102",                // trailing data
+		"a201010101",              // duplicate key
+		"a1f600",                  // unsupported key
+		"f97e00",                  // float
+		"9b" + "ffffffffffffffff", // huge array
+		"8181818181818181818181818181818181818100",
+	} {
+		data, err := hex.DecodeString(h)
+		require.NoError(t, err)
+		_, err = cborUnmarshal(data)
+		assert.Error(t, err, h)
+	}
+}
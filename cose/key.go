@@ -0,0 +1,153 @@
+// Copyright 2019 Spacemesh Authors
+// COSE_Key objects for Ed25519
+
+package cose
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+const (
+	// KeyTypeOKP is the COSE key type of octet key pairs.
+	KeyTypeOKP = 1
+	// CurveEd25519 is the COSE elliptic curve identifier of Ed25519.
+	CurveEd25519 = 6
+
+	keyLabelKty = 1
+	keyLabelKid = 2
+	keyLabelAlg = 3
+	keyLabelCrv = -1
+	keyLabelX   = -2
+	keyLabelD   = -4
+)
+
+// Key is an Ed25519 COSE_Key. PrivateKey is nil for public keys.
+type Key struct {
+	Kid        []byte
+	Alg        int64
+	PublicKey  ed25519.PublicKey
+	PrivateKey ed25519.PrivateKey
+}
+
+// Marshal returns the CBOR encoding of the key. Private keys are stored as
+// their seed.
+func (k *Key) Marshal() ([]byte, error) {
+	if len(k.PublicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("cose: bad public key length")
+	}
+	m := map[interface{}]interface{}{
+		int64(keyLabelKty): int64(KeyTypeOKP),
+		int64(keyLabelCrv): int64(CurveEd25519),
+		int64(keyLabelX):   []byte(k.PublicKey),
+	}
+	if k.Kid != nil {
+		m[int64(keyLabelKid)] = k.Kid
+	}
+	if k.Alg != 0 {
+		m[int64(keyLabelAlg)] = k.Alg
+	}
+	if k.PrivateKey != nil {
+		if len(k.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, errors.New("cose: bad private key length")
+		}
+		m[int64(keyLabelD)] = k.PrivateKey.Seed()
+	}
+	return cborMarshal(m)
+}
+
+// ParseKey parses an Ed25519 COSE_Key. For private keys it checks that the
+// public key matches the seed.
+func ParseKey(data []byte) (*Key, error) {
+	v, err := cborUnmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrMalformed
+	}
+	if m[int64(keyLabelKty)] != int64(KeyTypeOKP) || m[int64(keyLabelCrv)] != int64(CurveEd25519) {
+		return nil, errors.New("cose: not an Ed25519 key")
+	}
+
+	k := &Key{}
+	x, ok := m[int64(keyLabelX)].([]byte)
+	if !ok || len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("cose: invalid key x parameter")
+	}
+	k.PublicKey = x
+	if kid, ok := m[int64(keyLabelKid)]; ok {
+		if k.Kid, ok = kid.([]byte); !ok {
+			return nil, ErrMalformed
+		}
+	}
+	if alg, ok := m[int64(keyLabelAlg)]; ok {
+		if k.Alg, ok = alg.(int64); !ok {
+			return nil, ErrMalformed
+		}
+	}
+	if d, ok := m[int64(keyLabelD)]; ok {
+		seed, ok := d.([]byte)
+		if !ok || len(seed) != ed25519.SeedSize {
+			return nil, errors.New("cose: invalid key d parameter")
+		}
+		k.PrivateKey = ed25519.NewKeyFromSeed(seed)
+		if !k.PublicKey.Equal(k.PrivateKey.Public()) {
+			return nil, errors.New("cose: key x parameter does not match d")
+		}
+	}
+	return k, nil
+}
+
+// KeySet is a KeyResolver trusting a fixed set of keys. It is safe for
+// concurrent use.
+type KeySet struct {
+	mu    sync.RWMutex
+	byKid map[string]ed25519.PublicKey
+	known map[string]struct{}
+}
+
+// NewKeySet returns a KeySet trusting keys, which must have key ids unless
+// they are only used with AlgEdDSA2.
+func NewKeySet(keys ...*Key) *KeySet {
+	s := &KeySet{
+		byKid: make(map[string]ed25519.PublicKey),
+		known: make(map[string]struct{}),
+	}
+	for _, k := range keys {
+		s.Add(k.Kid, k.PublicKey)
+	}
+	return s
+}
+
+// Add trusts publicKey under key id kid. A nil kid trusts the key for
+// AlgEdDSA2 only.
+func (s *KeySet) Add(kid []byte, publicKey ed25519.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kid != nil {
+		s.byKid[string(kid)] = publicKey
+	}
+	s.known[string(publicKey)] = struct{}{}
+}
+
+// ResolveKey implements KeyResolver.
+func (s *KeySet) ResolveKey(kid []byte) (ed25519.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if publicKey, ok := s.byKid[string(kid)]; ok && kid != nil {
+		return publicKey, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// KnownKey implements KeyResolver.
+func (s *KeySet) KnownKey(publicKey ed25519.PublicKey) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.known[string(publicKey)]
+	return ok
+}
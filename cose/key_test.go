@@ -0,0 +1,45 @@
+// Copyright 2019 Spacemesh Authors
+// COSE_Key objects for Ed25519 unit tests
+
+package cose
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRoundTrip(t *testing.T) {
+	key := exampleKey(t)
+	data, err := key.Marshal()
+	require.NoError(t, err)
+
+	parsed, err := ParseKey(data)
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	public := &Key{Kid: key.Kid, PublicKey: key.PublicKey}
+	data, err = public.Marshal()
+	require.NoError(t, err)
+	// {1: 1, 2: h'3131', -1: 6, -2: h'd75a...'}
+	assert.Equal(t, "a40101024231312006215820"+exampleX, hex.EncodeToString(data))
+	parsed, err = ParseKey(data)
+	require.NoError(t, err)
+	assert.Equal(t, public, parsed)
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	for _, h := range []string{
+		"a3010220062158200000000000000000000000000000000000000000000000000000000000000000", // EC2 key type
+		"a301012001215820" + exampleX, // P-256 curve
+		"a301012006214100",            // short x
+		"a4010120062158200000000000000000000000000000000000000000000000000000000000000000235820" + exampleSeed, // d does not match x
+	} {
+		data, err := hex.DecodeString(h)
+		require.NoError(t, err)
+		_, err = ParseKey(data)
+		assert.Error(t, err, h)
+	}
+}
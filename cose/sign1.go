@@ -0,0 +1,294 @@
+// Copyright 2019 Spacemesh Authors
+// COSE_Sign1 signing and verification
+
+// Package cose implements Ed25519 COSE_Sign1 messages and COSE_Key objects
+// (RFC 9052, RFC 9053) on top of a minimal built-in CBOR codec.
+//
+// Besides EdDSA (-8), which signs with ed25519.Sign, the package supports the
+// experimental algorithm AlgEdDSA2 for ed25519.Sign2 signatures. Messages
+// using it may omit the key id: the verifier extracts the signer's key with
+// ed25519.ExtractPublicKey and accepts it only if its KeyResolver knows it.
+package cose
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+const (
+	// AlgEdDSA is the COSE algorithm identifier of EdDSA (RFC 9053).
+	AlgEdDSA int64 = -8
+	// AlgEdDSA2 is an experimental algorithm identifier, in the private use
+	// range, for signatures made by ed25519.Sign2.
+	AlgEdDSA2 int64 = -65537
+
+	// TagSign1 is the CBOR tag of COSE_Sign1 messages.
+	TagSign1 = 18
+
+	headerAlg  = 1
+	headerCrit = 2
+	headerKid  = 4
+
+	sign1Context = "Signature1"
+)
+
+var (
+	// ErrMalformed is returned when a message cannot be parsed.
+	ErrMalformed = errors.New("cose: malformed message")
+	// ErrUnsupportedAlgorithm is returned for algorithms other than AlgEdDSA
+	// and AlgEdDSA2.
+	ErrUnsupportedAlgorithm = errors.New("cose: unsupported algorithm")
+	// ErrUnsupportedCritical is returned when a message lists critical
+	// header parameters.
+	ErrUnsupportedCritical = errors.New("cose: unsupported critical header parameter")
+	// ErrUnknownKey is returned when the KeyResolver does not know the
+	// signer's key.
+	ErrUnknownKey = errors.New("cose: unknown key")
+	// ErrInvalidSignature is returned when a signature does not verify.
+	ErrInvalidSignature = errors.New("cose: invalid signature")
+	// ErrDetachedPayload is returned by Verify for messages with a detached
+	// payload, which must be verified with VerifyDetached.
+	ErrDetachedPayload = errors.New("cose: detached payload")
+)
+
+// KeyResolver supplies the keys a verifier trusts.
+type KeyResolver interface {
+	// ResolveKey returns the key with the given key id.
+	ResolveKey(kid []byte) (ed25519.PublicKey, error)
+	// KnownKey reports whether a key extracted from an AlgEdDSA2 signature
+	// is trusted.
+	KnownKey(publicKey ed25519.PublicKey) bool
+}
+
+// Signer holds a private key and the header parameters of its signatures.
+// The algorithm, AlgEdDSA by default, goes into the protected header and the
+// key id, if any, into the unprotected header.
+type Signer struct {
+	Key ed25519.PrivateKey
+	Alg int64
+	Kid []byte
+}
+
+// Sign1Message is a COSE_Sign1 message. A nil Payload is encoded as a
+// detached payload (RFC 9052, section 4.4), which is carried separately.
+type Sign1Message struct {
+	// Alg is the algorithm of the protected header.
+	Alg int64
+	// Kid is the key id of the unprotected header.
+	Kid       []byte
+	Payload   []byte
+	Signature []byte
+
+	protected []byte
+}
+
+// Sign1 signs payload, binding externalAAD to the signature. The payload is
+// carried in the message, even if nil.
+func Sign1(signer Signer, payload, externalAAD []byte) (*Sign1Message, error) {
+	if payload == nil {
+		payload = []byte{}
+	}
+	return sign1(signer, payload, payload, externalAAD)
+}
+
+// Sign1Detached signs content like Sign1, but returns a message with a
+// detached payload: content is sent separately and passed to VerifyDetached.
+func Sign1Detached(signer Signer, content, externalAAD []byte) (*Sign1Message, error) {
+	return sign1(signer, nil, content, externalAAD)
+}
+
+func sign1(signer Signer, payload, content, externalAAD []byte) (*Sign1Message, error) {
+	alg := signer.Alg
+	if alg == 0 {
+		alg = AlgEdDSA
+	}
+	protected, err := cborMarshal(map[interface{}]interface{}{int64(headerAlg): alg})
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := sigStructure(protected, externalAAD, content)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Sign1Message{Alg: alg, Kid: signer.Kid, Payload: payload, protected: protected}
+	switch alg {
+	case AlgEdDSA:
+		m.Signature = ed25519.Sign(signer.Key, toBeSigned)
+	case AlgEdDSA2:
+		m.Signature = ed25519.Sign2(signer.Key, toBeSigned)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return m, nil
+}
+
+// sigStructure returns the encoded Sig_structure of a COSE_Sign1 message.
+func sigStructure(protected, externalAAD, payload []byte) ([]byte, error) {
+	if externalAAD == nil {
+		externalAAD = []byte{}
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	return cborMarshal([]interface{}{sign1Context, protected, externalAAD, payload})
+}
+
+// Marshal returns the tagged CBOR encoding of the message.
+func (m *Sign1Message) Marshal() ([]byte, error) {
+	unprotected := map[interface{}]interface{}{}
+	if m.Kid != nil {
+		unprotected[int64(headerKid)] = m.Kid
+	}
+	var payload interface{}
+	if m.Payload != nil {
+		payload = m.Payload
+	}
+	protected := m.protected
+	if protected == nil {
+		protected = []byte{}
+	}
+	return cborMarshal(cborTag{
+		Number:  TagSign1,
+		Content: []interface{}{protected, unprotected, payload, m.Signature},
+	})
+}
+
+// ParseSign1 parses a tagged or untagged COSE_Sign1 message.
+func ParseSign1(data []byte) (*Sign1Message, error) {
+	v, err := cborUnmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag, ok := v.(cborTag); ok {
+		if tag.Number != TagSign1 {
+			return nil, ErrMalformed
+		}
+		v = tag.Content
+	}
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 4 {
+		return nil, ErrMalformed
+	}
+	protected, ok1 := a[0].([]byte)
+	unprotected, ok2 := a[1].(map[interface{}]interface{})
+	signature, ok3 := a[3].([]byte)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, ErrMalformed
+	}
+
+	m := &Sign1Message{protected: protected, Signature: signature}
+	switch payload := a[2].(type) {
+	case nil:
+	case []byte:
+		m.Payload = payload
+	default:
+		return nil, ErrMalformed
+	}
+
+	if len(protected) > 0 {
+		v, err := cborUnmarshal(protected)
+		if err != nil {
+			return nil, err
+		}
+		header, ok := v.(map[interface{}]interface{})
+		if !ok {
+			return nil, ErrMalformed
+		}
+		if _, ok := header[int64(headerCrit)]; ok {
+			return nil, ErrUnsupportedCritical
+		}
+		for label := range header {
+			if _, dup := unprotected[label]; dup {
+				return nil, errors.New("cose: header parameter in both buckets")
+			}
+		}
+		if alg, ok := header[int64(headerAlg)]; ok {
+			if m.Alg, ok = alg.(int64); !ok {
+				return nil, ErrMalformed
+			}
+		}
+		if kid, ok := header[int64(headerKid)]; ok {
+			if m.Kid, ok = kid.([]byte); !ok {
+				return nil, ErrMalformed
+			}
+		}
+	}
+	if _, ok := unprotected[int64(headerAlg)]; ok {
+		return nil, errors.New("cose: algorithm must be protected")
+	}
+	if kid, ok := unprotected[int64(headerKid)]; ok {
+		if m.Kid, ok = kid.([]byte); !ok {
+			return nil, ErrMalformed
+		}
+	}
+	return m, nil
+}
+
+// Verify verifies the message signature and returns the signer's key. For
+// AlgEdDSA the key is looked up by key id. For AlgEdDSA2 the key is extracted
+// from the signature and must be known to resolver; a key id, if present,
+// must name the extracted key. It returns ErrDetachedPayload if the payload
+// is detached.
+func (m *Sign1Message) Verify(externalAAD []byte, resolver KeyResolver) (ed25519.PublicKey, error) {
+	if m.Payload == nil {
+		return nil, ErrDetachedPayload
+	}
+	return m.verify(m.Payload, externalAAD, resolver)
+}
+
+// VerifyDetached verifies the signature of a message with a detached payload
+// over content, as Verify does.
+func (m *Sign1Message) VerifyDetached(content, externalAAD []byte, resolver KeyResolver) (ed25519.PublicKey, error) {
+	if m.Payload != nil {
+		return nil, errors.New("cose: payload is not detached")
+	}
+	return m.verify(content, externalAAD, resolver)
+}
+
+func (m *Sign1Message) verify(content, externalAAD []byte, resolver KeyResolver) (ed25519.PublicKey, error) {
+	if len(m.Signature) != ed25519.SignatureSize {
+		return nil, ErrInvalidSignature
+	}
+	toBeSigned, err := sigStructure(m.protected, externalAAD, content)
+	if err != nil {
+		return nil, err
+	}
+
+	switch m.Alg {
+	case AlgEdDSA:
+		key, err := resolver.ResolveKey(m.Kid)
+		if err != nil {
+			return nil, err
+		}
+		if !ed25519.Verify(key, toBeSigned, m.Signature) {
+			return nil, ErrInvalidSignature
+		}
+		return key, nil
+	case AlgEdDSA2:
+		key, err := ed25519.ExtractPublicKey(toBeSigned, m.Signature)
+		if err != nil {
+			return nil, ErrInvalidSignature
+		}
+		if !resolver.KnownKey(key) {
+			return nil, ErrUnknownKey
+		}
+		if m.Kid != nil {
+			named, err := resolver.ResolveKey(m.Kid)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(named, key) {
+				return nil, ErrInvalidSignature
+			}
+		}
+		if !ed25519.Verify2(key, toBeSigned, m.Signature) {
+			return nil, ErrInvalidSignature
+		}
+		return key, nil
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
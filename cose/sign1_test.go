@@ -0,0 +1,173 @@
+// Copyright 2019 Spacemesh Authors
+// COSE_Sign1 signing and verification unit tests
+
+package cose
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+// The Ed25519 key of the COSE WG examples (RFC 8032, test 1), kid "11".
+const (
+	exampleSeed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+	exampleX    = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+)
+
+// cose-wg/Examples eddsa-examples/eddsa-sig-01.json: the Sig_structure and
+// the complete COSE_Sign1 message.
+const (
+	exampleSign1ToBeSigned = "846A5369676E61747572653143A101274054546869732069732074686520636F6E74656E742E"
+	exampleSign1Message    = "D28443A10127A10442313154546869732069732074686520636F6E74656E742E5840" +
+		"6354488F9F290E36CD80E23762E664A5CB03E4267C66A8CFFAEF7C66D89A40BF" +
+		"2CBB8222432A08E5EE410D8B540C6931D26FB6AF673F7E2100655D8BAE765C04"
+)
+
+func exampleKey(t *testing.T) *Key {
+	seed, err := hex.DecodeString(exampleSeed)
+	require.NoError(t, err)
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	require.Equal(t, exampleX, hex.EncodeToString(privateKey[32:]))
+	return &Key{Kid: []byte("11"), Alg: AlgEdDSA, PublicKey: privateKey.Public().(ed25519.PublicKey), PrivateKey: privateKey}
+}
+
+func TestSign1Example(t *testing.T) {
+	key := exampleKey(t)
+
+	toBeSigned, err := hex.DecodeString(exampleSign1ToBeSigned)
+	require.NoError(t, err)
+	computed, err := sigStructure([]byte{0xa1, 0x01, 0x27}, nil, []byte("This is the content."))
+	require.NoError(t, err)
+	assert.Equal(t, toBeSigned, computed)
+
+	expected, err := hex.DecodeString(exampleSign1Message)
+	require.NoError(t, err)
+
+	m, err := Sign1(Signer{Key: key.PrivateKey, Kid: key.Kid}, []byte("This is the content."), nil)
+	require.NoError(t, err)
+	data, err := m.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, expected, data)
+
+	parsed, err := ParseSign1(expected)
+	require.NoError(t, err)
+	assert.Equal(t, AlgEdDSA, parsed.Alg)
+	assert.Equal(t, []byte("11"), parsed.Kid)
+	assert.Equal(t, []byte("This is the content."), parsed.Payload)
+	signer, err := parsed.Verify(nil, NewKeySet(key))
+	require.NoError(t, err)
+	assert.Equal(t, key.PublicKey, signer)
+}
+
+func testKey(index uint64) *Key {
+	seed := make([]byte, ed25519.SeedSize)
+	privateKey := ed25519.NewDerivedKeyFromSeed(seed, index, []byte("cose"))
+	return &Key{PublicKey: privateKey.Public().(ed25519.PublicKey), PrivateKey: privateKey}
+}
+
+func TestSign1EdDSA2(t *testing.T) {
+	alice, mallory := testKey(1), testKey(2)
+	keys := NewKeySet(alice)
+
+	// no key id: the key is extracted from the signature
+	m, err := Sign1(Signer{Key: alice.PrivateKey, Alg: AlgEdDSA2}, []byte("payload"), []byte("aad"))
+	require.NoError(t, err)
+	data, err := m.Marshal()
+	require.NoError(t, err)
+	parsed, err := ParseSign1(data)
+	require.NoError(t, err)
+	assert.Nil(t, parsed.Kid)
+	signer, err := parsed.Verify([]byte("aad"), keys)
+	require.NoError(t, err)
+	assert.Equal(t, alice.PublicKey, signer)
+
+	// wrong external data extracts an unknown key
+	_, err = parsed.Verify([]byte("other aad"), keys)
+	assert.Equal(t, ErrUnknownKey, err)
+
+	m, err = Sign1(Signer{Key: mallory.PrivateKey, Alg: AlgEdDSA2}, []byte("payload"), nil)
+	require.NoError(t, err)
+	_, err = m.Verify(nil, keys)
+	assert.Equal(t, ErrUnknownKey, err)
+
+	// a key id must name the extracted key
+	keys.Add([]byte("mallory"), mallory.PublicKey)
+	m, err = Sign1(Signer{Key: alice.PrivateKey, Alg: AlgEdDSA2, Kid: []byte("mallory")}, []byte("payload"), nil)
+	require.NoError(t, err)
+	_, err = m.Verify(nil, keys)
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestSign1DetachedPayload(t *testing.T) {
+	alice := testKey(1)
+	alice.Kid = []byte("alice")
+	keys := NewKeySet(alice)
+
+	m, err := Sign1Detached(Signer{Key: alice.PrivateKey, Kid: alice.Kid}, []byte("payload"), []byte("aad"))
+	require.NoError(t, err)
+	data, err := m.Marshal()
+	require.NoError(t, err)
+
+	parsed, err := ParseSign1(data)
+	require.NoError(t, err)
+	assert.Nil(t, parsed.Payload)
+	signer, err := parsed.VerifyDetached([]byte("payload"), []byte("aad"), keys)
+	require.NoError(t, err)
+	assert.Equal(t, alice.PublicKey, signer)
+	_, err = parsed.VerifyDetached([]byte("tampered"), []byte("aad"), keys)
+	assert.Equal(t, ErrInvalidSignature, err)
+	_, err = parsed.VerifyDetached([]byte("payload"), nil, keys)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// a detached payload is not verified as an empty one
+	_, err = parsed.Verify([]byte("aad"), keys)
+	assert.Equal(t, ErrDetachedPayload, err)
+	empty, err := Sign1Detached(Signer{Key: alice.PrivateKey, Kid: alice.Kid}, nil, nil)
+	require.NoError(t, err)
+	_, err = empty.Verify(nil, keys)
+	assert.Equal(t, ErrDetachedPayload, err)
+
+	// and an embedded payload cannot be replaced
+	m, err = Sign1(Signer{Key: alice.PrivateKey, Kid: alice.Kid}, nil, nil)
+	require.NoError(t, err)
+	_, err = m.Verify(nil, keys)
+	require.NoError(t, err)
+	_, err = m.VerifyDetached([]byte("payload"), nil, keys)
+	assert.Error(t, err)
+}
+
+func TestParseSign1Errors(t *testing.T) {
+	sig := "5840" + strings.Repeat("00", 64)
+	for _, h := range []string{
+		"",
+		"D28443A10127A1044231315454",               // truncated
+		"D18443A10127A1044231314040" + sig[:4],     // wrong tag
+		"D28343A10127A10442313140",                 // three elements
+		"D28440A1012740" + sig,                     // unprotected algorithm
+		"D28443A10127A1012740" + sig,               // algorithm in both buckets
+		"D28445A201270280A040" + sig,               // critical parameters
+		"D2845FFF" + "A040" + sig,                  // indefinite length
+		"D28444A1012700A040" + sig,                 // trailing bytes in protected header
+		"D2844043A10127A040" + sig,                 // protected header is not a map
+		"D28443A10127A00140" + sig,                 // payload is an integer
+		"D28443A10127A040" + sig + "00",            // trailing bytes
+		"D28443A10127A0401B00000000000000FF" + sig, // non-shortest length
+	} {
+		data, err := hex.DecodeString(h)
+		require.NoError(t, err)
+		_, err = ParseSign1(data)
+		assert.Error(t, err, h)
+	}
+
+	// a well-formed message for comparison
+	data, err := hex.DecodeString("D28443A10127A040" + sig)
+	require.NoError(t, err)
+	_, err = ParseSign1(data)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Spacemesh Authors
+// X25519 key agreement with Ed25519 keys
+
+package ed25519
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"strconv"
+
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+)
+
+const (
+	// X25519Size is the size, in bytes, of X25519 scalars, points and
+	// shared secrets.
+	X25519Size = 32
+)
+
+// Basepoint is the canonical curve25519 generator, u = 9.
+var Basepoint = []byte{9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+// ErrLowOrderPoint is returned by X25519 when the result is the all-zero
+// value, which happens for low-order points.
+var ErrLowOrderPoint = errors.New("ed25519: low order point")
+
+// X25519 returns the result of the scalar multiplication scalar·point, as
+// defined in RFC 7748, section 5, where point is a u-coordinate. Use
+// Basepoint as point to compute the public key of scalar. It returns an error
+// if the result is all zeros, as for low-order points. It runs in constant
+// time with respect to scalar.
+func X25519(scalar, point []byte) ([]byte, error) {
+	if l := len(scalar); l != X25519Size {
+		return nil, errors.New("ed25519: bad scalar length: " + strconv.Itoa(l))
+	}
+	if l := len(point); l != X25519Size {
+		return nil, errors.New("ed25519: bad point length: " + strconv.Itoa(l))
+	}
+	var out, s, u [32]byte
+	copy(s[:], scalar)
+	copy(u[:], point)
+	edwards25519.X25519(&out, &s, &u)
+
+	var zero [32]byte
+	if subtle.ConstantTimeCompare(out[:], zero[:]) == 1 {
+		return nil, ErrLowOrderPoint
+	}
+	return out[:], nil
+}
+
+// PublicKeyToCurve25519 returns the curve25519 u-coordinate of the Ed25519
+// public key, the X25519 public key of the scalar returned by
+// PrivateKeyToCurve25519 for the matching private key.
+func PublicKeyToCurve25519(publicKey PublicKey) ([]byte, error) {
+	if l := len(publicKey); l != PublicKeySize {
+		return nil, errors.New("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+	var A edwards25519.ExtendedGroupElement
+	var encoded [32]byte
+	copy(encoded[:], publicKey)
+	if !A.FromBytes(&encoded) {
+		return nil, ErrInvalidPublicKey
+	}
+	var u [32]byte
+	A.ToMontgomery(&u)
+	return u[:], nil
+}
+
+// PrivateKeyToCurve25519 returns the X25519 scalar of privateKey: the clamped
+// first half of SHA-512 of its seed, which is also the Ed25519 secret scalar.
+// It will panic if len(privateKey) is not PrivateKeySize.
+func PrivateKeyToCurve25519(privateKey PrivateKey) []byte {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+	digest := sha512.Sum512(privateKey[:SeedSize])
+	digest[0] &= 248
+	digest[31] &= 127
+	digest[31] |= 64
+	return digest[:X25519Size]
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Spacemesh Authors
+// X25519 key agreement unit tests
+
+package ed25519
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// RFC 7748, section 5.2
+func TestX25519Vectors(t *testing.T) {
+	vectors := []struct{ scalar, point, out string }{
+		{
+			"a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4",
+			"e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c",
+			"c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552",
+		},
+		{
+			"4b66e9d4d1b4673c5ad22691957d6af5c11b6421e0ea01d42ca4169e7918ba0d",
+			"e5210f12786811d3f4b7959d0538ae2c31dbe7106fc03c3efc4cd549c715a493",
+			"95cbde9476e8907d7aade45cb4b873f88b595a68799fa152e6f8f7647aac7957",
+		},
+	}
+	for _, v := range vectors {
+		out, err := X25519(decodeHex(t, v.scalar), decodeHex(t, v.point))
+		require.NoError(t, err)
+		assert.Equal(t, v.out, hex.EncodeToString(out))
+	}
+}
+
+// RFC 7748, section 5.2, iterated: k, u = X25519(k, u), k
+func TestX25519Iterated(t *testing.T) {
+	k := append([]byte(nil), Basepoint...)
+	u := append([]byte(nil), Basepoint...)
+	iterations := map[int]string{
+		1:    "422c8e7a6227d7bca1350b3e2bb7279f7897b87bb6854b783c60e80311ae3079",
+		1000: "684cf59ba83309552800ef566f2f4d3c1c3887c49360e3875f2eb94d99532c51",
+	}
+	n := 1000
+	if testing.Short() {
+		n = 1
+	}
+	for i := 1; i <= n; i++ {
+		out, err := X25519(k, u)
+		require.NoError(t, err)
+		u, k = k, out
+		if want, ok := iterations[i]; ok {
+			assert.Equal(t, want, hex.EncodeToString(k), "iteration %d", i)
+		}
+	}
+}
+
+// RFC 7748, section 6.1
+func TestX25519DiffieHellman(t *testing.T) {
+	alice := decodeHex(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bob := decodeHex(t, "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+
+	alicePub, err := X25519(alice, Basepoint)
+	require.NoError(t, err)
+	assert.Equal(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a", hex.EncodeToString(alicePub))
+	bobPub, err := X25519(bob, Basepoint)
+	require.NoError(t, err)
+	assert.Equal(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f", hex.EncodeToString(bobPub))
+
+	shared1, err := X25519(alice, bobPub)
+	require.NoError(t, err)
+	shared2, err := X25519(bob, alicePub)
+	require.NoError(t, err)
+	assert.Equal(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742", hex.EncodeToString(shared1))
+	assert.Equal(t, shared1, shared2)
+}
+
+func TestX25519LowOrder(t *testing.T) {
+	scalar := bytes.Repeat([]byte{0x42}, X25519Size)
+	for _, point := range []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	} {
+		_, err := X25519(scalar, decodeHex(t, point))
+		assert.ErrorIs(t, err, ErrLowOrderPoint, point)
+	}
+	_, err := X25519(scalar[:31], Basepoint)
+	assert.Error(t, err)
+}
+
+func TestCurve25519Conversion(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		seed := bytes.Repeat([]byte{byte(i)}, SeedSize)
+		privateKey := NewKeyFromSeed(seed)
+		publicKey := privateKey.Public().(PublicKey)
+
+		scalar := PrivateKeyToCurve25519(privateKey)
+		u, err := PublicKeyToCurve25519(publicKey)
+		require.NoError(t, err)
+		fromScalar, err := X25519(scalar, Basepoint)
+		require.NoError(t, err)
+		assert.Equal(t, u, fromScalar, "seed %d", i)
+	}
+
+	// both parties derive the same secret from their Ed25519 keys
+	a := NewKeyFromSeed(bytes.Repeat([]byte{1}, SeedSize))
+	b := NewDerivedKeyFromSeed(bytes.Repeat([]byte{2}, SeedSize), 5, []byte("x25519"))
+	aPub, err := PublicKeyToCurve25519(a.Public().(PublicKey))
+	require.NoError(t, err)
+	bPub, err := PublicKeyToCurve25519(b.Public().(PublicKey))
+	require.NoError(t, err)
+	s1, err := X25519(PrivateKeyToCurve25519(a), bPub)
+	require.NoError(t, err)
+	s2, err := X25519(PrivateKeyToCurve25519(b), aPub)
+	require.NoError(t, err)
+	assert.Equal(t, s1, s2)
+
+	// the Ed25519 base point maps to u = 9
+	u, err := PublicKeyToCurve25519(decodeHex(t, "5866666666666666666666666666666666666666666666666666666666666666"))
+	require.NoError(t, err)
+	assert.Equal(t, Basepoint, u)
+
+	// y = 2 is not on the curve
+	notOnCurve := make([]byte, PublicKeySize)
+	notOnCurve[0] = 2
+	_, err = PublicKeyToCurve25519(notOnCurve)
+	assert.Error(t, err)
+}
+
+func BenchmarkX25519(b *testing.B) {
+	scalar := bytes.Repeat([]byte{0x42}, X25519Size)
+	for i := 0; i < b.N; i++ {
+		if _, err := X25519(scalar, Basepoint); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
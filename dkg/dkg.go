@@ -0,0 +1,370 @@
+// Copyright 2019 Spacemesh Authors
+// Distributed key generation
+
+// Package dkg implements Pedersen distributed key generation with Feldman
+// commitments, so that n participants jointly create a group Ed25519 key and
+// t-of-n shares of it for package frost, without any party ever holding the
+// group secret.
+//
+// Each participant deals a random secret like a frost dealer, and the group
+// secret is the sum of the secrets of the qualified dealers. The protocol
+// does not move messages itself: each step takes the messages of the
+// previous one and returns those to send, which are plain structs the
+// caller can encode for any transport.
+//
+//  1. New returns a Round1Message, broadcast to all: the commitment to the
+//     dealer's polynomial with a proof of knowledge of its secret.
+//  2. Round1 takes the Round1Messages of the others and returns a
+//     Round2Message for each of them, holding its share, sent privately.
+//  3. Round2 takes the Round2Messages addressed to the participant and
+//     returns a Complaint, broadcast to all, against each dealer whose share
+//     is missing or does not match its commitment.
+//  4. Respond takes all the Complaints and returns the shares the
+//     participant sent to those complaining about it, broadcast to all.
+//  5. Finish takes all the Complaints and responses and returns the
+//     participant's frost.KeyShare and the frost.GroupKey.
+//
+// All participants pass New the same session ID, unique to the key
+// generation, such as a hash of the participants' long-term keys and a
+// counter. The proofs of knowledge bind it, so that a dealer cannot replay
+// the Round1Message of another key generation.
+//
+// Dealers with an invalid proof, or that do not answer a complaint with a
+// share that matches their commitment, are disqualified. Messages must be
+// authenticated, and broadcasts must reach every participant unchanged, so
+// that all agree on who is.
+package dkg
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/spacemeshos/ed25519/frost"
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+	"github.com/spacemeshos/ed25519/internal/vss"
+)
+
+// proofDomain separates the hash of the proofs of knowledge.
+const proofDomain = "spacemesh ed25519 dkg proof v1"
+
+var (
+	// ErrWrongRound is returned when the steps are called out of order.
+	ErrWrongRound = errors.New("dkg: step called out of order")
+	// ErrTooFewDealers is returned when fewer than threshold dealers are
+	// qualified.
+	ErrTooFewDealers = errors.New("dkg: too few qualified dealers")
+)
+
+// Round1Message is the broadcast commitment of a dealer to its polynomial,
+// with a Schnorr proof of knowledge R || s of its secret, which prevents a
+// dealer from choosing its commitment based on those of the others.
+type Round1Message struct {
+	From       frost.Identifier
+	Commitment [][32]byte
+	Proof      [64]byte
+}
+
+// Round2Message is the share of participant To from dealer From. It is sent
+// privately, unless it answers a complaint.
+type Round2Message struct {
+	From  frost.Identifier
+	To    frost.Identifier
+	Share [32]byte
+}
+
+// Complaint is the broadcast claim of participant From that its share from
+// dealer Against is missing or invalid.
+type Complaint struct {
+	From    frost.Identifier
+	Against frost.Identifier
+}
+
+// Participant is the state of one participant in a key generation.
+type Participant struct {
+	id           frost.Identifier
+	session      []byte
+	threshold, n int
+	round        int
+	poly         vss.Polynomial
+	// commitments holds those of the qualified dealers, and shares the
+	// shares received from them.
+	commitments  map[frost.Identifier]vss.Commitment
+	shares       map[frost.Identifier][32]byte
+	disqualified []frost.Identifier
+}
+
+// New starts the key generation session of participant id, from 1 to n,
+// for a threshold of threshold, and returns its Round1Message. If rand is
+// nil, crypto/rand.Reader will be used.
+func New(rand io.Reader, session []byte, id frost.Identifier, threshold, n int) (*Participant, *Round1Message, error) {
+	if len(session) == 0 {
+		return nil, nil, errors.New("dkg: empty session ID")
+	}
+	if threshold < 2 || n < threshold || n > 0xffff {
+		return nil, nil, errors.New("dkg: bad threshold " + strconv.Itoa(threshold) + " of " + strconv.Itoa(n))
+	}
+	if id == 0 || int(id) > n {
+		return nil, nil, errors.New("dkg: bad identifier " + strconv.Itoa(int(id)))
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	poly, err := vss.NewPolynomial(rand, nil, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	k, err := vss.RandomScalar(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := &Round1Message{From: id, Commitment: poly.Commit()}
+
+	// R = k·B, s = k + c·a0
+	var R edwards25519.ExtendedGroupElement
+	var r, c, s [32]byte
+	edwards25519.GeScalarMultBase(&R, &k)
+	R.ToBytes(&r)
+	c = proofChallenge(session, id, &msg.Commitment[0], &r)
+	edwards25519.ScMulAdd(&s, &c, &poly[0], &k)
+	copy(msg.Proof[:], r[:])
+	copy(msg.Proof[32:], s[:])
+
+	p := &Participant{
+		id:          id,
+		session:     append([]byte{}, session...),
+		threshold:   threshold,
+		n:           n,
+		poly:        poly,
+		commitments: map[frost.Identifier]vss.Commitment{id: msg.Commitment},
+		shares:      map[frost.Identifier][32]byte{id: poly.Evaluate(uint16(id))},
+	}
+	return p, msg, nil
+}
+
+// proofChallenge returns the challenge of the proof of knowledge of the
+// secret of dealer id in session, with public key A and nonce commitment R.
+func proofChallenge(session []byte, id frost.Identifier, A, R *[32]byte) [32]byte {
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(session)))
+	h := sha512.New()
+	h.Write([]byte(proofDomain))
+	h.Write(length[:])
+	h.Write(session)
+	h.Write([]byte{byte(id), byte(id >> 8)})
+	h.Write(A[:])
+	h.Write(R[:])
+	var digest [64]byte
+	var c [32]byte
+	h.Sum(digest[:0])
+	edwards25519.ScReduce(&c, &digest)
+	return c
+}
+
+// verifyProof checks the proof of knowledge of msg in session:
+// s·B - c·A = R.
+func verifyProof(session []byte, msg *Round1Message) bool {
+	var A edwards25519.ExtendedGroupElement
+	if !A.FromBytes(&msg.Commitment[0]) {
+		return false
+	}
+	var r, s, c, got [32]byte
+	copy(r[:], msg.Proof[:32])
+	copy(s[:], msg.Proof[32:])
+	if !edwards25519.ScMinimal(&s) {
+		return false
+	}
+	c = proofChallenge(session, msg.From, &msg.Commitment[0], &r)
+	edwards25519.ScNeg(&c, &c)
+	var R edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultVartime(&R, &c, &A, &s)
+	R.ToBytes(&got)
+	return got == r
+}
+
+// Identifier returns the identifier of p.
+func (p *Participant) Identifier() frost.Identifier {
+	return p.id
+}
+
+// Disqualified returns the dealers disqualified so far, in increasing order.
+func (p *Participant) Disqualified() []frost.Identifier {
+	return append([]frost.Identifier{}, p.disqualified...)
+}
+
+func (p *Participant) disqualify(id frost.Identifier) {
+	delete(p.commitments, id)
+	delete(p.shares, id)
+	p.disqualified = append(p.disqualified, id)
+	sort.Slice(p.disqualified, func(i, j int) bool { return p.disqualified[i] < p.disqualified[j] })
+}
+
+// Round1 takes the Round1Messages of the other participants, disqualifies
+// the dealers whose message is missing or invalid, and returns the shares to
+// send to each of the others.
+func (p *Participant) Round1(msgs []*Round1Message) ([]*Round2Message, error) {
+	if p.round != 0 {
+		return nil, ErrWrongRound
+	}
+	received := make(map[frost.Identifier]*Round1Message, len(msgs))
+	for _, msg := range msgs {
+		if msg.From == p.id {
+			continue
+		}
+		if msg.From == 0 || int(msg.From) > p.n || received[msg.From] != nil {
+			return nil, errors.New("dkg: unexpected round 1 message from " + strconv.Itoa(int(msg.From)))
+		}
+		received[msg.From] = msg
+	}
+	for i := 1; i <= p.n; i++ {
+		id := frost.Identifier(i)
+		if id == p.id {
+			continue
+		}
+		msg := received[id]
+		if msg == nil || len(msg.Commitment) != p.threshold || !verifyProof(p.session, msg) {
+			p.disqualify(id)
+			continue
+		}
+		p.commitments[id] = append(vss.Commitment{}, msg.Commitment...)
+	}
+
+	out := make([]*Round2Message, 0, p.n-1)
+	for i := 1; i <= p.n; i++ {
+		if frost.Identifier(i) != p.id {
+			out = append(out, p.share(frost.Identifier(i)))
+		}
+	}
+	p.round = 1
+	return out, nil
+}
+
+// share returns the share of participant to from p.
+func (p *Participant) share(to frost.Identifier) *Round2Message {
+	return &Round2Message{From: p.id, To: to, Share: p.poly.Evaluate(uint16(to))}
+}
+
+// Round2 takes the Round2Messages addressed to p, and returns a Complaint
+// against each qualified dealer whose share is missing or does not match its
+// commitment.
+func (p *Participant) Round2(msgs []*Round2Message) ([]*Complaint, error) {
+	if p.round != 1 {
+		return nil, ErrWrongRound
+	}
+	received := make(map[frost.Identifier]*Round2Message, len(msgs))
+	for _, msg := range msgs {
+		if msg.To != p.id || msg.From == p.id || received[msg.From] != nil {
+			return nil, errors.New("dkg: unexpected round 2 message from " + strconv.Itoa(int(msg.From)))
+		}
+		received[msg.From] = msg
+	}
+	var complaints []*Complaint
+	for _, id := range p.dealers() {
+		if id == p.id {
+			continue
+		}
+		msg := received[id]
+		if msg == nil || !p.commitments[id].VerifyShare(uint16(p.id), &msg.Share) {
+			complaints = append(complaints, &Complaint{From: p.id, Against: id})
+			continue
+		}
+		p.shares[id] = msg.Share
+	}
+	p.round = 2
+	return complaints, nil
+}
+
+// dealers returns the qualified dealers in increasing order.
+func (p *Participant) dealers() []frost.Identifier {
+	ids := make([]frost.Identifier, 0, len(p.commitments))
+	for id := range p.commitments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Respond takes all the Complaints and returns, for those against p, the
+// share p sent to the complaining participant, to be broadcast.
+func (p *Participant) Respond(complaints []*Complaint) []*Round2Message {
+	var out []*Round2Message
+	for _, c := range complaints {
+		if c.Against == p.id && c.From != p.id && c.From != 0 && int(c.From) <= p.n {
+			out = append(out, p.share(c.From))
+		}
+	}
+	return out
+}
+
+// Finish takes all the Complaints and the responses of the dealers, and
+// disqualifies the dealers that did not answer a complaint against them with
+// a share matching their commitment. It returns the share of p of the sum of
+// the secrets of the qualified dealers, and the group key. It returns
+// ErrTooFewDealers if fewer than threshold dealers are qualified.
+func (p *Participant) Finish(complaints []*Complaint, responses []*Round2Message) (*frost.KeyShare, *frost.GroupKey, error) {
+	if p.round != 2 {
+		return nil, nil, ErrWrongRound
+	}
+	type pair struct{ from, to frost.Identifier }
+	answers := make(map[pair]*[32]byte, len(responses))
+	for _, r := range responses {
+		answers[pair{r.From, r.To}] = &r.Share
+	}
+	for _, c := range complaints {
+		commitment, ok := p.commitments[c.Against]
+		if !ok || c.From == 0 || int(c.From) > p.n {
+			continue
+		}
+		share := answers[pair{c.Against, c.From}]
+		if share == nil || !commitment.VerifyShare(uint16(c.From), share) {
+			p.disqualify(c.Against)
+			continue
+		}
+		if c.From == p.id {
+			p.shares[c.Against] = *share
+		}
+	}
+	dealers := p.dealers()
+	if len(dealers) < p.threshold {
+		return nil, nil, ErrTooFewDealers
+	}
+
+	commitments := make([]vss.Commitment, len(dealers))
+	var secret [32]byte
+	for i, id := range dealers {
+		share, ok := p.shares[id]
+		if !ok {
+			// a complaint of p that did not reach Finish
+			return nil, nil, errors.New("dkg: no share from dealer " + strconv.Itoa(int(id)))
+		}
+		commitments[i] = p.commitments[id]
+		edwards25519.ScAdd(&secret, &secret, &share)
+	}
+	sum, err := vss.Sum(commitments)
+	if err != nil {
+		return nil, nil, err
+	}
+	group, err := frost.NewGroupKey(sum, p.n)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyShare := &frost.KeyShare{
+		Identifier:     p.id,
+		Secret:         secret,
+		GroupPublicKey: group.PublicKey,
+		Threshold:      p.threshold,
+	}
+	if !keyShare.Verify(sum) {
+		return nil, nil, errors.New("dkg: share does not match the group commitment")
+	}
+
+	for i := range p.poly {
+		p.poly[i] = [32]byte{}
+	}
+	p.round = 3
+	return keyShare, group, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 Spacemesh Authors
+// Distributed key generation unit tests
+
+package dkg
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/frost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// network runs a key generation between n in-process participants. The
+// hooks, if set, let a test tamper with the messages of a round before they
+// are delivered.
+type network struct {
+	session      []byte
+	threshold    int
+	participants []*Participant
+	round1       func([]*Round1Message)
+	round2       func([]*Round2Message)
+	complaints   func([]*Complaint) []*Complaint
+	responses    func([]*Round2Message) []*Round2Message
+}
+
+func newNetwork(threshold, n int) *network {
+	return &network{session: []byte("test session"), threshold: threshold, participants: make([]*Participant, n)}
+}
+
+type result struct {
+	shares []*frost.KeyShare
+	groups []*frost.GroupKey
+	errs   []error
+}
+
+func (net *network) run(t *testing.T) *result {
+	n := len(net.participants)
+	round1 := make([]*Round1Message, n)
+	for i := range net.participants {
+		var err error
+		net.participants[i], round1[i], err = New(rand.Reader, net.session, frost.Identifier(i+1), net.threshold, n)
+		require.NoError(t, err)
+	}
+	if net.round1 != nil {
+		net.round1(round1)
+	}
+
+	var round2 []*Round2Message
+	for _, p := range net.participants {
+		msgs, err := p.Round1(round1)
+		require.NoError(t, err)
+		require.Len(t, msgs, n-1)
+		round2 = append(round2, msgs...)
+	}
+	if net.round2 != nil {
+		net.round2(round2)
+	}
+
+	var complaints []*Complaint
+	for _, p := range net.participants {
+		var inbox []*Round2Message
+		for _, msg := range round2 {
+			if msg.To == p.Identifier() {
+				inbox = append(inbox, msg)
+			}
+		}
+		c, err := p.Round2(inbox)
+		require.NoError(t, err)
+		complaints = append(complaints, c...)
+	}
+	if net.complaints != nil {
+		complaints = net.complaints(complaints)
+	}
+
+	var responses []*Round2Message
+	for _, p := range net.participants {
+		responses = append(responses, p.Respond(complaints)...)
+	}
+	if net.responses != nil {
+		responses = net.responses(responses)
+	}
+
+	r := &result{
+		shares: make([]*frost.KeyShare, n),
+		groups: make([]*frost.GroupKey, n),
+		errs:   make([]error, n),
+	}
+	for i, p := range net.participants {
+		r.shares[i], r.groups[i], r.errs[i] = p.Finish(complaints, responses)
+	}
+	return r
+}
+
+// checkSign checks that the participants of r agree on the group key, and
+// that the shares of signers sign under it.
+func checkSign(t *testing.T, r *result, signers ...int) {
+	for i := range r.groups {
+		require.NoError(t, r.errs[i])
+		assert.Equal(t, r.groups[0], r.groups[i])
+	}
+	group := r.groups[0]
+
+	message := []byte("jointly generated key")
+	nonces := make([]*frost.Nonces, len(signers))
+	commitments := make([]frost.Commitment, len(signers))
+	for i, s := range signers {
+		var c *frost.Commitment
+		var err error
+		nonces[i], c, err = frost.Commit(nil, r.shares[s])
+		require.NoError(t, err)
+		commitments[i] = *c
+	}
+	shares := make(map[frost.Identifier][]byte)
+	for i, s := range signers {
+		share, err := frost.Sign(r.shares[s], nonces[i], commitments, message, frost.ModeSign2)
+		require.NoError(t, err)
+		shares[r.shares[s].Identifier] = share
+	}
+	sig, err := frost.Aggregate(group, commitments, shares, message, frost.ModeSign2)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify2(group.PublicKey, message, sig))
+	extracted, err := ed25519.ExtractPublicKey(message, sig)
+	require.NoError(t, err)
+	assert.Equal(t, group.PublicKey, extracted)
+}
+
+func TestDKG(t *testing.T) {
+	net := newNetwork(3, 5)
+	r := net.run(t)
+	for _, p := range net.participants {
+		assert.Empty(t, p.Disqualified())
+	}
+	checkSign(t, r, 0, 2, 4)
+	checkSign(t, r, 4, 3, 1, 0)
+
+	_, err := net.participants[0].Round1(nil)
+	assert.Equal(t, ErrWrongRound, err)
+	_, _, err = net.participants[0].Finish(nil, nil)
+	assert.Equal(t, ErrWrongRound, err)
+
+	_, _, err = New(rand.Reader, net.session, 6, 3, 5)
+	assert.Error(t, err)
+	_, _, err = New(rand.Reader, net.session, 1, 1, 5)
+	assert.Error(t, err)
+	_, _, err = New(rand.Reader, nil, 1, 3, 5)
+	assert.Error(t, err)
+}
+
+// TestBadShareJustified checks that a dealer complained against keeps its
+// place by revealing the right share.
+func TestBadShareJustified(t *testing.T) {
+	net := newNetwork(2, 4)
+	net.round2 = func(msgs []*Round2Message) {
+		for _, msg := range msgs {
+			if msg.From == 2 && msg.To == 3 {
+				msg.Share[0] ^= 1
+			}
+		}
+	}
+	var complaints []*Complaint
+	net.complaints = func(c []*Complaint) []*Complaint {
+		complaints = c
+		return c
+	}
+	r := net.run(t)
+	assert.Equal(t, []*Complaint{{From: 3, Against: 2}}, complaints)
+	for _, p := range net.participants {
+		assert.Empty(t, p.Disqualified())
+	}
+	checkSign(t, r, 1, 2)
+	checkSign(t, r, 2, 3)
+}
+
+// TestBadShareUnjustified checks that a dealer that sends a wrong share and
+// does not reveal the right one is disqualified by everyone.
+func TestBadShareUnjustified(t *testing.T) {
+	for _, reveal := range []bool{false, true} {
+		net := newNetwork(2, 4)
+		net.round2 = func(msgs []*Round2Message) {
+			for _, msg := range msgs {
+				if msg.From == 2 && msg.To == 3 {
+					msg.Share[0] ^= 1
+				}
+			}
+		}
+		net.responses = func(msgs []*Round2Message) []*Round2Message {
+			if !reveal {
+				return nil
+			}
+			for _, msg := range msgs {
+				msg.Share[1] ^= 1
+			}
+			return msgs
+		}
+		r := net.run(t)
+		for _, p := range net.participants {
+			assert.Equal(t, []frost.Identifier{2}, p.Disqualified())
+		}
+		checkSign(t, r, 0, 3)
+		checkSign(t, r, 1, 2)
+	}
+}
+
+// TestFalseComplaint checks that a participant cannot get an honest dealer
+// disqualified by complaining about it.
+func TestFalseComplaint(t *testing.T) {
+	net := newNetwork(2, 3)
+	net.complaints = func(c []*Complaint) []*Complaint {
+		return append(c, &Complaint{From: 1, Against: 3})
+	}
+	r := net.run(t)
+	for _, p := range net.participants {
+		assert.Empty(t, p.Disqualified())
+	}
+	checkSign(t, r, 0, 2)
+}
+
+func TestInvalidRound1(t *testing.T) {
+	// a dealer whose proof does not verify, e.g. because it copied its
+	// commitment from another dealer to cancel its secret, is disqualified
+	net := newNetwork(3, 4)
+	net.round1 = func(msgs []*Round1Message) {
+		msgs[3].Commitment = msgs[0].Commitment
+		msgs[1].Proof[40] ^= 1
+		msgs[2].Commitment = msgs[2].Commitment[:2]
+	}
+	r := net.run(t)
+	for i := range r.errs {
+		assert.Equal(t, ErrTooFewDealers, r.errs[i], "participant %d", i+1)
+	}
+
+	net = newNetwork(2, 4)
+	net.round1 = func(msgs []*Round1Message) {
+		msgs[3].Proof = msgs[0].Proof
+	}
+	r = net.run(t)
+	for _, p := range net.participants[:3] {
+		assert.Equal(t, []frost.Identifier{4}, p.Disqualified())
+	}
+	// a dealer does not check its own message
+	assert.Empty(t, net.participants[3].Disqualified())
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.errs[i])
+		assert.Equal(t, r.groups[0], r.groups[i])
+	}
+	checkSign(t, &result{shares: r.shares[:3], groups: r.groups[:3], errs: r.errs[:3]}, 0, 2)
+}
+
+// TestReplayedRound1 checks that a Round1Message from another session is
+// rejected, so that a dealer cannot reuse a commitment and proof it saw
+// there.
+func TestReplayedRound1(t *testing.T) {
+	_, replayed, err := New(rand.Reader, []byte("earlier session"), 3, 2, 3)
+	require.NoError(t, err)
+	net := newNetwork(2, 3)
+	net.round1 = func(msgs []*Round1Message) {
+		*msgs[2] = *replayed
+	}
+	r := net.run(t)
+	for _, p := range net.participants[:2] {
+		assert.Equal(t, []frost.Identifier{3}, p.Disqualified())
+	}
+	checkSign(t, &result{shares: r.shares[:2], groups: r.groups[:2], errs: r.errs[:2]}, 0, 1)
+}
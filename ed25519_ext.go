@@ -32,8 +32,10 @@ func ExtractPublicKey(message, sig []byte) (PublicKey, error) {
 	var hReduced [32]byte
 	edwards25519.ScReduce(&hReduced, &digest)
 
+	// h is computed from public values only, so it can be inverted in
+	// variable time
 	var hInv [32]byte
-	edwards25519.InvertModL(&hInv, &hReduced)
+	edwards25519.ScInvertVartime(&hInv, &hReduced)
 
 	var s [32]byte
 	if l := copy(s[:], sig[32:]); l != PublicKeySize {
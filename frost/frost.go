@@ -0,0 +1,475 @@
+// Copyright 2019 Spacemesh Authors
+// FROST threshold signatures
+
+// Package frost implements FROST(Ed25519, SHA-512), the two-round threshold
+// Schnorr signatures of RFC 9591: any t of the n holders of key shares can
+// produce an Ed25519 signature under the group key, which no single party
+// holds.
+//
+// Keys are split by a trusted dealer with Deal or SplitPrivateKey, or
+// generated without one by package dkg. To sign, each participant calls
+// Commit and sends its Commitment to a coordinator, which picks at least t
+// of them and sends the list and the message back. Each participant then
+// returns a signature share from Sign, and the coordinator combines them
+// with Aggregate, which checks every share and names the participants whose
+// shares are wrong.
+//
+// ModeEd25519 follows the RFC, and its signatures verify with
+// ed25519.Verify. ModeSign2 uses the challenge of ed25519.Sign2 instead, so
+// signatures verify with ed25519.Verify2 and ed25519.ExtractPublicKey
+// recovers the group key.
+package frost
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+	"github.com/spacemeshos/ed25519/internal/vss"
+)
+
+// contextString prefixes the hashes H1, H3, H4 and H5 of the ciphersuite.
+const contextString = "FROST-ED25519-SHA512-v1"
+
+// Mode selects the challenge of the signature.
+type Mode int
+
+const (
+	// ModeEd25519 hashes R || A || M, as RFC 9591 and ed25519.Sign do.
+	ModeEd25519 Mode = iota
+	// ModeSign2 hashes R || M, as ed25519.Sign2 does.
+	ModeSign2
+)
+
+var (
+	// ErrNonceReused is returned when Nonces are used a second time.
+	ErrNonceReused = errors.New("frost: nonces already used")
+	// ErrInvalidCommitment is returned for commitments that do not encode
+	// points of the prime-order subgroup other than the identity, lists of
+	// commitments that are too short or repeat a participant, and lists
+	// whose group commitment is the identity.
+	ErrInvalidCommitment = errors.New("frost: invalid commitment")
+)
+
+// Identifier is a participant's nonzero x-coordinate in the sharing of the
+// group key.
+type Identifier uint16
+
+// KeyShare is a participant's share of a group key.
+type KeyShare struct {
+	Identifier Identifier
+	// Secret is the signing share, a scalar mod l.
+	Secret [32]byte
+	// GroupPublicKey is the public key of the group.
+	GroupPublicKey ed25519.PublicKey
+	// Threshold is the number of participants needed to sign.
+	Threshold int
+}
+
+// Verify reports whether s is consistent with the commitment to the sharing
+// polynomial published by the dealer.
+func (s *KeyShare) Verify(commitment [][32]byte) bool {
+	if len(commitment) != s.Threshold || !bytes.Equal(commitment[0][:], s.GroupPublicKey) {
+		return false
+	}
+	// a zero share has the identity as its verifying share
+	if s.Secret == [32]byte{} || !validCommitment(commitment) {
+		return false
+	}
+	return vss.Commitment(commitment).VerifyShare(uint16(s.Identifier), &s.Secret)
+}
+
+// GroupKey holds the public information of a sharing: the group public key
+// and each participant's verifying share, the public key of its signing
+// share.
+type GroupKey struct {
+	PublicKey       ed25519.PublicKey
+	VerifyingShares map[Identifier][32]byte
+	Threshold       int
+}
+
+// NewGroupKey derives the GroupKey of participants 1 to n from the
+// commitment to the sharing polynomial.
+func NewGroupKey(commitment [][32]byte, n int) (*GroupKey, error) {
+	if len(commitment) == 0 || n < len(commitment) || n > 0xffff {
+		return nil, errors.New("frost: bad commitment or number of participants")
+	}
+	if !validCommitment(commitment) {
+		return nil, ErrInvalidCommitment
+	}
+	g := &GroupKey{
+		PublicKey:       append(ed25519.PublicKey{}, commitment[0][:]...),
+		VerifyingShares: make(map[Identifier][32]byte, n),
+		Threshold:       len(commitment),
+	}
+	for i := 1; i <= n; i++ {
+		share, err := vss.Commitment(commitment).Evaluate(uint16(i))
+		if err != nil || share == identity {
+			return nil, ErrInvalidCommitment
+		}
+		g.VerifyingShares[Identifier(i)] = share
+	}
+	return g, nil
+}
+
+// Deal splits secret, a scalar that is random if nil, into n shares of
+// which threshold can sign. It also returns the commitment to the sharing
+// polynomial, which participants check their share against with
+// KeyShare.Verify. The dealer learns the group secret and must be trusted;
+// package dkg avoids that. If rand is nil, crypto/rand.Reader will be used.
+func Deal(rand io.Reader, secret *[32]byte, threshold, n int) ([]*KeyShare, *GroupKey, [][32]byte, error) {
+	if threshold < 2 || n < threshold || n > 0xffff {
+		return nil, nil, nil, errors.New("frost: bad threshold " + strconv.Itoa(threshold) + " of " + strconv.Itoa(n))
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	p, err := vss.NewPolynomial(rand, secret, threshold)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	commitment := p.Commit()
+	group, err := NewGroupKey(commitment, n)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	shares := make([]*KeyShare, n)
+	for i := range shares {
+		shares[i] = &KeyShare{
+			Identifier:     Identifier(i + 1),
+			Secret:         p.Evaluate(uint16(i + 1)),
+			GroupPublicKey: group.PublicKey,
+			Threshold:      threshold,
+		}
+	}
+	return shares, group, commitment, nil
+}
+
+// SplitPrivateKey deals shares of the secret scalar of privateKey, so that
+// the group key is its public key, as Deal does. It will panic if
+// len(privateKey) is not PrivateKeySize.
+func SplitPrivateKey(rand io.Reader, privateKey ed25519.PrivateKey, threshold, n int) ([]*KeyShare, *GroupKey, [][32]byte, error) {
+	var secret [32]byte
+	copy(secret[:], ed25519.PrivateKeyToCurve25519(privateKey))
+	return Deal(rand, &secret, threshold, n)
+}
+
+// Commitment is the pair of nonce commitments a participant publishes in
+// the first round.
+type Commitment struct {
+	Identifier Identifier
+	Hiding     [32]byte
+	Binding    [32]byte
+}
+
+// Nonces holds the secret nonces of a Commitment. They can be used for a
+// single signature: Sign erases them and fails when called again. Reusing
+// them in two signatures reveals the signing share, so Nonces must not be
+// copied, stored or restored.
+type Nonces struct {
+	mu              sync.Mutex
+	hiding, binding [32]byte
+	commitment      Commitment
+	used            bool
+}
+
+// Commit returns fresh nonces of share for one signature, and their
+// commitment. If rand is nil, crypto/rand.Reader will be used.
+func Commit(rand io.Reader, share *KeyShare) (*Nonces, *Commitment, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	var hidingRandom, bindingRandom [32]byte
+	if _, err := io.ReadFull(rand, hidingRandom[:]); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(rand, bindingRandom[:]); err != nil {
+		return nil, nil, err
+	}
+	nonces := newNonces(share, &hidingRandom, &bindingRandom)
+	c := nonces.commitment
+	return nonces, &c, nil
+}
+
+func newNonces(share *KeyShare, hidingRandom, bindingRandom *[32]byte) *Nonces {
+	n := &Nonces{
+		hiding:     nonceGenerate(hidingRandom, &share.Secret),
+		binding:    nonceGenerate(bindingRandom, &share.Secret),
+		commitment: Commitment{Identifier: share.Identifier},
+	}
+	var P edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&P, &n.hiding)
+	P.ToBytes(&n.commitment.Hiding)
+	edwards25519.GeScalarMultBase(&P, &n.binding)
+	P.ToBytes(&n.commitment.Binding)
+	return n
+}
+
+// nonceGenerate is nonce_generate of the RFC: H3(random || secret).
+func nonceGenerate(random, secret *[32]byte) [32]byte {
+	return hashToScalar([]byte(contextString+"nonce"), random[:], secret[:])
+}
+
+// identity is the encoding of the neutral element.
+var identity = [32]byte{1}
+
+// decodeElement is DeserializeElement of the RFC: it decodes the canonical
+// encoding of a point of the prime-order subgroup other than the identity.
+func decodeElement(p *edwards25519.ExtendedGroupElement, b *[32]byte) bool {
+	if *b == identity || !p.FromBytes(b) {
+		return false
+	}
+	var canonical [32]byte
+	p.ToBytes(&canonical)
+	return canonical == *b && edwards25519.GeTorsionFree(p)
+}
+
+// validCommitment reports whether every point of the commitment to a
+// sharing polynomial decodes with decodeElement.
+func validCommitment(commitment [][32]byte) bool {
+	var P edwards25519.ExtendedGroupElement
+	for i := range commitment {
+		if !decodeElement(&P, &commitment[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hashToScalar returns SHA-512 of the concatenation of parts, reduced mod l.
+func hashToScalar(parts ...[]byte) [32]byte {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	var digest [64]byte
+	var s [32]byte
+	h.Sum(digest[:0])
+	edwards25519.ScReduce(&s, &digest)
+	return s
+}
+
+// signingPackage holds what the participants in a signature derive from
+// the commitments and the message.
+type signingPackage struct {
+	commitments    []Commitment
+	identifiers    []uint16
+	bindingFactors [][32]byte
+	// r is the group commitment, the R of the signature
+	r         [32]byte
+	challenge [32]byte
+	hiding    []edwards25519.ExtendedGroupElement
+	binding   []edwards25519.ExtendedGroupElement
+}
+
+func newSigningPackage(groupPublicKey []byte, threshold int, commitments []Commitment, message []byte, mode Mode) (*signingPackage, error) {
+	if len(commitments) < threshold || len(groupPublicKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidCommitment
+	}
+	p := &signingPackage{
+		commitments:    append([]Commitment{}, commitments...),
+		identifiers:    make([]uint16, len(commitments)),
+		bindingFactors: make([][32]byte, len(commitments)),
+		hiding:         make([]edwards25519.ExtendedGroupElement, len(commitments)),
+		binding:        make([]edwards25519.ExtendedGroupElement, len(commitments)),
+	}
+	sort.Slice(p.commitments, func(i, j int) bool {
+		return p.commitments[i].Identifier < p.commitments[j].Identifier
+	})
+
+	// encode_group_commitment_list
+	var encoded []byte
+	for i := range p.commitments {
+		c := &p.commitments[i]
+		if c.Identifier == 0 || i > 0 && c.Identifier == p.commitments[i-1].Identifier {
+			return nil, ErrInvalidCommitment
+		}
+		if !decodeElement(&p.hiding[i], &c.Hiding) || !decodeElement(&p.binding[i], &c.Binding) {
+			return nil, ErrInvalidCommitment
+		}
+		p.identifiers[i] = uint16(c.Identifier)
+		id := vss.Scalar(uint16(c.Identifier))
+		encoded = append(encoded, id[:]...)
+		encoded = append(encoded, c.Hiding[:]...)
+		encoded = append(encoded, c.Binding[:]...)
+	}
+
+	// compute_binding_factors
+	messageHash := sha512.Sum512(append([]byte(contextString+"msg"), message...))
+	commitmentHash := sha512.Sum512(append([]byte(contextString+"com"), encoded...))
+	for i := range p.commitments {
+		id := vss.Scalar(p.identifiers[i])
+		p.bindingFactors[i] = hashToScalar([]byte(contextString+"rho"), groupPublicKey, messageHash[:], commitmentHash[:], id[:])
+	}
+
+	// compute_group_commitment: Σ hiding + binding_factor·binding
+	n := len(p.commitments)
+	scalars := make([][32]byte, 2*n)
+	points := make([]edwards25519.ExtendedGroupElement, 2*n)
+	for i := 0; i < n; i++ {
+		scalars[2*i] = [32]byte{1}
+		scalars[2*i+1] = p.bindingFactors[i]
+		points[2*i] = p.hiding[i]
+		points[2*i+1] = p.binding[i]
+	}
+	var R edwards25519.ExtendedGroupElement
+	edwards25519.GeMultiScalarMultVartime(&R, scalars, points)
+	R.ToBytes(&p.r)
+	if p.r == identity {
+		return nil, ErrInvalidCommitment
+	}
+
+	// compute_challenge
+	if mode == ModeSign2 {
+		p.challenge = hashToScalar(p.r[:], message)
+	} else {
+		p.challenge = hashToScalar(p.r[:], groupPublicKey, message)
+	}
+	return p, nil
+}
+
+// index returns the position of the commitment of id.
+func (p *signingPackage) index(id Identifier) int {
+	i := sort.Search(len(p.commitments), func(i int) bool {
+		return p.commitments[i].Identifier >= id
+	})
+	if i == len(p.commitments) || p.commitments[i].Identifier != id {
+		return -1
+	}
+	return i
+}
+
+// Sign returns the signature share of share for message, with the nonces of
+// its commitment, which must be in commitments. nonces are erased and
+// cannot be used again.
+func Sign(share *KeyShare, nonces *Nonces, commitments []Commitment, message []byte, mode Mode) ([]byte, error) {
+	p, err := newSigningPackage(share.GroupPublicKey, share.Threshold, commitments, message, mode)
+	if err != nil {
+		return nil, err
+	}
+	i := p.index(share.Identifier)
+	if i < 0 {
+		return nil, errors.New("frost: no commitment of participant " + strconv.Itoa(int(share.Identifier)))
+	}
+
+	nonces.mu.Lock()
+	defer nonces.mu.Unlock()
+	if nonces.used {
+		return nil, ErrNonceReused
+	}
+	if nonces.commitment != p.commitments[i] {
+		return nil, errors.New("frost: commitment does not match the nonces")
+	}
+	nonces.used = true
+	hiding, binding := nonces.hiding, nonces.binding
+	nonces.hiding, nonces.binding = [32]byte{}, [32]byte{}
+
+	lambda, err := vss.Lagrange(uint16(share.Identifier), p.identifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	// hiding + binding·binding_factor + lambda·secret·challenge
+	var lc, r, z [32]byte
+	edwards25519.ScMul(&lc, &lambda, &p.challenge)
+	edwards25519.ScMulAdd(&r, &binding, &p.bindingFactors[i], &hiding)
+	edwards25519.ScMulAdd(&z, &lc, &share.Secret, &r)
+	return z[:], nil
+}
+
+// verifyShare checks z·B = hiding + binding_factor·binding +
+// challenge·lambda·PK of the participant at index i.
+func (p *signingPackage) verifyShare(i int, verifyingShare *[32]byte, share []byte) bool {
+	var z [32]byte
+	if len(share) != 32 {
+		return false
+	}
+	copy(z[:], share)
+	if !edwards25519.ScMinimal(&z) {
+		return false
+	}
+	var PK edwards25519.ExtendedGroupElement
+	if !decodeElement(&PK, verifyingShare) {
+		return false
+	}
+	lambda, err := vss.Lagrange(p.identifiers[i], p.identifiers)
+	if err != nil {
+		return false
+	}
+	var lc [32]byte
+	edwards25519.ScMul(&lc, &lambda, &p.challenge)
+
+	var sum, zB edwards25519.ExtendedGroupElement
+	points := []edwards25519.ExtendedGroupElement{p.hiding[i], p.binding[i], PK}
+	edwards25519.GeMultiScalarMultVartime(&sum, [][32]byte{{1}, p.bindingFactors[i], lc}, points)
+	edwards25519.GeScalarMultBase(&zB, &z)
+	var got, want [32]byte
+	sum.ToBytes(&want)
+	zB.ToBytes(&got)
+	return got == want
+}
+
+// InvalidSharesError lists the participants whose signature shares failed
+// verification, or are missing.
+type InvalidSharesError struct {
+	Identifiers []Identifier
+}
+
+func (e *InvalidSharesError) Error() string {
+	ids := make([]string, len(e.Identifiers))
+	for i, id := range e.Identifiers {
+		ids[i] = strconv.Itoa(int(id))
+	}
+	return "frost: invalid signature shares from participants " + strings.Join(ids, ", ")
+}
+
+// VerifyShare reports whether share is the valid signature share of the
+// participant id, whose commitment is in commitments.
+func VerifyShare(group *GroupKey, id Identifier, share []byte, commitments []Commitment, message []byte, mode Mode) bool {
+	p, err := newSigningPackage(group.PublicKey, group.Threshold, commitments, message, mode)
+	if err != nil {
+		return false
+	}
+	verifyingShare, ok := group.VerifyingShares[id]
+	i := p.index(id)
+	return ok && i >= 0 && p.verifyShare(i, &verifyingShare, share)
+}
+
+// Aggregate checks the signature shares of the participants of commitments
+// and returns their signature of message. If any share is missing or
+// invalid, it returns an *InvalidSharesError naming their participants.
+func Aggregate(group *GroupKey, commitments []Commitment, shares map[Identifier][]byte, message []byte, mode Mode) ([]byte, error) {
+	p, err := newSigningPackage(group.PublicKey, group.Threshold, commitments, message, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	var z, zi [32]byte
+	invalid := new(InvalidSharesError)
+	for i, c := range p.commitments {
+		share, ok := shares[c.Identifier]
+		verifyingShare, known := group.VerifyingShares[c.Identifier]
+		if !ok || !known || !p.verifyShare(i, &verifyingShare, share) {
+			invalid.Identifiers = append(invalid.Identifiers, c.Identifier)
+			continue
+		}
+		copy(zi[:], share)
+		edwards25519.ScAdd(&z, &z, &zi)
+	}
+	if len(invalid.Identifiers) > 0 {
+		return nil, invalid
+	}
+
+	signature := make([]byte, ed25519.SignatureSize)
+	copy(signature, p.r[:])
+	copy(signature[32:], z[:])
+	return signature, nil
+}
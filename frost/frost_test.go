@@ -0,0 +1,324 @@
+// Copyright 2019 Spacemesh Authors
+// FROST unit tests
+
+package frost
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+	"github.com/spacemeshos/ed25519/internal/vss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustHex32(t *testing.T, s string) [32]byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	require.Len(t, b, 32)
+	var out [32]byte
+	copy(out[:], b)
+	return out
+}
+
+// TestRFC9591 checks the FROST(Ed25519, SHA-512) vectors of RFC 9591,
+// appendix E.1: 2 of 3 shares, participants 1 and 3 signing "test".
+func TestRFC9591(t *testing.T) {
+	p := vss.Polynomial{
+		mustHex32(t, "7b1c33d3f5291d85de664833beb1ad469f7fb6025a0ec78b3a790c6e13a98304"),
+		mustHex32(t, "178199860edd8c62f5212ee91eff1295d0d670ab4ed4506866bae57e7030b204"),
+	}
+	group, err := NewGroupKey(p.Commit(), 3)
+	require.NoError(t, err)
+	assert.Equal(t, "15d21ccd7ee42959562fc8aa63224c8851fb3ec85a3faf66040d380fb9738673", hex.EncodeToString(group.PublicKey))
+
+	wantShares := []string{
+		"929dcc590407aae7d388761cddb0c0db6f5627aea8e217f4a033f2ec83d93509",
+		"a91e66e012e4364ac9aaa405fcafd370402d9859f7b6685c07eed76bf409e80d",
+		"d3cb090a075eb154e82fdb4b3cb507f110040905468bb9c46da8bdea643a9a02",
+	}
+	shares := make([]*KeyShare, 3)
+	for i := range shares {
+		shares[i] = &KeyShare{
+			Identifier:     Identifier(i + 1),
+			Secret:         p.Evaluate(uint16(i + 1)),
+			GroupPublicKey: group.PublicKey,
+			Threshold:      2,
+		}
+		assert.Equal(t, wantShares[i], hex.EncodeToString(shares[i].Secret[:]))
+		assert.True(t, shares[i].Verify(p.Commit()))
+	}
+
+	hiding1 := mustHex32(t, "0fd2e39e111cdc266f6c0f4d0fd45c947761f1f5d3cb583dfcb9bbaf8d4c9fec")
+	binding1 := mustHex32(t, "69cd85f631d5f7f2721ed5e40519b1366f340a87c2f6856363dbdcda348a7501")
+	hiding3 := mustHex32(t, "86d64a260059e495d0fb4fcc17ea3da7452391baa494d4b00321098ed2a0062f")
+	binding3 := mustHex32(t, "13e6b25afb2eba51716a9a7d44130c0dbae0004a9ef8d7b5550c8a0e07c61775")
+	nonces1 := newNonces(shares[0], &hiding1, &binding1)
+	nonces3 := newNonces(shares[2], &hiding3, &binding3)
+	assert.Equal(t, "812d6104142944d5a55924de6d49940956206909f2acaeedecda2b726e630407", hex.EncodeToString(nonces1.hiding[:]))
+	assert.Equal(t, "b1110165fc2334149750b28dd813a39244f315cff14d4e89e6142f262ed83301", hex.EncodeToString(nonces1.binding[:]))
+	assert.Equal(t, "b5aa8ab305882a6fc69cbee9327e5a45e54c08af61ae77cb8207be3d2ce13de3", hex.EncodeToString(nonces1.commitment.Hiding[:]))
+	assert.Equal(t, "67e98ab55aa310c3120418e5050c9cf76cf387cb20ac9e4b6fdb6f82a469f932", hex.EncodeToString(nonces1.commitment.Binding[:]))
+
+	message := []byte("test")
+	commitments := []Commitment{nonces3.commitment, nonces1.commitment}
+	share1, err := Sign(shares[0], nonces1, commitments, message, ModeEd25519)
+	require.NoError(t, err)
+	share3, err := Sign(shares[2], nonces3, commitments, message, ModeEd25519)
+	require.NoError(t, err)
+	assert.Equal(t, "001719ab5a53ee1a12095cd088fd149702c0720ce5fd2f29dbecf24b7281b603", hex.EncodeToString(share1))
+	assert.Equal(t, "bd86125de990acc5e1f13781d8e32c03a9bbd4c53539bbc106058bfd14326007", hex.EncodeToString(share3))
+
+	sig, err := Aggregate(group, commitments, map[Identifier][]byte{1: share1, 3: share3}, message, ModeEd25519)
+	require.NoError(t, err)
+	assert.Equal(t, "36282629c383bb820a88b71cae937d41f2f2adfcc3d02e55507e2fb9e2dd3cbe"+
+		"bd9d2b0844e49ae0f3fa935161e1419aab7b47d21a37ebeae1f17d4987b3160b", hex.EncodeToString(sig))
+	assert.True(t, ed25519.Verify(group.PublicKey, message, sig))
+}
+
+// sign runs both rounds for the participants of signers and returns the
+// commitments and the signature shares.
+func sign(t *testing.T, signers []*KeyShare, message []byte, mode Mode) ([]Commitment, map[Identifier][]byte) {
+	nonces := make([]*Nonces, len(signers))
+	commitments := make([]Commitment, len(signers))
+	for i, s := range signers {
+		var c *Commitment
+		var err error
+		nonces[i], c, err = Commit(nil, s)
+		require.NoError(t, err)
+		commitments[i] = *c
+	}
+	shares := make(map[Identifier][]byte, len(signers))
+	for i, s := range signers {
+		share, err := Sign(s, nonces[i], commitments, message, mode)
+		require.NoError(t, err)
+		shares[s.Identifier] = share
+	}
+	return commitments, shares
+}
+
+func TestThresholdSign(t *testing.T) {
+	shares, group, _, err := Deal(rand.Reader, nil, 3, 5)
+	require.NoError(t, err)
+	message := []byte("treasury transfer")
+
+	for _, signers := range [][]*KeyShare{shares[:3], {shares[4], shares[1], shares[2]}, shares} {
+		commitments, sigShares := sign(t, signers, message, ModeEd25519)
+		for id, share := range sigShares {
+			assert.True(t, VerifyShare(group, id, share, commitments, message, ModeEd25519))
+		}
+		sig, err := Aggregate(group, commitments, sigShares, message, ModeEd25519)
+		require.NoError(t, err)
+		assert.True(t, ed25519.Verify(group.PublicKey, message, sig))
+		assert.False(t, ed25519.Verify2(group.PublicKey, message, sig))
+	}
+
+	// fewer than threshold cannot sign
+	nonces, c, err := Commit(rand.Reader, shares[0])
+	require.NoError(t, err)
+	_, err = Sign(shares[0], nonces, []Commitment{*c}, message, ModeEd25519)
+	assert.Equal(t, ErrInvalidCommitment, err)
+
+	_, _, _, err = Deal(rand.Reader, nil, 4, 3)
+	assert.Error(t, err)
+	_, _, _, err = Deal(rand.Reader, nil, 1, 3)
+	assert.Error(t, err)
+}
+
+func TestSign2Mode(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	shares, group, commitment, err := SplitPrivateKey(rand.Reader, privateKey, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, privateKey.Public(), group.PublicKey)
+	for _, s := range shares {
+		assert.True(t, s.Verify(commitment))
+	}
+
+	message := []byte("threshold account transfer")
+	commitments, sigShares := sign(t, shares[1:], message, ModeSign2)
+	sig, err := Aggregate(group, commitments, sigShares, message, ModeSign2)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify2(group.PublicKey, message, sig))
+	assert.False(t, ed25519.Verify(group.PublicKey, message, sig))
+
+	extracted, err := ed25519.ExtractPublicKey(message, sig)
+	require.NoError(t, err)
+	assert.Equal(t, group.PublicKey, extracted)
+}
+
+func TestMisbehavingParticipants(t *testing.T) {
+	shares, group, commitment, err := Deal(rand.Reader, nil, 3, 4)
+	require.NoError(t, err)
+	message := []byte("treasury transfer")
+
+	// a dealer handing out a wrong share is caught
+	bad := *shares[1]
+	bad.Secret[0] ^= 1
+	assert.False(t, bad.Verify(commitment))
+	assert.False(t, shares[1].Verify(commitment[:2]))
+
+	// wrong signature shares are caught and blamed on their participants
+	commitments, sigShares := sign(t, shares, message, ModeEd25519)
+	sigShares[2] = append([]byte{}, sigShares[2]...)
+	sigShares[2][0] ^= 1
+	sigShares[4] = sigShares[1]
+	assert.False(t, VerifyShare(group, 2, sigShares[2], commitments, message, ModeEd25519))
+	assert.False(t, VerifyShare(group, 2, sigShares[3], commitments, message, ModeEd25519))
+	assert.False(t, VerifyShare(group, 3, sigShares[3], commitments, []byte("another message"), ModeEd25519))
+	assert.False(t, VerifyShare(group, 3, sigShares[3], commitments, message, ModeSign2))
+	assert.True(t, VerifyShare(group, 3, sigShares[3], commitments, message, ModeEd25519))
+
+	_, err = Aggregate(group, commitments, sigShares, message, ModeEd25519)
+	var invalid *InvalidSharesError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []Identifier{2, 4}, invalid.Identifiers)
+	assert.Contains(t, err.Error(), "participants 2, 4")
+
+	// as is a missing share
+	delete(sigShares, 2)
+	sigShares[4] = nil
+	_, err = Aggregate(group, commitments, sigShares, message, ModeEd25519)
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []Identifier{2, 4}, invalid.Identifiers)
+
+	// malformed commitment lists are rejected
+	duplicate := append([]Commitment{}, commitments...)
+	duplicate[1].Identifier = duplicate[0].Identifier
+	_, err = Aggregate(group, duplicate, sigShares, message, ModeEd25519)
+	assert.Equal(t, ErrInvalidCommitment, err)
+	invalidPoint := append([]Commitment{}, commitments...)
+	invalidPoint[0].Binding = [32]byte{2}
+	_, err = Aggregate(group, invalidPoint, sigShares, message, ModeEd25519)
+	assert.Equal(t, ErrInvalidCommitment, err)
+}
+
+// smallOrder is a point of order 8.
+const smallOrder = "c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a"
+
+// withTorsion returns the encoding of P + T, for T of order 8.
+func withTorsion(t *testing.T, P [32]byte) [32]byte {
+	T := mustHex32(t, smallOrder)
+	var p, q edwards25519.ExtendedGroupElement
+	require.True(t, p.FromBytes(&P))
+	require.True(t, q.FromBytes(&T))
+	edwards25519.GeAdd(&p, &p, &q)
+	var out [32]byte
+	p.ToBytes(&out)
+	return out
+}
+
+// TestInvalidElements checks that commitments and verifying shares are
+// rejected if they are the identity or not in the prime-order subgroup, as
+// DeserializeElement of RFC 9591 requires.
+func TestInvalidElements(t *testing.T) {
+	shares, group, commitment, err := Deal(rand.Reader, nil, 2, 3)
+	require.NoError(t, err)
+	message := []byte("treasury transfer")
+	commitments, sigShares := sign(t, shares[:2], message, ModeEd25519)
+	_, err = Aggregate(group, commitments, sigShares, message, ModeEd25519)
+	require.NoError(t, err)
+
+	nonCanonical := mustHex32(t, "eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")
+	for _, bad := range [][32]byte{
+		identity,
+		nonCanonical,
+		mustHex32(t, smallOrder),
+		withTorsion(t, commitments[1].Hiding),
+	} {
+		for _, binding := range []bool{false, true} {
+			tampered := append([]Commitment{}, commitments...)
+			if binding {
+				tampered[1].Binding = bad
+			} else {
+				tampered[1].Hiding = bad
+			}
+			_, err = Aggregate(group, tampered, sigShares, message, ModeEd25519)
+			assert.Equal(t, ErrInvalidCommitment, err)
+			assert.False(t, VerifyShare(group, shares[0].Identifier, sigShares[shares[0].Identifier], tampered, message, ModeEd25519))
+		}
+
+		// the same holds for the commitment to the sharing polynomial
+		for i := range commitment {
+			tampered := append([][32]byte{}, commitment...)
+			tampered[i] = bad
+			_, err = NewGroupKey(tampered, 3)
+			assert.Equal(t, ErrInvalidCommitment, err)
+			assert.False(t, shares[0].Verify(tampered))
+		}
+
+		// and for verifying shares
+		tampered := &GroupKey{PublicKey: group.PublicKey, VerifyingShares: map[Identifier][32]byte{}, Threshold: 2}
+		for id, share := range group.VerifyingShares {
+			tampered.VerifyingShares[id] = share
+		}
+		tampered.VerifyingShares[shares[0].Identifier] = bad
+		_, err = Aggregate(tampered, commitments, sigShares, message, ModeEd25519)
+		var invalid *InvalidSharesError
+		require.True(t, errors.As(err, &invalid))
+		assert.Equal(t, []Identifier{shares[0].Identifier}, invalid.Identifiers)
+	}
+
+	// a polynomial a - a·x gives participant 1 the identity as verifying
+	// share, and a zero signing share
+	var a [32]byte
+	a[0] = 7
+	var A, minusA edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&A, &a)
+	edwards25519.GeNeg(&minusA, &A)
+	zeroAt1 := make([][32]byte, 2)
+	A.ToBytes(&zeroAt1[0])
+	minusA.ToBytes(&zeroAt1[1])
+	_, err = NewGroupKey(zeroAt1, 3)
+	assert.Equal(t, ErrInvalidCommitment, err)
+	zero := &KeyShare{Identifier: 1, GroupPublicKey: zeroAt1[0][:], Threshold: 2}
+	assert.True(t, vss.Commitment(zeroAt1).VerifyShare(1, &zero.Secret))
+	assert.False(t, zero.Verify(zeroAt1))
+}
+
+func TestNonceReuse(t *testing.T) {
+	shares, _, _, err := Deal(rand.Reader, nil, 2, 3)
+	require.NoError(t, err)
+	nonces1, c1, err := Commit(rand.Reader, shares[0])
+	require.NoError(t, err)
+	nonces2, c2, err := Commit(rand.Reader, shares[1])
+	require.NoError(t, err)
+	commitments := []Commitment{*c1, *c2}
+
+	_, err = Sign(shares[0], nonces1, commitments, []byte("first message"), ModeEd25519)
+	require.NoError(t, err)
+	_, err = Sign(shares[0], nonces1, commitments, []byte("second message"), ModeEd25519)
+	assert.Equal(t, ErrNonceReused, err)
+	assert.Equal(t, [32]byte{}, nonces1.hiding)
+	assert.Equal(t, [32]byte{}, nonces1.binding)
+
+	// nonces only sign with their own commitment, of their own participant
+	_, err = Sign(shares[0], nonces2, commitments, []byte("first message"), ModeEd25519)
+	assert.Error(t, err)
+	_, err = Sign(shares[2], nonces2, commitments, []byte("first message"), ModeEd25519)
+	assert.Error(t, err)
+	other := *c2
+	other.Hiding = c1.Hiding
+	_, err = Sign(shares[1], nonces2, []Commitment{*c1, other}, []byte("first message"), ModeEd25519)
+	assert.Error(t, err)
+	_, err = Sign(shares[1], nonces2, commitments, []byte("first message"), ModeEd25519)
+	assert.NoError(t, err)
+}
+
+func BenchmarkSign(b *testing.B) {
+	shares, _, _, err := Deal(rand.Reader, nil, 2, 3)
+	require.NoError(b, err)
+	_, c, err := Commit(rand.Reader, shares[1])
+	require.NoError(b, err)
+	message := []byte("treasury transfer")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nonces, own, _ := Commit(nil, shares[0])
+		_, _ = Sign(shares[0], nonces, []Commitment{*own, *c}, message, ModeEd25519)
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2019 Spacemesh Authors
+// Mutually authenticated handshake with Sign2 identities
+
+// Package handshake establishes mutually authenticated, encrypted sessions
+// over a net.Conn between peers identified by Ed25519 keys, without sending
+// the keys themselves: each peer recovers the other's identity from a Sign2
+// signature with ed25519.ExtractPublicKey.
+//
+// The initiator (Client) and the responder (Server) exchange three messages:
+//
+//	-> e_i
+//	<- e_r, AEAD(Sign2(s_r, "responder" || h))
+//	-> AEAD(Sign2(s_i, "initiator" || h || A_r))
+//
+// where e_i and e_r are ephemeral X25519 public keys, h is the SHA-512 hash
+// of the protocol name and the ephemeral keys, A_r is the responder's
+// identity and AEAD is AES-256-GCM under a key derived from the ephemeral
+// X25519 secret. Encrypting the signatures hides the identities from passive
+// observers, and binds them to the key exchange: only the party holding the
+// other ephemeral key can read or produce them.
+//
+// Any signature extracts to some public key, so a peer is only authenticated
+// when its identity is in the allowlist; the handshake fails otherwise.
+//
+// Session data is sent in frames of a 2-byte big-endian length followed by an
+// AES-256-GCM ciphertext, each direction with its own key and a counter
+// nonce.
+package handshake
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/hkdf"
+)
+
+const (
+	protocolName = "spacemesh-handshake-v1"
+
+	keySize   = ed25519.X25519Size
+	tagSize   = 16
+	nonceSize = 12
+
+	// MaxFrameSize is the largest payload of a session frame. Longer writes
+	// are split.
+	MaxFrameSize = 65535 - tagSize
+)
+
+var (
+	// ErrHandshake is returned when the peer's handshake messages are
+	// invalid.
+	ErrHandshake = errors.New("handshake: handshake failed")
+	// ErrPeerNotAllowed is returned when the peer's identity is not in the
+	// allowlist.
+	ErrPeerNotAllowed = errors.New("handshake: peer not allowed")
+	// ErrFrame is returned when a session frame fails authentication.
+	ErrFrame = errors.New("handshake: message authentication failed")
+)
+
+// Config configures one side of a handshake.
+type Config struct {
+	// PrivateKey is the local identity.
+	PrivateKey ed25519.PrivateKey
+	// AllowedPeers are the identities accepted from the peer.
+	AllowedPeers []ed25519.PublicKey
+	// Rand is the source of the ephemeral keys, crypto/rand.Reader if nil.
+	Rand io.Reader
+}
+
+func (c *Config) check() error {
+	if l := len(c.PrivateKey); l != ed25519.PrivateKeySize {
+		return errors.New("handshake: bad private key length: " + strconv.Itoa(l))
+	}
+	return nil
+}
+
+func (c *Config) allowed(peer ed25519.PublicKey) bool {
+	for _, k := range c.AllowedPeers {
+		if bytes.Equal(k, peer) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) rand() io.Reader {
+	if c.Rand == nil {
+		return cryptorand.Reader
+	}
+	return c.Rand
+}
+
+// Conn is an established session. Reads and writes are encrypted; the other
+// net.Conn methods act on the underlying connection.
+type Conn struct {
+	net.Conn
+	peer ed25519.PublicKey
+
+	readMu   sync.Mutex
+	recv     cipher.AEAD
+	recvSeq  uint64
+	readBuf  []byte
+	frameBuf []byte
+	readErr  error
+
+	writeMu  sync.Mutex
+	send     cipher.AEAD
+	sendSeq  uint64
+	writeErr error
+}
+
+// PeerPublicKey returns the peer's identity, recovered during the handshake.
+func (c *Conn) PeerPublicKey() ed25519.PublicKey {
+	return c.peer
+}
+
+// state is the key exchange state shared by both sides.
+type state struct {
+	hash   []byte // h
+	secret []byte
+	aead   cipher.AEAD
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newState derives the handshake key from the ephemeral X25519 secret.
+func newState(secret, initiatorE, responderE []byte) (*state, error) {
+	h := sha512.New()
+	h.Write([]byte(protocolName))
+	h.Write(initiatorE)
+	h.Write(responderE)
+	s := &state{hash: h.Sum(nil), secret: secret}
+	key, err := hkdf.Key(sha512.New, secret, s.hash, []byte(protocolName+" handshake"), 32)
+	if err != nil {
+		return nil, err
+	}
+	s.aead, err = newAEAD(key)
+	return s, err
+}
+
+// handshakeNonce returns the nonce of the n-th encrypted handshake message.
+func handshakeNonce(n byte) []byte {
+	nonce := make([]byte, nonceSize)
+	nonce[nonceSize-1] = n
+	return nonce
+}
+
+// responderMessage and initiatorMessage return the messages signed by each
+// side.
+func (s *state) responderMessage() []byte {
+	return append([]byte(protocolName+" responder"), s.hash...)
+}
+
+func (s *state) initiatorMessage(responder ed25519.PublicKey) []byte {
+	m := append([]byte(protocolName+" initiator"), s.hash...)
+	return append(m, responder...)
+}
+
+// open decrypts the n-th handshake message, extracts the signer's identity
+// and checks it against the allowlist.
+func (s *state) open(config *Config, n byte, ciphertext, message []byte) (ed25519.PublicKey, error) {
+	sig, err := s.aead.Open(nil, handshakeNonce(n), ciphertext, s.hash)
+	if err != nil {
+		return nil, ErrHandshake
+	}
+	peer, err := ed25519.ExtractPublicKey(message, sig)
+	if err != nil || !ed25519.Verify2(peer, message, sig) {
+		return nil, ErrHandshake
+	}
+	if !config.allowed(peer) {
+		return nil, ErrPeerNotAllowed
+	}
+	return peer, nil
+}
+
+// session derives the session keys, bound to both identities, and returns
+// the established Conn.
+func (s *state) session(conn net.Conn, initiator, responder ed25519.PublicKey, isInitiator bool) (*Conn, error) {
+	salt := append(append(append([]byte(nil), s.hash...), initiator...), responder...)
+	prk := hkdf.Extract(sha512.New, s.secret, salt)
+	keys, err := hkdf.Expand(sha512.New, prk, []byte(protocolName+" session"), 64)
+	if err != nil {
+		return nil, err
+	}
+	i2r, err := newAEAD(keys[:32])
+	if err != nil {
+		return nil, err
+	}
+	r2i, err := newAEAD(keys[32:])
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{Conn: conn, send: i2r, recv: r2i, peer: responder}
+	if !isInitiator {
+		c.send, c.recv, c.peer = r2i, i2r, initiator
+	}
+	return c, nil
+}
+
+// ephemeral returns a fresh X25519 scalar and its public key.
+func ephemeral(rand io.Reader) (scalar, public []byte, err error) {
+	scalar = make([]byte, keySize)
+	if _, err := io.ReadFull(rand, scalar); err != nil {
+		return nil, nil, err
+	}
+	public, err = ed25519.X25519(scalar, ed25519.Basepoint)
+	return scalar, public, err
+}
+
+// Client runs the initiator side of the handshake on conn. It returns once
+// the responder's identity is authenticated. The responder checks the
+// client's identity on receiving the last message, so a rejected client only
+// notices when the connection is closed.
+func Client(conn net.Conn, config *Config) (*Conn, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	scalar, e, err := ephemeral(config.rand())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(e); err != nil {
+		return nil, err
+	}
+
+	msg2 := make([]byte, keySize+ed25519.SignatureSize+tagSize)
+	if _, err := io.ReadFull(conn, msg2); err != nil {
+		return nil, err
+	}
+	peerE := msg2[:keySize]
+	secret, err := ed25519.X25519(scalar, peerE)
+	if err != nil {
+		return nil, ErrHandshake
+	}
+	s, err := newState(secret, e, peerE)
+	if err != nil {
+		return nil, err
+	}
+	responder, err := s.open(config, 0, msg2[keySize:], s.responderMessage())
+	if err != nil {
+		return nil, err
+	}
+
+	sig := ed25519.Sign2(config.PrivateKey, s.initiatorMessage(responder))
+	if _, err := conn.Write(s.aead.Seal(nil, handshakeNonce(1), sig, s.hash)); err != nil {
+		return nil, err
+	}
+	return s.session(conn, config.PrivateKey.Public().(ed25519.PublicKey), responder, true)
+}
+
+// Server runs the responder side of the handshake on conn. On error the
+// caller should close conn.
+func Server(conn net.Conn, config *Config) (*Conn, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	peerE := make([]byte, keySize)
+	if _, err := io.ReadFull(conn, peerE); err != nil {
+		return nil, err
+	}
+	scalar, e, err := ephemeral(config.rand())
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ed25519.X25519(scalar, peerE)
+	if err != nil {
+		return nil, ErrHandshake
+	}
+	s, err := newState(secret, peerE, e)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := ed25519.Sign2(config.PrivateKey, s.responderMessage())
+	if _, err := conn.Write(s.aead.Seal(e, handshakeNonce(0), sig, s.hash)); err != nil {
+		return nil, err
+	}
+
+	msg3 := make([]byte, ed25519.SignatureSize+tagSize)
+	if _, err := io.ReadFull(conn, msg3); err != nil {
+		return nil, err
+	}
+	responder := config.PrivateKey.Public().(ed25519.PublicKey)
+	initiator, err := s.open(config, 1, msg3, s.initiatorMessage(responder))
+	if err != nil {
+		return nil, err
+	}
+	return s.session(conn, initiator, responder, false)
+}
+
+// sessionNonce returns the counter nonce of the seq-th frame.
+func sessionNonce(seq uint64) []byte {
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce
+}
+
+// Read reads decrypted session data. Errors, including timeouts and frames
+// that fail authentication, are permanent: the frame stream cannot be
+// resynchronized.
+func (c *Conn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(c.readBuf) == 0 {
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+		c.readErr = c.readFrame()
+	}
+	n := copy(p, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+	return n, nil
+}
+
+func (c *Conn) readFrame() error {
+	var header [2]byte
+	if _, err := io.ReadFull(c.Conn, header[:]); err != nil {
+		return err
+	}
+	size := int(binary.BigEndian.Uint16(header[:]))
+	if size < tagSize {
+		return ErrFrame
+	}
+	if cap(c.frameBuf) < size {
+		c.frameBuf = make([]byte, size)
+	}
+	frame := c.frameBuf[:size]
+	if _, err := io.ReadFull(c.Conn, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if c.recvSeq == ^uint64(0) {
+		return ErrFrame
+	}
+	plaintext, err := c.recv.Open(frame[:0], sessionNonce(c.recvSeq), frame, header[:])
+	if err != nil {
+		return ErrFrame
+	}
+	c.recvSeq++
+	c.readBuf = plaintext
+	return nil
+}
+
+// Write encrypts and writes p, in frames of at most MaxFrameSize bytes. Like
+// Read, errors are permanent.
+func (c *Conn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.writeErr != nil {
+		return 0, c.writeErr
+	}
+	n := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > MaxFrameSize {
+			chunk = chunk[:MaxFrameSize]
+		}
+		if c.sendSeq == ^uint64(0) {
+			c.writeErr = errors.New("handshake: too many frames")
+			return n, c.writeErr
+		}
+		frame := make([]byte, 2, 2+len(chunk)+tagSize)
+		binary.BigEndian.PutUint16(frame, uint16(len(chunk)+tagSize))
+		frame = c.send.Seal(frame, sessionNonce(c.sendSeq), chunk, frame[:2])
+		c.sendSeq++
+		if _, err := c.Conn.Write(frame); err != nil {
+			c.writeErr = err
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2019 Spacemesh Authors
+// Handshake unit tests
+
+package handshake
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type peer struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func newPeer(t *testing.T) peer {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return peer{pub, priv}
+}
+
+type result struct {
+	conn *Conn
+	err  error
+}
+
+// run performs a handshake between client and server over the two ends of
+// a connection.
+func run(clientConn, serverConn net.Conn, client, server *Config) (result, result) {
+	done := make(chan result, 1)
+	go func() {
+		c, err := Server(serverConn, server)
+		if err != nil {
+			serverConn.Close()
+		}
+		done <- result{c, err}
+	}()
+	c, err := Client(clientConn, client)
+	if err != nil {
+		clientConn.Close()
+	}
+	return result{c, err}, <-done
+}
+
+func TestHandshake(t *testing.T) {
+	alice, bob := newPeer(t), newPeer(t)
+	a, b := net.Pipe()
+	client, server := run(a, b,
+		&Config{PrivateKey: alice.priv, AllowedPeers: []ed25519.PublicKey{bob.pub}},
+		&Config{PrivateKey: bob.priv, AllowedPeers: []ed25519.PublicKey{newPeer(t).pub, alice.pub}})
+	require.NoError(t, client.err)
+	require.NoError(t, server.err)
+	defer client.conn.Close()
+	defer server.conn.Close()
+
+	assert.Equal(t, bob.pub, client.conn.PeerPublicKey())
+	assert.Equal(t, alice.pub, server.conn.PeerPublicKey())
+
+	for _, size := range []int{1, 100, MaxFrameSize, 3*MaxFrameSize + 7} {
+		message := make([]byte, size)
+		_, err := rand.Read(message)
+		require.NoError(t, err)
+
+		// client to server
+		go func() {
+			n, err := client.conn.Write(message)
+			assert.NoError(t, err)
+			assert.Equal(t, len(message), n)
+		}()
+		got := make([]byte, size)
+		_, err = io.ReadFull(server.conn, got)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(message, got))
+
+		// server to client
+		go func() {
+			_, err := server.conn.Write(message)
+			assert.NoError(t, err)
+		}()
+		_, err = io.ReadFull(client.conn, got)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(message, got))
+	}
+}
+
+func TestDerivedKeys(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	alice := ed25519.NewDerivedKeyFromSeed(seed, 1, nil)
+	bob := ed25519.NewDerivedKeyFromSeed(seed, 2, nil)
+	a, b := net.Pipe()
+	client, server := run(a, b,
+		&Config{PrivateKey: alice, AllowedPeers: []ed25519.PublicKey{bob.Public().(ed25519.PublicKey)}},
+		&Config{PrivateKey: bob, AllowedPeers: []ed25519.PublicKey{alice.Public().(ed25519.PublicKey)}})
+	require.NoError(t, client.err)
+	require.NoError(t, server.err)
+	client.conn.Close()
+	server.conn.Close()
+}
+
+func TestServerNotAllowed(t *testing.T) {
+	alice, bob := newPeer(t), newPeer(t)
+	a, b := net.Pipe()
+	client, server := run(a, b,
+		&Config{PrivateKey: alice.priv, AllowedPeers: []ed25519.PublicKey{newPeer(t).pub}},
+		&Config{PrivateKey: bob.priv, AllowedPeers: []ed25519.PublicKey{alice.pub}})
+	assert.Equal(t, ErrPeerNotAllowed, client.err)
+	assert.Error(t, server.err)
+}
+
+func TestClientNotAllowed(t *testing.T) {
+	alice, bob := newPeer(t), newPeer(t)
+	a, b := net.Pipe()
+	client, server := run(a, b,
+		&Config{PrivateKey: alice.priv, AllowedPeers: []ed25519.PublicKey{bob.pub}},
+		&Config{PrivateKey: bob.priv})
+	assert.Equal(t, ErrPeerNotAllowed, server.err)
+
+	// the client only notices when the server hangs up
+	require.NoError(t, client.err)
+	_, err := client.conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+// tamper relays a connection, flipping a bit of the byte at offset in the
+// server to client direction.
+func tamper(t *testing.T, offset int) (clientConn, serverConn net.Conn) {
+	clientConn, relayClient := net.Pipe()
+	relayServer, serverConn := net.Pipe()
+	go func() {
+		io.Copy(relayServer, relayClient)
+		relayServer.Close()
+	}()
+	go func() {
+		buf := make([]byte, 1)
+		for i := 0; ; i++ {
+			if _, err := relayServer.Read(buf); err != nil {
+				break
+			}
+			if i == offset {
+				buf[0] ^= 0x10
+			}
+			if _, err := relayClient.Write(buf); err != nil {
+				break
+			}
+		}
+		relayClient.Close()
+	}()
+	return clientConn, serverConn
+}
+
+func TestTamperedHandshake(t *testing.T) {
+	alice, bob := newPeer(t), newPeer(t)
+	// the responder's ephemeral key, encrypted signature and tag
+	for _, offset := range []int{0, 31, 32, 95, 96, 111} {
+		a, b := tamper(t, offset)
+		client, _ := run(a, b,
+			&Config{PrivateKey: alice.priv, AllowedPeers: []ed25519.PublicKey{bob.pub}},
+			&Config{PrivateKey: bob.priv, AllowedPeers: []ed25519.PublicKey{alice.pub}})
+		assert.Error(t, client.err, "offset %d", offset)
+	}
+}
+
+func TestTamperedFrame(t *testing.T) {
+	alice, bob := newPeer(t), newPeer(t)
+	// the second byte of the first session frame's ciphertext
+	a, b := tamper(t, keySize+ed25519.SignatureSize+tagSize+2+1)
+	client, server := run(a, b,
+		&Config{PrivateKey: alice.priv, AllowedPeers: []ed25519.PublicKey{bob.pub}},
+		&Config{PrivateKey: bob.priv, AllowedPeers: []ed25519.PublicKey{alice.pub}})
+	require.NoError(t, client.err)
+	require.NoError(t, server.err)
+	defer server.conn.Close()
+
+	go server.conn.Write([]byte("hello, world"))
+	_, err := client.conn.Read(make([]byte, 100))
+	assert.Equal(t, ErrFrame, err)
+	// the failure is permanent
+	_, err = client.conn.Read(make([]byte, 100))
+	assert.Equal(t, ErrFrame, err)
+}
+
+// TestReplayedFrame checks that frames are bound to their position in the
+// stream.
+func TestReplayedFrame(t *testing.T) {
+	alice, bob := newPeer(t), newPeer(t)
+	a, b := net.Pipe()
+	client, server := run(a, b,
+		&Config{PrivateKey: alice.priv, AllowedPeers: []ed25519.PublicKey{bob.pub}},
+		&Config{PrivateKey: bob.priv, AllowedPeers: []ed25519.PublicKey{alice.pub}})
+	require.NoError(t, client.err)
+	require.NoError(t, server.err)
+
+	// capture a frame as sent on the wire and send it twice
+	frame := make([]byte, 2, 2+5+tagSize)
+	binary.BigEndian.PutUint16(frame, 5+tagSize)
+	frame = server.conn.send.Seal(frame, sessionNonce(0), []byte("hello"), frame[:2])
+	go func() {
+		b.Write(frame)
+		b.Write(frame)
+	}()
+	got := make([]byte, 5)
+	_, err := io.ReadFull(client.conn, got)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(got))
+	_, err = client.conn.Read(got)
+	assert.Equal(t, ErrFrame, err)
+}
+
+func TestLowOrderEphemeral(t *testing.T) {
+	bob := newPeer(t)
+	a, b := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := Server(b, &Config{PrivateKey: bob.priv})
+		done <- err
+	}()
+	_, err := a.Write(make([]byte, keySize)) // u = 0
+	require.NoError(t, err)
+	assert.Equal(t, ErrHandshake, <-done)
+}
+
+func TestBadConfig(t *testing.T) {
+	a, _ := net.Pipe()
+	_, err := Client(a, &Config{})
+	assert.Error(t, err)
+	_, err = Server(a, &Config{PrivateKey: make([]byte, 10)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 Spacemesh Authors
+// bech32 and bech32m encoding
+
+// Package bech32 implements the bech32 (BIP-173) and bech32m (BIP-350)
+// string encodings.
+package bech32
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Variant selects the checksum constant of the encoding.
+type Variant int
+
+const (
+	// Bech32 is the original BIP-173 checksum.
+	Bech32 Variant = iota
+	// Bech32m is the BIP-350 checksum.
+	Bech32m
+)
+
+func (v Variant) String() string {
+	switch v {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	default:
+		return "Variant(" + strconv.Itoa(int(v)) + ")"
+	}
+}
+
+// MaxLength is the maximum string length allowed by BIP-173. Formats that
+// need longer strings pass their own limit to Decode.
+const MaxLength = 90
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var (
+	// ErrMixedCase is returned for strings mixing upper and lower case.
+	ErrMixedCase = errors.New("bech32: mixed case")
+	// ErrMissingSeparator is returned when no '1' separates the
+	// human-readable part from the data part.
+	ErrMissingSeparator = errors.New("bech32: missing separator")
+	// ErrInvalidHRP is returned for an empty human-readable part or one with
+	// characters outside US-ASCII 33-126.
+	ErrInvalidHRP = errors.New("bech32: invalid human-readable part")
+	// ErrInvalidChecksum is returned when the checksum matches neither
+	// variant.
+	ErrInvalidChecksum = errors.New("bech32: invalid checksum")
+	// ErrInvalidPadding is returned when the data part does not convert to
+	// whole bytes.
+	ErrInvalidPadding = errors.New("bech32: invalid padding")
+)
+
+// LengthError is returned for strings that are too short or too long.
+type LengthError struct {
+	Length, Max int
+}
+
+func (e *LengthError) Error() string {
+	return "bech32: invalid length " + strconv.Itoa(e.Length) + " (maximum " + strconv.Itoa(e.Max) + ")"
+}
+
+// CharacterError is returned for a character outside the bech32 alphabet.
+type CharacterError struct {
+	Pos  int
+	Char byte
+}
+
+func (e *CharacterError) Error() string {
+	return "bech32: invalid character " + strconv.QuoteRune(rune(e.Char)) + " at position " + strconv.Itoa(e.Pos)
+}
+
+func (v Variant) constant() uint32 {
+	if v == Bech32m {
+		return 0x2bc830a3
+	}
+	return 1
+}
+
+func polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	out := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// Encode encodes data, a byte string, under the lower case human-readable
+// part hrp.
+func Encode(hrp string, data []byte, v Variant) (string, error) {
+	if len(hrp) == 0 {
+		return "", ErrInvalidHRP
+	}
+	for i := 0; i < len(hrp); i++ {
+		if c := hrp[i]; c < 33 || c > 126 || (c >= 'A' && c <= 'Z') {
+			return "", ErrInvalidHRP
+		}
+	}
+	values := ConvertBits(data, 8, 5, true)
+
+	checksumInput := append(hrpExpand(hrp), values...)
+	checksumInput = append(checksumInput, 0, 0, 0, 0, 0, 0)
+	mod := polymod(checksumInput) ^ v.constant()
+
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(values) + 6)
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, c := range values {
+		sb.WriteByte(charset[c])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(charset[(mod>>uint(5*(5-i)))&31])
+	}
+	return sb.String(), nil
+}
+
+// Decode decodes a string of at most maxLength characters and returns its
+// lower cased human-readable part, its data converted to bytes and the
+// checksum variant it carries.
+func Decode(s string, maxLength int) (string, []byte, Variant, error) {
+	if len(s) > maxLength {
+		return "", nil, 0, &LengthError{Length: len(s), Max: maxLength}
+	}
+	lower, upper := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c < 33 || c > 126:
+			return "", nil, 0, &CharacterError{Pos: i, Char: c}
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		}
+	}
+	if lower && upper {
+		return "", nil, 0, ErrMixedCase
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 0 {
+		return "", nil, 0, ErrMissingSeparator
+	}
+	if sep == 0 {
+		return "", nil, 0, ErrInvalidHRP
+	}
+	if len(s)-sep-1 < 6 {
+		return "", nil, 0, &LengthError{Length: len(s), Max: maxLength}
+	}
+	hrp := s[:sep]
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		d := strings.IndexByte(charset, s[i])
+		if d < 0 {
+			return "", nil, 0, &CharacterError{Pos: i, Char: s[i]}
+		}
+		values = append(values, byte(d))
+	}
+
+	var v Variant
+	switch polymod(append(hrpExpand(hrp), values...)) {
+	case Bech32.constant():
+		v = Bech32
+	case Bech32m.constant():
+		v = Bech32m
+	default:
+		return "", nil, 0, ErrInvalidChecksum
+	}
+
+	data := ConvertBits(values[:len(values)-6], 5, 8, false)
+	if data == nil {
+		return "", nil, 0, ErrInvalidPadding
+	}
+	return hrp, data, v, nil
+}
+
+// ConvertBits regroups data from fromBits-bit to toBits-bit values. With pad
+// set, a trailing partial group is zero padded; otherwise ConvertBits returns
+// nil if the input leaves non-zero or overlong padding.
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) []byte {
+	var acc, bits uint
+	maxv := uint(1)<<toBits - 1
+	out := make([]byte, 0, (uint(len(data))*fromBits+toBits-1)/toBits)
+	for _, value := range data {
+		acc = acc<<fromBits | uint(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte((acc>>bits)&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte((acc<<(toBits-bits))&maxv))
+		}
+	} else if bits >= fromBits || (acc<<(toBits-bits))&maxv != 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Spacemesh Authors
+// bech32 and bech32m encoding unit tests
+
+package bech32
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Valid test vectors of BIP-173 and BIP-350 whose data part converts to bytes
+func TestDecodeValid(t *testing.T) {
+	for _, tc := range []struct {
+		s string
+		v Variant
+	}{
+		{"A12UEL5L", Bech32},
+		{"a12uel5l", Bech32},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", Bech32},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", Bech32},
+		{"?1ezyfcl", Bech32},
+		{"A1LQFN3A", Bech32m},
+		{"a1lqfn3a", Bech32m},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", Bech32m},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", Bech32m},
+		{"?1v759aa", Bech32m},
+	} {
+		hrp, data, v, err := Decode(tc.s, MaxLength)
+		require.NoError(t, err, tc.s)
+		assert.Equal(t, tc.v, v, tc.s)
+
+		encoded, err := Encode(hrp, data, v)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToLower(tc.s), encoded)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		s   string
+		err error
+	}{
+		{"pzry9x0s0muk", ErrMissingSeparator},
+		{"1pzry9x0s0muk", ErrInvalidHRP},
+		{"10a06t8", ErrInvalidHRP},
+		{"A1G7SGD8", ErrInvalidChecksum},
+		{"a12uEl5l", ErrMixedCase},
+		{"x1b4n0q5v", &CharacterError{Pos: 2, Char: 'b'}},
+		{"a1\x7fqqqqqqq", &CharacterError{Pos: 2, Char: 0x7f}},
+		{"li1dgmt3", &LengthError{Length: 8, Max: MaxLength}},
+		{"an84characterslonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11d6pts4", &LengthError{Length: 91, Max: MaxLength}},
+	} {
+		_, _, _, err := Decode(tc.s, MaxLength)
+		assert.Equal(t, tc.err, err, tc.s)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := []byte("Spacemesh rocks")
+	for _, v := range []Variant{Bech32, Bech32m} {
+		s, err := Encode("sm", data, v)
+		require.NoError(t, err)
+		hrp, decoded, variant, err := Decode(s, MaxLength)
+		require.NoError(t, err)
+		assert.Equal(t, "sm", hrp)
+		assert.Equal(t, data, decoded)
+		assert.Equal(t, v, variant)
+	}
+
+	_, err := Encode("SM", data, Bech32)
+	assert.Equal(t, ErrInvalidHRP, err)
+}
+
+func TestConvertBitsPadding(t *testing.T) {
+	assert.Equal(t, []byte{0x1f, 0x1c}, ConvertBits([]byte{0xff}, 8, 5, true))
+	assert.Equal(t, []byte{0xff}, ConvertBits([]byte{0x1f, 0x1c}, 5, 8, false))
+	assert.Nil(t, ConvertBits([]byte{0x1f, 0x1d}, 5, 8, false), "non-zero padding")
+	assert.Nil(t, ConvertBits([]byte{0x1f, 0x1c, 0x00}, 5, 8, false), "overlong padding")
+}
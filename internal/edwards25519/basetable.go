@@ -0,0 +1,198 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 wide precomputed base point table
+
+package edwards25519
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// The ref10 base table holds 8 multiples of 256^i·B for 32 positions i, and
+// GeScalarMultBase adds 64 of its points with 4 doublings. The wide table
+// holds 2^(w-1) multiples of 2^(w·i)·B for every position of a signed radix
+// 2^w recoding of the scalar, so that a multiplication takes one addition
+// per digit and no doublings.
+//
+// Every lookup reads the whole row of its position, so the cost of a
+// lookup doubles with every bit of w while the number of additions only
+// falls as 1/w. wideBaseLookup keeps the per-entry cost low by storing
+// entries as packed encodings and combining masked words instead of moving
+// whole points. With w = 5 a multiplication takes 52 additions instead of
+// 64 and 4 doublings, and is about 10% faster than with the ref10 table;
+// from w = 6 on the lookups cost more than the additions they save.
+const (
+	wideBaseWindow    = 5
+	wideBaseEntries   = 1 << (wideBaseWindow - 1)
+	wideBasePositions = 256/wideBaseWindow + 1
+)
+
+// wideBaseEntry holds the canonical encodings of the yPlusX, yMinusX and
+// xy2d of a PreComputedGroupElement as little-endian words. It is the same
+// on every platform and smaller than the FieldElements, which makes the
+// lookups cheaper.
+type wideBaseEntry [12]uint64
+
+var (
+	wideBase     *[wideBasePositions][wideBaseEntries]wideBaseEntry
+	wideBaseOnce sync.Once
+)
+
+// initWideBase computes the wide table, with a single field inversion for
+// all the points.
+func initWideBase() {
+	const n = wideBasePositions * wideBaseEntries
+	points := make([]ExtendedGroupElement, n)
+
+	// P is 2^(w·i)·B
+	var P ExtendedGroupElement
+	geScalarMultBaseRef10(&P, &[32]byte{1})
+	var r CompletedGroupElement
+	var s ProjectiveGroupElement
+	var c CachedGroupElement
+	for i := 0; i < wideBasePositions; i++ {
+		row := points[i*wideBaseEntries : (i+1)*wideBaseEntries]
+		row[0] = P
+		P.ToCached(&c)
+		for j := 1; j < wideBaseEntries; j++ {
+			geAdd(&r, &row[j-1], &c)
+			r.ToExtended(&row[j])
+		}
+
+		P.ToProjective(&s)
+		for j := 0; j < wideBaseWindow; j++ {
+			s.Double(&r)
+			r.ToProjective(&s)
+		}
+		r.ToExtended(&P)
+	}
+
+	// invert all the Z at once: inv[k] is the product of the first k Z
+	inv := make([]FieldElement, n)
+	var acc FieldElement
+	FeOne(&acc)
+	for k := range points {
+		inv[k] = acc
+		FeMul(&acc, &acc, &points[k].Z)
+	}
+	FeInvert(&acc, &acc)
+	for k := n - 1; k >= 0; k-- {
+		// acc is the inverse of the product of the first k+1 Z
+		FeMul(&inv[k], &inv[k], &acc)
+		FeMul(&acc, &acc, &points[k].Z)
+	}
+
+	table := new([wideBasePositions][wideBaseEntries]wideBaseEntry)
+	for k := range points {
+		var x, y, f FieldElement
+		var b [32]byte
+		FeMul(&x, &points[k].X, &inv[k])
+		FeMul(&y, &points[k].Y, &inv[k])
+		e := &table[k/wideBaseEntries][k%wideBaseEntries]
+		FeAdd(&f, &y, &x)
+		FeToBytes(&b, &f)
+		putWords(e[0:4], &b)
+		FeSub(&f, &y, &x)
+		FeToBytes(&b, &f)
+		putWords(e[4:8], &b)
+		FeMul(&f, &x, &y)
+		FeMul(&f, &f, &d2)
+		FeToBytes(&b, &f)
+		putWords(e[8:12], &b)
+	}
+	wideBase = table
+}
+
+func putWords(w []uint64, b *[32]byte) {
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+}
+
+func getWords(b *[32]byte, w []uint64) {
+	for i := range w {
+		binary.LittleEndian.PutUint64(b[i*8:], w[i])
+	}
+}
+
+// wideBaseLookup sets e to entry b-1 of row, or to zero if b is 0,
+// reading every entry.
+func wideBaseLookup(e *wideBaseEntry, row *[wideBaseEntries]wideBaseEntry, b int32) {
+	var e0, e1, e2, e3, e4, e5, e6, e7, e8, e9, e10, e11 uint64
+	for i := range row {
+		m, r := -uint64(equal(b, int32(i+1))), &row[i]
+		e0 |= r[0] & m
+		e1 |= r[1] & m
+		e2 |= r[2] & m
+		e3 |= r[3] & m
+		e4 |= r[4] & m
+		e5 |= r[5] & m
+		e6 |= r[6] & m
+		e7 |= r[7] & m
+		e8 |= r[8] & m
+		e9 |= r[9] & m
+		e10 |= r[10] & m
+		e11 |= r[11] & m
+	}
+	*e = wideBaseEntry{e0, e1, e2, e3, e4, e5, e6, e7, e8, e9, e10, e11}
+}
+
+// wideBaseSelect sets t to b·2^(w·pos)·B in constant time, for b between
+// -2^(w-1) and 2^(w-1).
+func wideBaseSelect(t *PreComputedGroupElement, pos int, b int32) {
+	bNegative := negative(b)
+	bAbs := b - (((-bNegative) & b) << 1)
+
+	var e wideBaseEntry
+	wideBaseLookup(&e, &wideBase[pos], bAbs)
+
+	// the lookup gives zero if b is 0; yPlusX and yMinusX are then 1, for
+	// the identity
+	zero := uint64(equal(bAbs, 0))
+	e[0] |= zero
+	e[4] |= zero
+
+	var s [32]byte
+	getWords(&s, e[0:4])
+	FeFromBytes(&t.yPlusX, &s)
+	getWords(&s, e[4:8])
+	FeFromBytes(&t.yMinusX, &s)
+	getWords(&s, e[8:12])
+	FeFromBytes(&t.xy2d, &s)
+
+	// -(x, y) = (-x, y)
+	var minusT PreComputedGroupElement
+	FeCopy(&minusT.yPlusX, &t.yMinusX)
+	FeCopy(&minusT.yMinusX, &t.yPlusX)
+	FeNeg(&minusT.xy2d, &t.xy2d)
+	PreComputedGroupElementCMove(t, &minusT, bNegative)
+}
+
+// geScalarMultBaseWide computes h = a*B with the wide table, for a[31] <= 127.
+func geScalarMultBaseWide(h *ExtendedGroupElement, a *[32]byte) {
+	wideBaseOnce.Do(initWideBase)
+
+	// the digits of a in radix 2^w, recoded between -2^(w-1) and 2^(w-1)
+	var e [wideBasePositions]int32
+	var carry int32
+	for i := range e {
+		bit := i * wideBaseWindow
+		var v uint32
+		for j := 0; j < 4 && bit/8+j < 32; j++ {
+			v |= uint32(a[bit/8+j]) << (8 * j)
+		}
+		e[i] = int32(v>>(bit%8)&(1<<wideBaseWindow-1)) + carry
+		carry = (e[i] + wideBaseEntries) >> wideBaseWindow
+		e[i] -= carry << wideBaseWindow
+	}
+	// a < 2^255 leaves no carry out of the top digit
+
+	h.Zero()
+	var t PreComputedGroupElement
+	var r CompletedGroupElement
+	for i := range e {
+		wideBaseSelect(&t, i, e[i])
+		geMixedAdd(&r, h, &t)
+		r.ToExtended(h)
+	}
+}
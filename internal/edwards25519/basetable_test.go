@@ -0,0 +1,138 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 wide base point table unit tests
+
+package edwards25519
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func baseMultBytes(a *[32]byte) (wide, ref10 [32]byte) {
+	var h ExtendedGroupElement
+	geScalarMultBaseWide(&h, a)
+	h.ToBytes(&wide)
+	geScalarMultBaseRef10(&h, a)
+	h.ToBytes(&ref10)
+	return wide, ref10
+}
+
+func TestGeScalarMultBaseWide(t *testing.T) {
+	var max [32]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	max[31] = 127
+	// digits at the edges of the recoding: all 16, all -16 and carries
+	var sixteens, carries [32]byte
+	for i := 0; i < 255; i += wideBaseWindow {
+		sixteens[i/8] |= byte(16 << (i % 8))
+		if i%8 > 3 && i/8 < 31 {
+			sixteens[i/8+1] |= byte(16 >> (8 - i%8))
+		}
+	}
+	for i := range carries {
+		carries[i] = 0x84
+	}
+	carries[31] = 0x7f
+	scalars := []*[32]byte{{}, {1}, {2}, {16}, {17}, {0xf0}, &max, &sixteens, &carries}
+	for i := 0; i < 200; i++ {
+		a := rnd32Bytes(t)
+		a[31] &= 127
+		scalars = append(scalars, a)
+	}
+
+	for _, a := range scalars {
+		wide, ref10 := baseMultBytes(a)
+		assert.Equal(t, ref10, wide, "%x", a)
+	}
+}
+
+func TestWideBaseLookup(t *testing.T) {
+	wideBaseOnce.Do(initWideBase)
+	row := &wideBase[wideBasePositions-1]
+	for b := int32(0); b <= wideBaseEntries; b++ {
+		var e, want wideBaseEntry
+		wideBaseLookup(&e, row, b)
+		if b > 0 {
+			want = row[b-1]
+		}
+		assert.Equal(t, want, e, "b = %d", b)
+	}
+}
+
+func TestWideBaseSelect(t *testing.T) {
+	// b·2^(w·pos)·B for the digits of the second position
+	var identity ExtendedGroupElement
+	identity.Zero()
+	for b := int32(-wideBaseEntries); b <= wideBaseEntries; b++ {
+		var p PreComputedGroupElement
+		wideBaseSelect(&p, 1, b)
+		var r CompletedGroupElement
+		var h ExtendedGroupElement
+		geMixedAdd(&r, &identity, &p)
+		r.ToExtended(&h)
+		var got [32]byte
+		h.ToBytes(&got)
+
+		abs := b
+		if b < 0 {
+			abs = -b
+		}
+		var a [32]byte
+		a[0] = byte(abs << wideBaseWindow)
+		a[1] = byte(abs >> (8 - wideBaseWindow))
+		var want [32]byte
+		geScalarMultBaseRef10(&h, &a)
+		h.ToBytes(&want)
+		if b < 0 {
+			want[31] ^= 0x80
+		}
+		assert.Equal(t, want, got, "b = %d", b)
+	}
+}
+
+// TestGeScalarMultBaseWideTiming checks that the running time of the wide
+// table multiplication does not depend on the scalar, with the harness of
+// TestGeScalarMultTiming.
+func TestGeScalarMultBaseWideTiming(t *testing.T) {
+	skipUnlessTiming(t)
+	const samples = 4000
+	const threshold = 10
+
+	var h ExtendedGroupElement
+	ct := timingTest(func(a *[32]byte) { geScalarMultBaseWide(&h, a) }, [32]byte{}, samples)
+	t.Logf("geScalarMultBaseWide: t = %.2f", ct)
+	assert.Less(t, math.Abs(ct), float64(threshold))
+}
+
+func BenchmarkGeScalarMultBase(bench *testing.B) {
+	a := rnd32BytesBench(bench)
+	a[31] &= 127
+	var h ExtendedGroupElement
+	GeScalarMultBase(&h, a)
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		GeScalarMultBase(&h, a)
+	}
+}
+
+func BenchmarkGeScalarMultBaseRef10(bench *testing.B) {
+	a := rnd32BytesBench(bench)
+	a[31] &= 127
+	var h ExtendedGroupElement
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		geScalarMultBaseRef10(&h, a)
+	}
+}
+
+func BenchmarkInitWideBase(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		initWideBase()
+	}
+}
@@ -0,0 +1,11 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 base point table selection
+
+//go:build !ed25519_smallbase
+// +build !ed25519_smallbase
+
+package edwards25519
+
+// useWideBase reports whether GeScalarMultBase uses the wide table. Build
+// with the ed25519_smallbase tag to use the ref10 table only.
+const useWideBase = true
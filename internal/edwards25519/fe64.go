@@ -0,0 +1,285 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 radix 2^51 field arithmetic
+
+package edwards25519
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// fe64 represents an element of the field GF(2^255 - 19) with five 51-bit
+// limbs: t represents the integer t[0]+2^51 t[1]+2^102 t[2]+2^153 t[3]+2^204
+// t[4]. Every operation returns limbs below 2^51 + 2^13·19, which is all the
+// operations require of their inputs, so unlike with fe32 there are no
+// bounds to track across additions and subtractions.
+//
+// This is the FieldElement of 64-bit platforms, see field_64bit.go.
+type fe64 [5]uint64
+
+const maskLow51Bits uint64 = (1 << 51) - 1
+
+func fe64Zero(fe *fe64) {
+	*fe = fe64{}
+}
+
+func fe64One(fe *fe64) {
+	*fe = fe64{1}
+}
+
+// fe64CarryPropagate brings the limbs of v below 2^51 + 2^13·19.
+func fe64CarryPropagate(v *fe64) {
+	c0 := v[0] >> 51
+	c1 := v[1] >> 51
+	c2 := v[2] >> 51
+	c3 := v[3] >> 51
+	c4 := v[4] >> 51
+
+	v[0] = v[0]&maskLow51Bits + c4*19
+	v[1] = v[1]&maskLow51Bits + c0
+	v[2] = v[2]&maskLow51Bits + c1
+	v[3] = v[3]&maskLow51Bits + c2
+	v[4] = v[4]&maskLow51Bits + c3
+}
+
+func fe64Add(dst, a, b *fe64) {
+	dst[0] = a[0] + b[0]
+	dst[1] = a[1] + b[1]
+	dst[2] = a[2] + b[2]
+	dst[3] = a[3] + b[3]
+	dst[4] = a[4] + b[4]
+	fe64CarryPropagate(dst)
+}
+
+// fe64Sub sets dst = a - b, adding 2p to avoid underflow.
+func fe64Sub(dst, a, b *fe64) {
+	dst[0] = (a[0] + 0xFFFFFFFFFFFDA) - b[0]
+	dst[1] = (a[1] + 0xFFFFFFFFFFFFE) - b[1]
+	dst[2] = (a[2] + 0xFFFFFFFFFFFFE) - b[2]
+	dst[3] = (a[3] + 0xFFFFFFFFFFFFE) - b[3]
+	dst[4] = (a[4] + 0xFFFFFFFFFFFFE) - b[4]
+	fe64CarryPropagate(dst)
+}
+
+func fe64Neg(h, f *fe64) {
+	var zero fe64
+	fe64Sub(h, &zero, f)
+}
+
+func fe64Copy(dst, src *fe64) {
+	*dst = *src
+}
+
+// fe64CMove sets f = g if b == 1 and leaves f unchanged if b == 0.
+//
+// Preconditions: b in {0,1}.
+func fe64CMove(f, g *fe64, b int32) {
+	mask := -uint64(b)
+	f[0] ^= mask & (f[0] ^ g[0])
+	f[1] ^= mask & (f[1] ^ g[1])
+	f[2] ^= mask & (f[2] ^ g[2])
+	f[3] ^= mask & (f[3] ^ g[3])
+	f[4] ^= mask & (f[4] ^ g[4])
+}
+
+// fe64CSwap swaps f and g if b == 1 and leaves them unchanged if b == 0.
+//
+// Preconditions: b in {0,1}.
+func fe64CSwap(f, g *fe64, b int32) {
+	mask := -uint64(b)
+	for i := range f {
+		t := mask & (f[i] ^ g[i])
+		f[i] ^= t
+		g[i] ^= t
+	}
+}
+
+// fe64FromBytes sets dst to the little-endian value of src, ignoring its
+// most significant bit. Non-canonical values are accepted and reduced.
+func fe64FromBytes(dst *fe64, src *[32]byte) {
+	dst[0] = binary.LittleEndian.Uint64(src[0:8]) & maskLow51Bits
+	dst[1] = (binary.LittleEndian.Uint64(src[6:14]) >> 3) & maskLow51Bits
+	dst[2] = (binary.LittleEndian.Uint64(src[12:20]) >> 6) & maskLow51Bits
+	dst[3] = (binary.LittleEndian.Uint64(src[19:27]) >> 1) & maskLow51Bits
+	dst[4] = (binary.LittleEndian.Uint64(src[24:32]) >> 12) & maskLow51Bits
+}
+
+// fe64ToBytes sets s to the canonical little-endian encoding of h.
+func fe64ToBytes(s *[32]byte, h *fe64) {
+	t := *h
+	fe64CarryPropagate(&t)
+
+	// t < 2^255 + 2^13·19 now. q is 1 if t >= p, that is if t + 19 carries
+	// into 2^255, and 0 otherwise.
+	q := (t[0] + 19) >> 51
+	q = (t[1] + q) >> 51
+	q = (t[2] + q) >> 51
+	q = (t[3] + q) >> 51
+	q = (t[4] + q) >> 51
+
+	// t - q·p = t + 19q - 2^255q: add 19q and drop the carry out of 2^255.
+	t[0] += 19 * q
+	t[1] += t[0] >> 51
+	t[0] &= maskLow51Bits
+	t[2] += t[1] >> 51
+	t[1] &= maskLow51Bits
+	t[3] += t[2] >> 51
+	t[2] &= maskLow51Bits
+	t[4] += t[3] >> 51
+	t[3] &= maskLow51Bits
+	t[4] &= maskLow51Bits
+
+	binary.LittleEndian.PutUint64(s[0:8], t[0]|t[1]<<51)
+	binary.LittleEndian.PutUint64(s[8:16], t[1]>>13|t[2]<<38)
+	binary.LittleEndian.PutUint64(s[16:24], t[2]>>26|t[3]<<25)
+	binary.LittleEndian.PutUint64(s[24:32], t[3]>>39|t[4]<<12)
+}
+
+// uint128 holds a 128-bit product.
+type uint128 struct {
+	lo, hi uint64
+}
+
+// mul64 returns a·b.
+func mul64(a, b uint64) uint128 {
+	hi, lo := bits.Mul64(a, b)
+	return uint128{lo, hi}
+}
+
+// addMul64 returns v + a·b.
+func addMul64(v uint128, a, b uint64) uint128 {
+	hi, lo := bits.Mul64(a, b)
+	lo, c := bits.Add64(lo, v.lo, 0)
+	hi, _ = bits.Add64(hi, v.hi, c)
+	return uint128{lo, hi}
+}
+
+// shiftRightBy51 returns a >> 51. a is assumed to be below 2^115.
+func shiftRightBy51(a uint128) uint64 {
+	return (a.hi << (64 - 51)) | (a.lo >> 51)
+}
+
+// fe64Reduce sets h to the sum of the 128-bit limbs r, carrying the bits
+// above 51 into the next limb and multiplying the top carry by 19.
+func fe64Reduce(h *fe64, r0, r1, r2, r3, r4 uint128) {
+	c0 := shiftRightBy51(r0)
+	c1 := shiftRightBy51(r1)
+	c2 := shiftRightBy51(r2)
+	c3 := shiftRightBy51(r3)
+	c4 := shiftRightBy51(r4)
+
+	h[0] = r0.lo&maskLow51Bits + c4*19
+	h[1] = r1.lo&maskLow51Bits + c0
+	h[2] = r2.lo&maskLow51Bits + c1
+	h[3] = r3.lo&maskLow51Bits + c2
+	h[4] = r4.lo&maskLow51Bits + c3
+	fe64CarryPropagate(h)
+}
+
+// fe64MulGeneric calculates h = f * g. Can overlap h with f or g. fe64Mul
+// calls it, or an assembly version of it.
+//
+// Limb products that overflow 2^255 wrap around multiplied by 19, as
+// 2^255 = 19 mod p:
+//
+//	r0 = f0×g0 + 19×(f1×g4 + f2×g3 + f3×g2 + f4×g1)
+//	r1 = f0×g1 + f1×g0 + 19×(f2×g4 + f3×g3 + f4×g2)
+//	r2 = f0×g2 + f1×g1 + f2×g0 + 19×(f3×g4 + f4×g3)
+//	r3 = f0×g3 + f1×g2 + f2×g1 + f3×g0 + 19×f4×g4
+//	r4 = f0×g4 + f1×g3 + f2×g2 + f3×g1 + f4×g0
+func fe64MulGeneric(h, f, g *fe64) {
+	f0, f1, f2, f3, f4 := f[0], f[1], f[2], f[3], f[4]
+	g0, g1, g2, g3, g4 := g[0], g[1], g[2], g[3], g[4]
+
+	f1_19 := f1 * 19
+	f2_19 := f2 * 19
+	f3_19 := f3 * 19
+	f4_19 := f4 * 19
+
+	r0 := mul64(f0, g0)
+	r0 = addMul64(r0, f1_19, g4)
+	r0 = addMul64(r0, f2_19, g3)
+	r0 = addMul64(r0, f3_19, g2)
+	r0 = addMul64(r0, f4_19, g1)
+
+	r1 := mul64(f0, g1)
+	r1 = addMul64(r1, f1, g0)
+	r1 = addMul64(r1, f2_19, g4)
+	r1 = addMul64(r1, f3_19, g3)
+	r1 = addMul64(r1, f4_19, g2)
+
+	r2 := mul64(f0, g2)
+	r2 = addMul64(r2, f1, g1)
+	r2 = addMul64(r2, f2, g0)
+	r2 = addMul64(r2, f3_19, g4)
+	r2 = addMul64(r2, f4_19, g3)
+
+	r3 := mul64(f0, g3)
+	r3 = addMul64(r3, f1, g2)
+	r3 = addMul64(r3, f2, g1)
+	r3 = addMul64(r3, f3, g0)
+	r3 = addMul64(r3, f4_19, g4)
+
+	r4 := mul64(f0, g4)
+	r4 = addMul64(r4, f1, g3)
+	r4 = addMul64(r4, f2, g2)
+	r4 = addMul64(r4, f3, g1)
+	r4 = addMul64(r4, f4, g0)
+
+	fe64Reduce(h, r0, r1, r2, r3, r4)
+}
+
+// fe64SquareGeneric calculates h = f*f, with the symmetric products of
+// fe64MulGeneric merged. Can overlap h with f. fe64Square calls it, or an
+// assembly version of it.
+func fe64SquareGeneric(h, f *fe64) {
+	f0, f1, f2, f3, f4 := f[0], f[1], f[2], f[3], f[4]
+
+	f0_2 := f0 * 2
+	f1_2 := f1 * 2
+	f1_38 := f1 * 38
+	f2_38 := f2 * 38
+	f3_38 := f3 * 38
+	f3_19 := f3 * 19
+	f4_19 := f4 * 19
+
+	// r0 = f0×f0 + 19×2×(f1×f4 + f2×f3)
+	r0 := mul64(f0, f0)
+	r0 = addMul64(r0, f1_38, f4)
+	r0 = addMul64(r0, f2_38, f3)
+
+	// r1 = 2×f0×f1 + 19×(2×f2×f4 + f3×f3)
+	r1 := mul64(f0_2, f1)
+	r1 = addMul64(r1, f2_38, f4)
+	r1 = addMul64(r1, f3_19, f3)
+
+	// r2 = 2×f0×f2 + f1×f1 + 19×2×f3×f4
+	r2 := mul64(f0_2, f2)
+	r2 = addMul64(r2, f1, f1)
+	r2 = addMul64(r2, f3_38, f4)
+
+	// r3 = 2×f0×f3 + 2×f1×f2 + 19×f4×f4
+	r3 := mul64(f0_2, f3)
+	r3 = addMul64(r3, f1_2, f2)
+	r3 = addMul64(r3, f4_19, f4)
+
+	// r4 = 2×f0×f4 + 2×f1×f3 + f2×f2
+	r4 := mul64(f0_2, f4)
+	r4 = addMul64(r4, f1_2, f3)
+	r4 = addMul64(r4, f2, f2)
+
+	fe64Reduce(h, r0, r1, r2, r3, r4)
+}
+
+// fe64Square2 sets h = 2 * f * f. Can overlap h with f.
+func fe64Square2(h, f *fe64) {
+	fe64Square(h, f)
+	fe64Add(h, h, h)
+}
+
+// fe64FromFe32 sets h to the value of f.
+func fe64FromFe32(h *fe64, f *fe32) {
+	var s [32]byte
+	fe32ToBytes(&s, f)
+	fe64FromBytes(h, &s)
+}
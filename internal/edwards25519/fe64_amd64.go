@@ -0,0 +1,54 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 radix 2^51 field multiplication and squaring for amd64
+
+//go:build amd64 && !purego
+// +build amd64,!purego
+
+package edwards25519
+
+// useADX reports whether the CPU has the BMI2 (MULX) and ADX (ADCX, ADOX)
+// extensions used by fe64MulADX and fe64SquareADX.
+var useADX = hasBMI2ADX()
+
+func hasBMI2ADX() bool {
+	maxLeaf, _, _, _ := cpuid(0, 0)
+	if maxLeaf < 7 {
+		return false
+	}
+	_, ebx, _, _ := cpuid(7, 0)
+	const bmi2, adx = 1 << 8, 1 << 19
+	return ebx&bmi2 != 0 && ebx&adx != 0
+}
+
+//go:noescape
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+//go:noescape
+func fe64MulMULQ(out, a, b *fe64)
+
+//go:noescape
+func fe64MulADX(out, a, b *fe64)
+
+//go:noescape
+func fe64SquareMULQ(out, a *fe64)
+
+//go:noescape
+func fe64SquareADX(out, a *fe64)
+
+// fe64Mul calculates h = f * g. Can overlap h with f or g.
+func fe64Mul(h, f, g *fe64) {
+	if useADX {
+		fe64MulADX(h, f, g)
+	} else {
+		fe64MulMULQ(h, f, g)
+	}
+}
+
+// fe64Square calculates h = f*f. Can overlap h with f.
+func fe64Square(h, f *fe64) {
+	if useADX {
+		fe64SquareADX(h, f)
+	} else {
+		fe64SquareMULQ(h, f)
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 radix 2^51 field multiplication and squaring for amd64
+
+//go:build amd64 && !purego
+// +build amd64,!purego
+
+#include "textflag.h"
+
+// The products of fe64Mul and fe64Square are computed in three phases, to
+// leave room for the MULX multiplier in DX: r0 and r1 in R9:R8 and R11:R10,
+// r2 and r3 in R13:R12 and R15:R14, then r4 in R11:R9. Each phase folds its
+// carries into the reduced limbs h0 (R8), h1 (R10), h2 (R12), h3 (R14) and
+// h4 (R9), which are then carried once more, as in fe64Reduce.
+//
+// CX and BX point to the operands, AX and SI receive MULX products and DI is
+// scratch for multiplying by 19.
+
+#define MASK51 $0x7ffffffffffff
+
+// Loads of a limb scaled by 1, 2, 19 or 38 into AX (for MULQ) or DX (for
+// MULX). LEAQ leaves the flags, and so the ADX carry chains, untouched.
+#define LOAD_AX(a) MOVQ a, AX
+#define LOAD2_AX(a) MOVQ a, AX; LEAQ (AX)(AX*1), AX
+#define LOAD19_AX(a) MOVQ a, AX; LEAQ (AX)(AX*8), DI; LEAQ (AX)(DI*2), AX
+#define LOAD38_AX(a) LOAD19_AX(a); LEAQ (AX)(AX*1), AX
+#define LOAD_DX(a) MOVQ a, DX
+#define LOAD2_DX(a) MOVQ a, DX; LEAQ (DX)(DX*1), DX
+#define LOAD19_DX(a) MOVQ a, DX; LEAQ (DX)(DX*8), DI; LEAQ (DX)(DI*2), DX
+#define LOAD38_DX(a) LOAD19_DX(a); LEAQ (DX)(DX*1), DX
+
+// hi:lo = AX × src, and hi:lo += AX × src.
+#define MQ_INIT(src, lo, hi) MULQ src; MOVQ AX, lo; MOVQ DX, hi
+#define MQ_ADD(src, lo, hi) MULQ src; ADDQ AX, lo; ADCQ DX, hi
+
+// hi:lo = DX × src, and hi:lo += DX × src on the CF (ADCX) or OF (ADOX)
+// carry chain. The sums stay below 2^115, so the chains never carry out of
+// hi and can run across several products.
+#define MX_INIT(src, lo, hi) MULXQ src, lo, hi
+#define MX_CF(src, lo, hi) MULXQ src, AX, SI; ADCXQ AX, lo; ADCXQ SI, hi
+#define MX_OF(src, lo, hi) MULXQ src, AX, SI; ADOXQ AX, lo; ADOXQ SI, hi
+
+// h0 = r0 mod 2^51, h1 = r1 mod 2^51 + r0 >> 51, R11 = r1 >> 51.
+#define REDUCE01 \
+	MOVQ MASK51, DI; \
+	SHLQ $13, R8, R9; \
+	ANDQ DI, R8; \
+	SHLQ $13, R10, R11; \
+	ANDQ DI, R10; \
+	ADDQ R9, R10
+
+// h2 = r2 mod 2^51 + r1 >> 51, h3 = r3 mod 2^51 + r2 >> 51, R15 = r3 >> 51.
+#define REDUCE23 \
+	MOVQ MASK51, DI; \
+	SHLQ $13, R12, R13; \
+	ANDQ DI, R12; \
+	ADDQ R11, R12; \
+	SHLQ $13, R14, R15; \
+	ANDQ DI, R14; \
+	ADDQ R13, R14
+
+// h4 = r4 mod 2^51 + r3 >> 51, h0 += 19 × (r4 >> 51), then carry h once
+// more and store it to out.
+#define REDUCE4_STORE \
+	MOVQ MASK51, DI; \
+	SHLQ $13, R9, R11; \
+	ANDQ DI, R9; \
+	ADDQ R15, R9; \
+	LEAQ (R11)(R11*8), AX; \
+	LEAQ (R11)(AX*2), AX; \
+	ADDQ AX, R8; \
+	MOVQ R8, AX; \
+	SHRQ $51, AX; \
+	MOVQ R10, SI; \
+	SHRQ $51, SI; \
+	MOVQ R12, R11; \
+	SHRQ $51, R11; \
+	MOVQ R14, R13; \
+	SHRQ $51, R13; \
+	MOVQ R9, R15; \
+	SHRQ $51, R15; \
+	ANDQ DI, R8; \
+	ANDQ DI, R10; \
+	ANDQ DI, R12; \
+	ANDQ DI, R14; \
+	ANDQ DI, R9; \
+	LEAQ (R15)(R15*8), DX; \
+	LEAQ (R15)(DX*2), DX; \
+	ADDQ DX, R8; \
+	ADDQ AX, R10; \
+	ADDQ SI, R12; \
+	ADDQ R11, R14; \
+	ADDQ R13, R9; \
+	MOVQ out+0(FP), AX; \
+	MOVQ R8, 0(AX); \
+	MOVQ R10, 8(AX); \
+	MOVQ R12, 16(AX); \
+	MOVQ R14, 24(AX); \
+	MOVQ R9, 32(AX)
+
+// func fe64MulMULQ(out, a, b *fe64)
+TEXT ·fe64MulMULQ(SB), NOSPLIT, $0-24
+	MOVQ a+8(FP), CX
+	MOVQ b+16(FP), BX
+
+	// r0 = a0×b0 + 19×(a1×b4 + a2×b3 + a3×b2 + a4×b1)
+	LOAD_AX(0(CX))
+	MQ_INIT(0(BX), R8, R9)
+	LOAD19_AX(8(CX))
+	MQ_ADD(32(BX), R8, R9)
+	LOAD19_AX(16(CX))
+	MQ_ADD(24(BX), R8, R9)
+	LOAD19_AX(24(CX))
+	MQ_ADD(16(BX), R8, R9)
+	LOAD19_AX(32(CX))
+	MQ_ADD(8(BX), R8, R9)
+
+	// r1 = a0×b1 + a1×b0 + 19×(a2×b4 + a3×b3 + a4×b2)
+	LOAD_AX(0(CX))
+	MQ_INIT(8(BX), R10, R11)
+	LOAD_AX(8(CX))
+	MQ_ADD(0(BX), R10, R11)
+	LOAD19_AX(16(CX))
+	MQ_ADD(32(BX), R10, R11)
+	LOAD19_AX(24(CX))
+	MQ_ADD(24(BX), R10, R11)
+	LOAD19_AX(32(CX))
+	MQ_ADD(16(BX), R10, R11)
+
+	REDUCE01
+
+	// r2 = a0×b2 + a1×b1 + a2×b0 + 19×(a3×b4 + a4×b3)
+	LOAD_AX(0(CX))
+	MQ_INIT(16(BX), R12, R13)
+	LOAD_AX(8(CX))
+	MQ_ADD(8(BX), R12, R13)
+	LOAD_AX(16(CX))
+	MQ_ADD(0(BX), R12, R13)
+	LOAD19_AX(24(CX))
+	MQ_ADD(32(BX), R12, R13)
+	LOAD19_AX(32(CX))
+	MQ_ADD(24(BX), R12, R13)
+
+	// r3 = a0×b3 + a1×b2 + a2×b1 + a3×b0 + 19×a4×b4
+	LOAD_AX(0(CX))
+	MQ_INIT(24(BX), R14, R15)
+	LOAD_AX(8(CX))
+	MQ_ADD(16(BX), R14, R15)
+	LOAD_AX(16(CX))
+	MQ_ADD(8(BX), R14, R15)
+	LOAD_AX(24(CX))
+	MQ_ADD(0(BX), R14, R15)
+	LOAD19_AX(32(CX))
+	MQ_ADD(32(BX), R14, R15)
+
+	REDUCE23
+
+	// r4 = a0×b4 + a1×b3 + a2×b2 + a3×b1 + a4×b0
+	LOAD_AX(0(CX))
+	MQ_INIT(32(BX), R9, R11)
+	LOAD_AX(8(CX))
+	MQ_ADD(24(BX), R9, R11)
+	LOAD_AX(16(CX))
+	MQ_ADD(16(BX), R9, R11)
+	LOAD_AX(24(CX))
+	MQ_ADD(8(BX), R9, R11)
+	LOAD_AX(32(CX))
+	MQ_ADD(0(BX), R9, R11)
+
+	REDUCE4_STORE
+	RET
+
+// func fe64MulADX(out, a, b *fe64)
+TEXT ·fe64MulADX(SB), NOSPLIT, $0-24
+	MOVQ a+8(FP), CX
+	MOVQ b+16(FP), BX
+
+	// r0 on the CF chain, r1 on the OF chain
+	LOAD_DX(0(CX))
+	MX_INIT(0(BX), R8, R9)
+	MX_INIT(8(BX), R10, R11)
+	XORL AX, AX
+	LOAD19_DX(8(CX))
+	MX_CF(32(BX), R8, R9)
+	LOAD_DX(8(CX))
+	MX_OF(0(BX), R10, R11)
+	LOAD19_DX(16(CX))
+	MX_CF(24(BX), R8, R9)
+	MX_OF(32(BX), R10, R11)
+	LOAD19_DX(24(CX))
+	MX_CF(16(BX), R8, R9)
+	MX_OF(24(BX), R10, R11)
+	LOAD19_DX(32(CX))
+	MX_CF(8(BX), R8, R9)
+	MX_OF(16(BX), R10, R11)
+
+	REDUCE01
+
+	// r2 on the CF chain, r3 on the OF chain
+	LOAD_DX(0(CX))
+	MX_INIT(16(BX), R12, R13)
+	MX_INIT(24(BX), R14, R15)
+	XORL AX, AX
+	LOAD_DX(8(CX))
+	MX_CF(8(BX), R12, R13)
+	MX_OF(16(BX), R14, R15)
+	LOAD_DX(16(CX))
+	MX_CF(0(BX), R12, R13)
+	MX_OF(8(BX), R14, R15)
+	LOAD_DX(24(CX))
+	MX_OF(0(BX), R14, R15)
+	LOAD19_DX(24(CX))
+	MX_CF(32(BX), R12, R13)
+	LOAD19_DX(32(CX))
+	MX_CF(24(BX), R12, R13)
+	MX_OF(32(BX), R14, R15)
+
+	REDUCE23
+
+	// r4
+	LOAD_DX(0(CX))
+	MX_INIT(32(BX), R9, R11)
+	XORL AX, AX
+	LOAD_DX(8(CX))
+	MX_CF(24(BX), R9, R11)
+	LOAD_DX(16(CX))
+	MX_CF(16(BX), R9, R11)
+	LOAD_DX(24(CX))
+	MX_CF(8(BX), R9, R11)
+	LOAD_DX(32(CX))
+	MX_CF(0(BX), R9, R11)
+
+	REDUCE4_STORE
+	RET
+
+// func fe64SquareMULQ(out, a *fe64)
+TEXT ·fe64SquareMULQ(SB), NOSPLIT, $0-16
+	MOVQ a+8(FP), CX
+
+	// r0 = a0×a0 + 38×a1×a4 + 38×a2×a3
+	LOAD_AX(0(CX))
+	MQ_INIT(0(CX), R8, R9)
+	LOAD38_AX(8(CX))
+	MQ_ADD(32(CX), R8, R9)
+	LOAD38_AX(16(CX))
+	MQ_ADD(24(CX), R8, R9)
+
+	// r1 = 2×a0×a1 + 38×a2×a4 + 19×a3×a3
+	LOAD2_AX(0(CX))
+	MQ_INIT(8(CX), R10, R11)
+	LOAD38_AX(16(CX))
+	MQ_ADD(32(CX), R10, R11)
+	LOAD19_AX(24(CX))
+	MQ_ADD(24(CX), R10, R11)
+
+	REDUCE01
+
+	// r2 = 2×a0×a2 + a1×a1 + 38×a3×a4
+	LOAD2_AX(0(CX))
+	MQ_INIT(16(CX), R12, R13)
+	LOAD_AX(8(CX))
+	MQ_ADD(8(CX), R12, R13)
+	LOAD38_AX(24(CX))
+	MQ_ADD(32(CX), R12, R13)
+
+	// r3 = 2×a0×a3 + 2×a1×a2 + 19×a4×a4
+	LOAD2_AX(0(CX))
+	MQ_INIT(24(CX), R14, R15)
+	LOAD2_AX(8(CX))
+	MQ_ADD(16(CX), R14, R15)
+	LOAD19_AX(32(CX))
+	MQ_ADD(32(CX), R14, R15)
+
+	REDUCE23
+
+	// r4 = 2×a0×a4 + 2×a1×a3 + a2×a2
+	LOAD2_AX(0(CX))
+	MQ_INIT(32(CX), R9, R11)
+	LOAD2_AX(8(CX))
+	MQ_ADD(24(CX), R9, R11)
+	LOAD_AX(16(CX))
+	MQ_ADD(16(CX), R9, R11)
+
+	REDUCE4_STORE
+	RET
+
+// func fe64SquareADX(out, a *fe64)
+TEXT ·fe64SquareADX(SB), NOSPLIT, $0-16
+	MOVQ a+8(FP), CX
+
+	// r0 on the CF chain, r1 on the OF chain
+	LOAD_DX(0(CX))
+	MX_INIT(0(CX), R8, R9)
+	LOAD2_DX(0(CX))
+	MX_INIT(8(CX), R10, R11)
+	XORL AX, AX
+	LOAD38_DX(8(CX))
+	MX_CF(32(CX), R8, R9)
+	LOAD38_DX(16(CX))
+	MX_CF(24(CX), R8, R9)
+	MX_OF(32(CX), R10, R11)
+	LOAD19_DX(24(CX))
+	MX_OF(24(CX), R10, R11)
+
+	REDUCE01
+
+	// r2 on the CF chain, r3 on the OF chain
+	LOAD2_DX(0(CX))
+	MX_INIT(16(CX), R12, R13)
+	MX_INIT(24(CX), R14, R15)
+	XORL AX, AX
+	LOAD_DX(8(CX))
+	MX_CF(8(CX), R12, R13)
+	LOAD2_DX(8(CX))
+	MX_OF(16(CX), R14, R15)
+	LOAD38_DX(24(CX))
+	MX_CF(32(CX), R12, R13)
+	LOAD19_DX(32(CX))
+	MX_OF(32(CX), R14, R15)
+
+	REDUCE23
+
+	// r4
+	LOAD2_DX(0(CX))
+	MX_INIT(32(CX), R9, R11)
+	XORL AX, AX
+	LOAD2_DX(8(CX))
+	MX_CF(24(CX), R9, R11)
+	LOAD_DX(16(CX))
+	MX_CF(16(CX), R9, R11)
+
+	REDUCE4_STORE
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
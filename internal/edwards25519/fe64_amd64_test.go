@@ -0,0 +1,166 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 amd64 field arithmetic unit tests
+
+//go:build amd64 && !purego
+// +build amd64,!purego
+
+package edwards25519
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fe64Impl struct {
+	name   string
+	mul    func(out, a, b *fe64)
+	square func(out, a *fe64)
+}
+
+func fe64Impls(t testing.TB) []fe64Impl {
+	impls := []fe64Impl{{"MULQ", fe64MulMULQ, fe64SquareMULQ}}
+	if useADX {
+		impls = append(impls, fe64Impl{"ADX", fe64MulADX, fe64SquareADX})
+	} else {
+		t.Log("CPU lacks BMI2/ADX, skipping the ADX implementation")
+	}
+	return impls
+}
+
+// fe64EdgeValues returns field elements at the edges of the field and of
+// the limb bounds.
+func fe64EdgeValues() []fe64 {
+	const max = (1 << 51) - 1
+	// the largest limbs the operations return
+	const top, top0 = max + 1<<13, max + 19<<13
+	var values []fe64
+	for _, k := range []uint64{0, 1, 2, 18, 19, 20, 37, 38} {
+		// p - k, p + k and k
+		values = append(values,
+			fe64{max - 18 - k, max, max, max, max},
+			fe64{max - 18 + k, max, max, max, max},
+			fe64{k})
+	}
+	values = append(values,
+		fe64{max, max, max, max, max}, // 2^255 - 1
+		fe64{top0, top, top, top, top},
+		fe64{0, 0, 0, 0, top},
+		fe64{top0, 0, 0, 0, 0},
+		fe64{1 << 50, 1 << 50, 1 << 50, 1 << 50, 1 << 50},
+	)
+	return values
+}
+
+// randomFe64 returns an element with random limbs up to the operation
+// bounds.
+func randomFe64(rng *rand.Rand) fe64 {
+	var v fe64
+	for i := range v {
+		v[i] = rng.Uint64() & (1<<51 - 1)
+		if rng.Intn(8) == 0 {
+			v[i] += uint64(rng.Intn(19 << 13))
+		}
+	}
+	return v
+}
+
+// TestFe64Asm checks the assembly multiplication and squaring against the Go
+// implementation limb for limb, not only modulo p.
+func TestFe64Asm(t *testing.T) {
+	values := fe64EdgeValues()
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		values = append(values, randomFe64(rng))
+	}
+
+	for _, impl := range fe64Impls(t) {
+		for i := range values {
+			a := &values[i]
+			var want, got fe64
+			fe64SquareGeneric(&want, a)
+			impl.square(&got, a)
+			if !assert.Equal(t, want, got, "%s square %x", impl.name, *a) {
+				return
+			}
+
+			for _, b := range []*fe64{a, &values[(i+1)%len(values)], &values[rng.Intn(len(values))]} {
+				fe64MulGeneric(&want, a, b)
+				impl.mul(&got, a, b)
+				if !assert.Equal(t, want, got, "%s mul %x %x", impl.name, *a, *b) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// TestFe64AsmAliasing checks that the output may overlap the inputs.
+func TestFe64AsmAliasing(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, impl := range fe64Impls(t) {
+		a, b := randomFe64(rng), randomFe64(rng)
+		var want fe64
+		fe64MulGeneric(&want, &a, &b)
+		got := a
+		impl.mul(&got, &got, &b)
+		assert.Equal(t, want, got, impl.name)
+		got = b
+		impl.mul(&got, &a, &got)
+		assert.Equal(t, want, got, impl.name)
+
+		fe64SquareGeneric(&want, &a)
+		got = a
+		impl.square(&got, &got)
+		assert.Equal(t, want, got, impl.name)
+	}
+}
+
+func BenchmarkFe64MulGeneric(b *testing.B) {
+	x := fe64{1, 2, 3, 4, 5}
+	for i := 0; i < b.N; i++ {
+		fe64MulGeneric(&x, &x, &x)
+	}
+}
+
+func BenchmarkFe64MulMULQ(b *testing.B) {
+	x := fe64{1, 2, 3, 4, 5}
+	for i := 0; i < b.N; i++ {
+		fe64MulMULQ(&x, &x, &x)
+	}
+}
+
+func BenchmarkFe64MulADX(b *testing.B) {
+	if !useADX {
+		b.Skip("CPU lacks BMI2/ADX")
+	}
+	x := fe64{1, 2, 3, 4, 5}
+	for i := 0; i < b.N; i++ {
+		fe64MulADX(&x, &x, &x)
+	}
+}
+
+func BenchmarkFe64SquareGeneric(b *testing.B) {
+	x := fe64{1, 2, 3, 4, 5}
+	for i := 0; i < b.N; i++ {
+		fe64SquareGeneric(&x, &x)
+	}
+}
+
+func BenchmarkFe64SquareMULQ(b *testing.B) {
+	x := fe64{1, 2, 3, 4, 5}
+	for i := 0; i < b.N; i++ {
+		fe64SquareMULQ(&x, &x)
+	}
+}
+
+func BenchmarkFe64SquareADX(b *testing.B) {
+	if !useADX {
+		b.Skip("CPU lacks BMI2/ADX")
+	}
+	x := fe64{1, 2, 3, 4, 5}
+	for i := 0; i < b.N; i++ {
+		fe64SquareADX(&x, &x)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 radix 2^51 field multiplication and squaring in Go
+
+//go:build !amd64 || purego
+// +build !amd64 purego
+
+package edwards25519
+
+// fe64Mul calculates h = f * g. Can overlap h with f or g.
+func fe64Mul(h, f, g *fe64) {
+	fe64MulGeneric(h, f, g)
+}
+
+// fe64Square calculates h = f*f. Can overlap h with f.
+func fe64Square(h, f *fe64) {
+	fe64SquareGeneric(h, f)
+}
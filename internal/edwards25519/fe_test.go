@@ -0,0 +1,220 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 field arithmetic differential tests
+
+package edwards25519
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fePair holds a value in both field representations, so that every
+// operation can be applied to both and the results compared.
+type fePair struct {
+	a fe32
+	b fe64
+}
+
+func (p *fePair) bytes32() (s [32]byte) {
+	fe32ToBytes(&s, &p.a)
+	return s
+}
+
+func (p *fePair) bytes64() (s [32]byte) {
+	fe64ToBytes(&s, &p.b)
+	return s
+}
+
+func newFePair(s *[32]byte) fePair {
+	var p fePair
+	fe32FromBytes(&p.a, s)
+	fe64FromBytes(&p.b, s)
+	return p
+}
+
+// interestingBytes returns random encodings, with a bias towards the
+// edges of the field: small values, values near p and non-canonical ones.
+func interestingBytes(rng *rand.Rand) [32]byte {
+	var s [32]byte
+	switch rng.Intn(6) {
+	case 0:
+		s[0] = byte(rng.Intn(20))
+	case 1:
+		// p - k for small k
+		for i := range s {
+			s[i] = 0xff
+		}
+		s[31] = 0x7f
+		s[0] = 0xed - byte(rng.Intn(20))
+	case 2:
+		// p + k for small k, not canonical
+		for i := range s {
+			s[i] = 0xff
+		}
+		s[31] = 0x7f
+		s[0] = 0xed + byte(rng.Intn(18))
+	case 3:
+		// high bit set, which is ignored
+		rng.Read(s[:])
+		s[31] |= 0x80
+	default:
+		rng.Read(s[:])
+	}
+	return s
+}
+
+// TestFieldBackends checks that the 32-bit and 64-bit field arithmetic give
+// identical results, on chains of operations whose intermediate values stay
+// in each representation.
+func TestFieldBackends(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		x, y := interestingBytes(rng), interestingBytes(rng)
+		f, g := newFePair(&x), newFePair(&y)
+		require.Equal(t, f.bytes32(), f.bytes64(), "FromBytes %x", x)
+
+		for step := 0; step < 20; step++ {
+			var h fePair
+			switch op := rng.Intn(8); op {
+			case 0:
+				fe32Mul(&h.a, &f.a, &g.a)
+				fe64Mul(&h.b, &f.b, &g.b)
+			case 1:
+				fe32Square(&h.a, &f.a)
+				fe64Square(&h.b, &f.b)
+			case 2:
+				fe32Square2(&h.a, &f.a)
+				fe64Square2(&h.b, &f.b)
+			case 3:
+				// fe32 additions are only bounded when followed by a
+				// multiplication, as in the group operations
+				fe32Add(&h.a, &f.a, &g.a)
+				fe32Mul(&h.a, &h.a, &g.a)
+				fe64Add(&h.b, &f.b, &g.b)
+				fe64Mul(&h.b, &h.b, &g.b)
+			case 4:
+				fe32Sub(&h.a, &f.a, &g.a)
+				fe32Mul(&h.a, &h.a, &f.a)
+				fe64Sub(&h.b, &f.b, &g.b)
+				fe64Mul(&h.b, &h.b, &f.b)
+			case 5:
+				fe32Neg(&h.a, &f.a)
+				fe64Neg(&h.b, &f.b)
+			case 6:
+				b := int32(rng.Intn(2))
+				h = f
+				fe32CMove(&h.a, &g.a, b)
+				fe64CMove(&h.b, &g.b, b)
+			case 7:
+				b := int32(rng.Intn(2))
+				h = f
+				fe32CSwap(&h.a, &g.a, b)
+				fe64CSwap(&h.b, &g.b, b)
+			}
+			require.Equal(t, h.bytes32(), h.bytes64(), "iteration %d step %d", i, step)
+			f, g = g, h
+		}
+	}
+}
+
+// TestFe64AgainstBigInt checks fe64 against math/big.
+func TestFe64AgainstBigInt(t *testing.T) {
+	p, _ := new(big.Int).SetString("57896044618658097711785492504343953926634992332820282019728792003956564819949", 10)
+	toBig := func(s [32]byte) *big.Int {
+		for i, j := 0, 31; i < j; i, j = i+1, j-1 {
+			s[i], s[j] = s[j], s[i]
+		}
+		s[0] &= 0x7f
+		return new(big.Int).SetBytes(s[:])
+	}
+
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 1000; i++ {
+		x, y := interestingBytes(rng), interestingBytes(rng)
+		var f, g, h fe64
+		fe64FromBytes(&f, &x)
+		fe64FromBytes(&g, &y)
+		bx, by := toBig(x), toBig(y)
+
+		var s [32]byte
+		fe64Mul(&h, &f, &g)
+		fe64ToBytes(&s, &h)
+		want := new(big.Int).Mul(bx, by)
+		assert.Equal(t, want.Mod(want, p).String(), toBig(s).String())
+
+		fe64Sub(&h, &f, &g)
+		fe64ToBytes(&s, &h)
+		want = new(big.Int).Sub(bx, by)
+		assert.Equal(t, want.Mod(want, p).String(), toBig(s).String())
+
+		// canonical encoding
+		fe64FromBytes(&h, &x)
+		fe64ToBytes(&s, &h)
+		assert.Equal(t, new(big.Int).Mod(bx, p).String(), toBig(s).String())
+	}
+}
+
+// TestFeConst checks that the curve constants survive the conversion to
+// FieldElement.
+func TestFeConst(t *testing.T) {
+	var s [32]byte
+	var minusOne, sq FieldElement
+
+	// SqrtM1² = -1
+	FeSquare(&sq, &SqrtM1)
+	FeOne(&minusOne)
+	FeNeg(&minusOne, &minusOne)
+	var got, want [32]byte
+	FeToBytes(&got, &sq)
+	FeToBytes(&want, &minusOne)
+	assert.Equal(t, want, got)
+
+	// d2 = 2d
+	var d2Sum FieldElement
+	FeAdd(&d2Sum, &d, &d)
+	FeToBytes(&got, &d2Sum)
+	FeToBytes(&want, &d2)
+	assert.Equal(t, want, got)
+
+	// d = -121665/121666
+	var n, m FieldElement
+	FeFromBytes(&n, &[32]byte{0x41, 0xdb, 0x01}) // 121665
+	FeFromBytes(&m, &[32]byte{0x42, 0xdb, 0x01}) // 121666
+	FeMul(&m, &m, &d)
+	FeNeg(&n, &n)
+	FeToBytes(&got, &m)
+	FeToBytes(&s, &n)
+	assert.Equal(t, s, got)
+}
+
+func BenchmarkFe32Mul(b *testing.B) {
+	x := newFePair(&[32]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	for i := 0; i < b.N; i++ {
+		fe32Mul(&x.a, &x.a, &x.a)
+	}
+}
+
+func BenchmarkFe64Mul(b *testing.B) {
+	x := newFePair(&[32]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	for i := 0; i < b.N; i++ {
+		fe64Mul(&x.b, &x.b, &x.b)
+	}
+}
+
+func BenchmarkFe32Square(b *testing.B) {
+	x := newFePair(&[32]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	for i := 0; i < b.N; i++ {
+		fe32Square(&x.a, &x.a)
+	}
+}
+
+func BenchmarkFe64Square(b *testing.B) {
+	x := newFePair(&[32]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	for i := 0; i < b.N; i++ {
+		fe64Square(&x.b, &x.b)
+	}
+}
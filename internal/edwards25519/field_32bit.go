@@ -0,0 +1,31 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 field arithmetic on 32-bit platforms
+
+//go:build !(amd64 || arm64 || ppc64 || ppc64le || riscv64 || s390x || mips64 || mips64le || loong64) || ed25519_fe32
+// +build !amd64,!arm64,!ppc64,!ppc64le,!riscv64,!s390x,!mips64,!mips64le,!loong64 ed25519_fe32
+
+package edwards25519
+
+// FieldElement represents an element of the field GF(2^255 - 19). On 32-bit
+// platforms, and with the ed25519_fe32 build tag, it is an fe32, the ten
+// limb ref10 representation.
+type FieldElement = fe32
+
+// feConst converts a constant written in the ref10 representation.
+func feConst(f fe32) FieldElement {
+	return f
+}
+
+func FeZero(fe *FieldElement)                      { fe32Zero(fe) }
+func FeOne(fe *FieldElement)                       { fe32One(fe) }
+func FeAdd(dst, a, b *FieldElement)                { fe32Add(dst, a, b) }
+func FeSub(dst, a, b *FieldElement)                { fe32Sub(dst, a, b) }
+func FeCopy(dst, src *FieldElement)                { fe32Copy(dst, src) }
+func FeCMove(f, g *FieldElement, b int32)          { fe32CMove(f, g, b) }
+func FeCSwap(f, g *FieldElement, b int32)          { fe32CSwap(f, g, b) }
+func FeFromBytes(dst *FieldElement, src *[32]byte) { fe32FromBytes(dst, src) }
+func FeToBytes(s *[32]byte, h *FieldElement)       { fe32ToBytes(s, h) }
+func FeNeg(h, f *FieldElement)                     { fe32Neg(h, f) }
+func FeMul(h, f, g *FieldElement)                  { fe32Mul(h, f, g) }
+func FeSquare(h, f *FieldElement)                  { fe32Square(h, f) }
+func FeSquare2(h, f *FieldElement)                 { fe32Square2(h, f) }
@@ -0,0 +1,33 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 field arithmetic on 64-bit platforms
+
+//go:build (amd64 || arm64 || ppc64 || ppc64le || riscv64 || s390x || mips64 || mips64le || loong64) && !ed25519_fe32
+// +build amd64 arm64 ppc64 ppc64le riscv64 s390x mips64 mips64le loong64
+// +build !ed25519_fe32
+
+package edwards25519
+
+// FieldElement represents an element of the field GF(2^255 - 19). On 64-bit
+// platforms it is an fe64, five 51-bit limbs multiplied with math/bits.Mul64.
+// Build with the ed25519_fe32 tag to use the 32-bit fe32 instead.
+type FieldElement = fe64
+
+// feConst converts a constant written in the ref10 representation.
+func feConst(f fe32) (h FieldElement) {
+	fe64FromFe32(&h, &f)
+	return h
+}
+
+func FeZero(fe *FieldElement)                      { fe64Zero(fe) }
+func FeOne(fe *FieldElement)                       { fe64One(fe) }
+func FeAdd(dst, a, b *FieldElement)                { fe64Add(dst, a, b) }
+func FeSub(dst, a, b *FieldElement)                { fe64Sub(dst, a, b) }
+func FeCopy(dst, src *FieldElement)                { fe64Copy(dst, src) }
+func FeCMove(f, g *FieldElement, b int32)          { fe64CMove(f, g, b) }
+func FeCSwap(f, g *FieldElement, b int32)          { fe64CSwap(f, g, b) }
+func FeFromBytes(dst *FieldElement, src *[32]byte) { fe64FromBytes(dst, src) }
+func FeToBytes(s *[32]byte, h *FieldElement)       { fe64ToBytes(s, h) }
+func FeNeg(h, f *FieldElement)                     { fe64Neg(h, f) }
+func FeMul(h, f, g *FieldElement)                  { fe64Mul(h, f, g) }
+func FeSquare(h, f *FieldElement)                  { fe64Square(h, f) }
+func FeSquare2(h, f *FieldElement)                 { fe64Square2(h, f) }
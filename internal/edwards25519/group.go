@@ -0,0 +1,60 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 point and scalar helpers for multi-party signing
+
+package edwards25519
+
+// scOne is 1 and scMinusOne is l - 1, as scalars.
+var (
+	scOne      = [32]byte{1}
+	scMinusOne = [32]byte{
+		0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58, 0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+	}
+)
+
+// GeAdd sets r = p + q. Can overlap r with p or q.
+func GeAdd(r, p, q *ExtendedGroupElement) {
+	var c CachedGroupElement
+	var t CompletedGroupElement
+	q.ToCached(&c)
+	geAdd(&t, p, &c)
+	t.ToExtended(r)
+}
+
+// GeNeg sets r = -p. Can overlap r with p.
+func GeNeg(r, p *ExtendedGroupElement) {
+	FeNeg(&r.X, &p.X)
+	FeCopy(&r.Y, &p.Y)
+	FeCopy(&r.Z, &p.Z)
+	FeNeg(&r.T, &p.T)
+}
+
+// GeTorsionFree reports whether p is in the prime-order subgroup, that is
+// whether l·p is the identity. It is as slow as a scalar multiplication.
+func GeTorsionFree(p *ExtendedGroupElement) bool {
+	// (l - 1)·p = -p
+	var r ProjectiveGroupElement
+	var minusP ExtendedGroupElement
+	GeScalarMultVartime(&r, &scMinusOne, p)
+	GeNeg(&minusP, p)
+	var got, want [32]byte
+	r.ToBytes(&got)
+	minusP.ToBytes(&want)
+	return got == want
+}
+
+// ScAdd sets s = a + b mod l, for a and b as large as 2^256 - 1.
+func ScAdd(s, a, b *[32]byte) {
+	ScMulAdd(s, a, &scOne, b)
+}
+
+// ScSub sets s = a - b mod l, for a and b as large as 2^256 - 1.
+func ScSub(s, a, b *[32]byte) {
+	ScMulAdd(s, b, &scMinusOne, a)
+}
+
+// ScNeg sets s = -a mod l, for a as large as 2^256 - 1.
+func ScNeg(s, a *[32]byte) {
+	var zero [32]byte
+	ScMulAdd(s, a, &scMinusOne, &zero)
+}
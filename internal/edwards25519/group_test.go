@@ -0,0 +1,96 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 point and scalar helper unit tests
+
+package edwards25519
+
+import (
+	"encoding/hex"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScAddSubNeg(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 500; i++ {
+		a, b := interestingScalar(rng), interestingScalar(rng)
+		ba, bb := ToInt(a[:]), ToInt(b[:])
+
+		var s [32]byte
+		ScAdd(&s, &a, &b)
+		want := new(big.Int).Add(ba, bb)
+		assert.Equal(t, want.Mod(want, scOrder).String(), ToInt(s[:]).String())
+
+		ScSub(&s, &a, &b)
+		want = new(big.Int).Sub(ba, bb)
+		assert.Equal(t, want.Mod(want, scOrder).String(), ToInt(s[:]).String())
+
+		ScNeg(&s, &a)
+		want = new(big.Int).Neg(ba)
+		assert.Equal(t, want.Mod(want, scOrder).String(), ToInt(s[:]).String())
+	}
+}
+
+func TestGeAddNeg(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		a, b := rnd32Bytes(t), rnd32Bytes(t)
+		a[31] &= 127
+		b[31] &= 127
+
+		// a·B + b·B = (a + b)·B
+		var A, B, sum, want ExtendedGroupElement
+		GeScalarMultBase(&A, a)
+		GeScalarMultBase(&B, b)
+		GeAdd(&sum, &A, &B)
+		var ab [32]byte
+		ScAdd(&ab, a, b)
+		GeScalarMultBase(&want, &ab)
+		var got, wantBytes [32]byte
+		sum.ToBytes(&got)
+		want.ToBytes(&wantBytes)
+		assert.Equal(t, wantBytes, got)
+
+		// A + (-A) = 0, overlapping the output
+		var minusA ExtendedGroupElement
+		GeNeg(&minusA, &A)
+		GeAdd(&A, &A, &minusA)
+		A.ToBytes(&got)
+		assert.Equal(t, [32]byte{1}, got)
+	}
+}
+
+func TestGeTorsionFree(t *testing.T) {
+	var identity ExtendedGroupElement
+	identity.Zero()
+	assert.True(t, GeTorsionFree(&identity))
+
+	smallOrder := []string{
+		"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000080",
+		"c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a",
+		"26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05",
+	}
+	for i := 0; i < 4; i++ {
+		a := rnd32Bytes(t)
+		a[31] &= 127
+		var A ExtendedGroupElement
+		GeScalarMultBase(&A, a)
+		assert.True(t, GeTorsionFree(&A))
+
+		// A plus a point of order 2, 4 or 8 is not
+		for _, s := range smallOrder {
+			b, err := hex.DecodeString(s)
+			assert.NoError(t, err)
+			var enc [32]byte
+			copy(enc[:], b)
+			var T, sum ExtendedGroupElement
+			assert.True(t, T.FromBytes(&enc), s)
+			assert.False(t, GeTorsionFree(&T), s)
+			GeAdd(&sum, &A, &T)
+			assert.False(t, GeTorsionFree(&sum), s)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Spacemesh Authors
+// curve25519 Montgomery ladder and Edwards to Montgomery conversion
+
+package edwards25519
+
+// a24 is (486662+2)/4, the ladder constant of curve25519 in the form used by
+// the ref10 crypto_scalarmult implementation.
+var a24 = feConst(fe32{121666, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+
+// X25519 sets out to scalar·point on curve25519, where point is a
+// u-coordinate, as specified in RFC 7748, section 5: the scalar is clamped
+// and the most significant bit of point is ignored. It runs in constant time
+// with respect to scalar.
+func X25519(out, scalar, point *[32]byte) {
+	var e [32]byte
+	copy(e[:], scalar[:])
+	e[0] &= 248
+	e[31] &= 127
+	e[31] |= 64
+
+	var u [32]byte
+	copy(u[:], point[:])
+	u[31] &= 127
+
+	var x1, x2, z2, x3, z3, tmp0, tmp1 FieldElement
+	FeFromBytes(&x1, &u)
+	FeOne(&x2)
+	FeZero(&z2)
+	FeCopy(&x3, &x1)
+	FeOne(&z3)
+
+	swap := int32(0)
+	for pos := 254; pos >= 0; pos-- {
+		b := int32(e[pos/8]>>uint(pos&7)) & 1
+		swap ^= b
+		FeCSwap(&x2, &x3, swap)
+		FeCSwap(&z2, &z3, swap)
+		swap = b
+
+		FeSub(&tmp0, &x3, &z3)
+		FeSub(&tmp1, &x2, &z2)
+		FeAdd(&x2, &x2, &z2)
+		FeAdd(&z2, &x3, &z3)
+		FeMul(&z3, &tmp0, &x2)
+		FeMul(&z2, &z2, &tmp1)
+		FeSquare(&tmp0, &tmp1)
+		FeSquare(&tmp1, &x2)
+		FeAdd(&x3, &z3, &z2)
+		FeSub(&z2, &z3, &z2)
+		FeMul(&x2, &tmp1, &tmp0)
+		FeSub(&tmp1, &tmp1, &tmp0)
+		FeSquare(&z2, &z2)
+		FeMul(&z3, &tmp1, &a24)
+		FeSquare(&x3, &x3)
+		FeAdd(&tmp0, &tmp0, &z3)
+		FeMul(&z3, &x1, &z2)
+		FeMul(&z2, &tmp1, &tmp0)
+	}
+	FeCSwap(&x2, &x3, swap)
+	FeCSwap(&z2, &z3, swap)
+
+	FeInvert(&z2, &z2)
+	FeMul(&x2, &x2, &z2)
+	FeToBytes(out, &x2)
+}
+
+// ToMontgomery sets u to the curve25519 u-coordinate (1+y)/(1-y) of p. The
+// identity, whose y is 1, maps to 0.
+func (p *ExtendedGroupElement) ToMontgomery(u *[32]byte) {
+	var n, d FieldElement
+	FeAdd(&n, &p.Z, &p.Y)
+	FeSub(&d, &p.Z, &p.Y)
+	FeInvert(&d, &d)
+	FeMul(&n, &n, &d)
+	FeToBytes(u, &n)
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 Spacemesh Authors
+// curve25519 Montgomery ladder unit tests
+
+package edwards25519
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeCSwap(t *testing.T) {
+	var f, g FieldElement
+	FeFromBytes(&f, rnd32Bytes(t))
+	FeFromBytes(&g, rnd32Bytes(t))
+	f0, g0 := f, g
+
+	FeCSwap(&f, &g, 0)
+	assert.Equal(t, f0, f)
+	assert.Equal(t, g0, g)
+
+	FeCSwap(&f, &g, 1)
+	assert.Equal(t, g0, f)
+	assert.Equal(t, f0, g)
+}
+
+// TestToMontgomery checks that the u-coordinate of a·B matches the X25519
+// ladder applied to the base point u = 9.
+func TestToMontgomery(t *testing.T) {
+	base := [32]byte{9}
+	for i := 0; i < 32; i++ {
+		a := rnd32Bytes(t)
+		a[0] &= 248
+		a[31] &= 127
+		a[31] |= 64
+
+		var A ExtendedGroupElement
+		GeScalarMultBase(&A, a)
+		var u, want [32]byte
+		A.ToMontgomery(&u)
+		X25519(&want, a, &base)
+		assert.Equal(t, want, u)
+	}
+}
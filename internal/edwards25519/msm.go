@@ -0,0 +1,177 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 variable-time multi-scalar multiplication
+
+package edwards25519
+
+// strausMaxPoints is the largest number of points GeMultiScalarMultVartime
+// multiplies with Straus' method; Pippenger's is faster from there on.
+const strausMaxPoints = 160
+
+// GeMultiScalarMultVartime sets h = a[0]*A[0] + a[1]*A[1] + ... + a[n-1]*A[n-1]
+// in variable time. For few points it shares the doublings of
+// GeDoubleScalarMultVartime between all of them (Straus); for many it sorts
+// the points into buckets by digit (Pippenger).
+//
+// Preconditions:
+//
+//	len(a) == len(A)
+//	a[i][31] <= 127
+func GeMultiScalarMultVartime(h *ExtendedGroupElement, a [][32]byte, A []ExtendedGroupElement) {
+	if len(a) != len(A) {
+		panic("edwards25519: mismatched numbers of scalars and points")
+	}
+	if len(a) <= strausMaxPoints {
+		geMultiScalarMultStraus(h, a, A)
+	} else {
+		geMultiScalarMultPippenger(h, a, A)
+	}
+}
+
+// geMultiScalarMultStraus computes the sum with one table of odd multiples
+// and one slide recoding per point, and a single chain of doublings.
+func geMultiScalarMultStraus(h *ExtendedGroupElement, a [][32]byte, A []ExtendedGroupElement) {
+	slides := make([][256]int8, len(a))
+	tables := make([][8]CachedGroupElement, len(A)) // A,3A,5A,...,15A
+	var t CompletedGroupElement
+	var u, A2 ExtendedGroupElement
+
+	top := -1
+	for k := range a {
+		slide(&slides[k], &a[k])
+		for i := 255; i > top; i-- {
+			if slides[k][i] != 0 {
+				top = i
+				break
+			}
+		}
+
+		Ai := &tables[k]
+		A[k].ToCached(&Ai[0])
+		A[k].Double(&t)
+		t.ToExtended(&A2)
+		for i := 0; i < 7; i++ {
+			geAdd(&t, &A2, &Ai[i])
+			t.ToExtended(&u)
+			u.ToCached(&Ai[i+1])
+		}
+	}
+
+	if top < 0 {
+		h.Zero()
+		return
+	}
+	var r ProjectiveGroupElement
+	r.Zero()
+	for i := top; i >= 0; i-- {
+		r.Double(&t)
+
+		for k := range slides {
+			if d := slides[k][i]; d > 0 {
+				t.ToExtended(&u)
+				geAdd(&t, &u, &tables[k][d/2])
+			} else if d < 0 {
+				t.ToExtended(&u)
+				geSub(&t, &u, &tables[k][(-d)/2])
+			}
+		}
+
+		t.ToProjective(&r)
+	}
+	t.ToExtended(h)
+}
+
+// pippengerWindow returns the digit size in bits for n points, which
+// balances the additions into buckets, n per digit, against the two
+// additions per bucket that sum them.
+func pippengerWindow(n int) uint {
+	switch {
+	case n < 500:
+		return 6
+	case n < 800:
+		return 7
+	default:
+		return 8
+	}
+}
+
+// geMultiScalarMultPippenger computes the sum with signed radix 2^c digits:
+// for every digit position, from the top, it doubles the sum c times, adds
+// each point to or subtracts it from the bucket of its digit, and adds
+// Σ j·bucket[j] to the sum.
+func geMultiScalarMultPippenger(h *ExtendedGroupElement, a [][32]byte, A []ExtendedGroupElement) {
+	c := pippengerWindow(len(a))
+	// with a < 2^255 the carry out of the last full digit fits in one more
+	positions := 256/int(c) + 1
+	buckets := 1 << (c - 1)
+
+	digits := make([]int16, positions*len(a))
+	for k := range a {
+		recodeSigned(digits[k*positions:(k+1)*positions], &a[k], c)
+	}
+	cached := make([]CachedGroupElement, len(A))
+	for k := range A {
+		A[k].ToCached(&cached[k])
+	}
+
+	bucket := make([]ExtendedGroupElement, buckets)
+	var t CompletedGroupElement
+	var sum, running ExtendedGroupElement
+	var ct CachedGroupElement
+	var p ProjectiveGroupElement
+	h.Zero()
+	for pos := positions - 1; pos >= 0; pos-- {
+		if pos != positions-1 {
+			h.ToProjective(&p)
+			for i := uint(0); i < c; i++ {
+				p.Double(&t)
+				t.ToProjective(&p)
+			}
+			t.ToExtended(h)
+		}
+
+		for j := range bucket {
+			bucket[j].Zero()
+		}
+		for k := range cached {
+			if d := digits[k*positions+pos]; d > 0 {
+				geAdd(&t, &bucket[d-1], &cached[k])
+				t.ToExtended(&bucket[d-1])
+			} else if d < 0 {
+				geSub(&t, &bucket[-d-1], &cached[k])
+				t.ToExtended(&bucket[-d-1])
+			}
+		}
+
+		// Σ j·bucket[j-1] as a sum of running sums, from the top bucket
+		running = bucket[buckets-1]
+		sum = running
+		for j := buckets - 2; j >= 0; j-- {
+			bucket[j].ToCached(&ct)
+			geAdd(&t, &running, &ct)
+			t.ToExtended(&running)
+			running.ToCached(&ct)
+			geAdd(&t, &sum, &ct)
+			t.ToExtended(&sum)
+		}
+		sum.ToCached(&ct)
+		geAdd(&t, h, &ct)
+		t.ToExtended(h)
+	}
+}
+
+// recodeSigned sets e to the digits of a in radix 2^c, each between
+// -2^(c-1) and 2^(c-1), least significant first. e must have room for the
+// final carry.
+func recodeSigned(e []int16, a *[32]byte, c uint) {
+	var carry int16
+	for i := range e {
+		bit := i * int(c)
+		var v uint32
+		for j := 0; j < 3 && bit/8+j < 32; j++ {
+			v |= uint32(a[bit/8+j]) << (8 * j)
+		}
+		e[i] = int16(v>>(bit%8)&(1<<c-1)) + carry
+		carry = (e[i] + 1<<(c-1)) >> c
+		e[i] -= carry << c
+	}
+}
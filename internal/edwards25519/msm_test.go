@@ -0,0 +1,186 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 multi-scalar multiplication unit tests
+
+package edwards25519
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// multiScalarMultNaive returns the encoding of Σ a[i]*A[i] computed with one
+// GeScalarMultVartime per point.
+func multiScalarMultNaive(a [][32]byte, A []ExtendedGroupElement) [32]byte {
+	var sum, u ExtendedGroupElement
+	var p ProjectiveGroupElement
+	var c CachedGroupElement
+	var t CompletedGroupElement
+	sum.Zero()
+	for i := range a {
+		GeScalarMultVartime(&p, &a[i], &A[i])
+		p.ToExtended(&u)
+		u.ToCached(&c)
+		geAdd(&t, &sum, &c)
+		t.ToExtended(&sum)
+	}
+	var out [32]byte
+	sum.ToBytes(&out)
+	return out
+}
+
+// randomMSMInput returns n random scalars below 2^255 and n random
+// multiples of B.
+func randomMSMInput(n int) ([][32]byte, []ExtendedGroupElement) {
+	rng := rand.New(rand.NewSource(int64(n)))
+	a := make([][32]byte, n)
+	A := make([]ExtendedGroupElement, n)
+	for i := range a {
+		var s [32]byte
+		rng.Read(s[:])
+		s[31] &= 127
+		GeScalarMultBase(&A[i], &s)
+		rng.Read(a[i][:])
+		a[i][31] &= 127
+	}
+	return a, A
+}
+
+func TestGeMultiScalarMultVartime(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 7, 64, strausMaxPoints + 1, 600, 900} {
+		a, A := randomMSMInput(n)
+		want := multiScalarMultNaive(a, A)
+
+		var h ExtendedGroupElement
+		var got [32]byte
+		GeMultiScalarMultVartime(&h, a, A)
+		h.ToBytes(&got)
+		assert.Equal(t, want, got, "n = %d", n)
+
+		geMultiScalarMultStraus(&h, a, A)
+		h.ToBytes(&got)
+		assert.Equal(t, want, got, "Straus, n = %d", n)
+
+		geMultiScalarMultPippenger(&h, a, A)
+		h.ToBytes(&got)
+		assert.Equal(t, want, got, "Pippenger, n = %d", n)
+	}
+}
+
+func TestGeMultiScalarMultVartimeEdgeCases(t *testing.T) {
+	var max [32]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	max[31] = 127
+	// l - 1
+	lMinus1 := [32]byte{
+		0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58, 0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+	}
+
+	// zero and extreme scalars, the identity and repeated points, and a
+	// sum that cancels: 1*P + (l-1)*P
+	P := randomPoint(t)
+	var identity ExtendedGroupElement
+	identity.Zero()
+	a := [][32]byte{{}, {1}, lMinus1, max, {0x20}, {0xe0, 0xff}, max}
+	A := []ExtendedGroupElement{*P, *P, *P, identity, *P, *randomPoint(t), *P}
+
+	for _, n := range []int{1, 3, len(a)} {
+		want := multiScalarMultNaive(a[:n], A[:n])
+		var h ExtendedGroupElement
+		var got [32]byte
+		geMultiScalarMultStraus(&h, a[:n], A[:n])
+		h.ToBytes(&got)
+		assert.Equal(t, want, got, "Straus, n = %d", n)
+		geMultiScalarMultPippenger(&h, a[:n], A[:n])
+		h.ToBytes(&got)
+		assert.Equal(t, want, got, "Pippenger, n = %d", n)
+	}
+
+	var h ExtendedGroupElement
+	var got, want [32]byte
+	identity.ToBytes(&want)
+	geMultiScalarMultStraus(&h, a[1:3], A[1:3])
+	h.ToBytes(&got)
+	assert.Equal(t, want, got)
+	geMultiScalarMultPippenger(&h, a[1:3], A[1:3])
+	h.ToBytes(&got)
+	assert.Equal(t, want, got)
+
+	assert.Panics(t, func() { GeMultiScalarMultVartime(&h, a[:2], A[:1]) })
+}
+
+func TestRecodeSigned(t *testing.T) {
+	for c := uint(4); c <= 8; c++ {
+		a := rnd32Bytes(t)
+		a[31] &= 127
+		e := make([]int16, 256/int(c)+1)
+		recodeSigned(e, a, c)
+
+		// Σ e[i]·2^(c·i) mod l, against a mod l
+		var sum, pow, d [32]byte
+		pow[0] = 1
+		lMinus1 := [32]byte{0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58, 0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14}
+		lMinus1[31] = 0x10
+		step := [32]byte{}
+		step[c/8] = 1 << (c % 8)
+		for i := range e {
+			assert.LessOrEqual(t, e[i], int16(1<<(c-1)))
+			assert.GreaterOrEqual(t, e[i], -int16(1<<(c-1)))
+			d = [32]byte{}
+			if e[i] < 0 {
+				d[0], d[1] = byte(-e[i]), byte(-e[i]>>8)
+				ScMul(&d, &d, &lMinus1)
+			} else {
+				d[0], d[1] = byte(e[i]), byte(e[i]>>8)
+			}
+			ScMulAdd(&sum, &d, &pow, &sum)
+			ScMul(&pow, &pow, &step)
+		}
+		var wide [64]byte
+		var want [32]byte
+		copy(wide[:], a[:])
+		ScReduce(&want, &wide)
+		assert.Equal(t, want, sum, "c = %d", c)
+	}
+}
+
+func BenchmarkGeMultiScalarMultVartime(bench *testing.B) {
+	for n := 2; n <= 4096; n *= 2 {
+		a, A := randomMSMInput(n)
+		bench.Run(strconv.Itoa(n), func(bench *testing.B) {
+			var h ExtendedGroupElement
+			for i := 0; i < bench.N; i++ {
+				GeMultiScalarMultVartime(&h, a, A)
+			}
+		})
+	}
+}
+
+func BenchmarkGeMultiScalarMultStraus(bench *testing.B) {
+	for n := 64; n <= 512; n *= 2 {
+		a, A := randomMSMInput(n)
+		bench.Run(strconv.Itoa(n), func(bench *testing.B) {
+			var h ExtendedGroupElement
+			for i := 0; i < bench.N; i++ {
+				geMultiScalarMultStraus(&h, a, A)
+			}
+		})
+	}
+}
+
+func BenchmarkGeMultiScalarMultPippenger(bench *testing.B) {
+	for n := 64; n <= 4096; n *= 2 {
+		a, A := randomMSMInput(n)
+		bench.Run(strconv.Itoa(n), func(bench *testing.B) {
+			var h ExtendedGroupElement
+			for i := 0; i < bench.N; i++ {
+				geMultiScalarMultPippenger(&h, a, A)
+			}
+		})
+	}
+}
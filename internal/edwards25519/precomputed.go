@@ -0,0 +1,68 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 precomputed multiples for repeated double scalar multiplication
+
+package edwards25519
+
+// PreComputedMultiples holds the odd multiples A, 3A, ..., 63A of a point A
+// for GeDoubleScalarMultPrecomputedVartime. Its window is two bits wider
+// than the one GeDoubleScalarMultVartime builds on every call, which pays
+// off when A is used many times.
+type PreComputedMultiples [32]CachedGroupElement
+
+// FromPoint sets m to the odd multiples of A.
+func (m *PreComputedMultiples) FromPoint(A *ExtendedGroupElement) {
+	var t CompletedGroupElement
+	var u, A2 ExtendedGroupElement
+
+	A.ToCached(&m[0])
+	A.Double(&t)
+	t.ToExtended(&A2)
+
+	for i := 0; i < len(m)-1; i++ {
+		geAdd(&t, &A2, &m[i])
+		t.ToExtended(&u)
+		u.ToCached(&m[i+1])
+	}
+}
+
+// GeDoubleScalarMultPrecomputedVartime sets r = a*A + b*B, like
+// GeDoubleScalarMultVartime, where A holds the multiples of A.
+func GeDoubleScalarMultPrecomputedVartime(r *ProjectiveGroupElement, a *[32]byte, A *PreComputedMultiples, b *[32]byte) {
+	var aSlide, bSlide [256]int8
+	var t CompletedGroupElement
+	var u ExtendedGroupElement
+	var i int
+
+	slideWindow(&aSlide, a, 2*int8(len(A))-1)
+	slide(&bSlide, b)
+
+	r.Zero()
+
+	for i = 255; i >= 0; i-- {
+		if aSlide[i] != 0 || bSlide[i] != 0 {
+			break
+		}
+	}
+
+	for ; i >= 0; i-- {
+		r.Double(&t)
+
+		if aSlide[i] > 0 {
+			t.ToExtended(&u)
+			geAdd(&t, &u, &A[aSlide[i]/2])
+		} else if aSlide[i] < 0 {
+			t.ToExtended(&u)
+			geSub(&t, &u, &A[(-aSlide[i])/2])
+		}
+
+		if bSlide[i] > 0 {
+			t.ToExtended(&u)
+			geMixedAdd(&t, &u, &bi[bSlide[i]/2])
+		} else if bSlide[i] < 0 {
+			t.ToExtended(&u)
+			geMixedSub(&t, &u, &bi[(-bSlide[i])/2])
+		}
+
+		t.ToProjective(r)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 precomputed multiples unit tests
+
+package edwards25519
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlideWindow(t *testing.T) {
+	for i := 0; i < 64; i++ {
+		a := rnd32Bytes(t)
+		a[31] &= 127
+		var r [256]int8
+		slideWindow(&r, a, 63)
+
+		// the digits are odd, small and add up to a
+		var sum, pow [32]byte
+		pow[0] = 1
+		two := [32]byte{2}
+		lMinusOne := [32]byte{0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58, 0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14}
+		lMinusOne[31] = 0x10
+		for j := range r {
+			if r[j] != 0 {
+				assert.Equal(t, int8(1), r[j]&1)
+				assert.LessOrEqual(t, r[j], int8(63))
+				assert.GreaterOrEqual(t, r[j], int8(-63))
+				d := [32]byte{byte(r[j])}
+				if r[j] < 0 {
+					d[0] = byte(-r[j])
+					ScMul(&d, &d, &lMinusOne)
+				}
+				ScMulAdd(&sum, &d, &pow, &sum)
+			}
+			ScMul(&pow, &pow, &two)
+		}
+		var want [32]byte
+		var wide [64]byte
+		copy(wide[:], a[:])
+		ScReduce(&want, &wide)
+		assert.Equal(t, want, sum)
+	}
+}
+
+func TestGeDoubleScalarMultPrecomputedVartime(t *testing.T) {
+	var max [32]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	max[31] = 127
+	scalars := []*[32]byte{{}, {1}, {63}, {65}, {0x7f, 0x7f}, &max}
+	for i := 0; i < 32; i++ {
+		a := rnd32Bytes(t)
+		a[31] &= 127
+		scalars = append(scalars, a)
+	}
+
+	A := randomPoint(t)
+	var m PreComputedMultiples
+	m.FromPoint(A)
+	for _, a := range scalars {
+		b := rnd32Bytes(t)
+		b[31] &= 127
+		var r ProjectiveGroupElement
+		var got, want [32]byte
+		GeDoubleScalarMultVartime(&r, a, A, b)
+		r.ToBytes(&want)
+		GeDoubleScalarMultPrecomputedVartime(&r, a, &m, b)
+		r.ToBytes(&got)
+		assert.Equal(t, want, got, "%x", a)
+	}
+}
+
+func BenchmarkGeDoubleScalarMultVartime(bench *testing.B) {
+	a, b := rnd32BytesBench(bench), rnd32BytesBench(bench)
+	a[31] &= 127
+	b[31] &= 127
+	var A ExtendedGroupElement
+	GeScalarMultBase(&A, rnd32BytesBench(bench))
+	var r ProjectiveGroupElement
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		GeDoubleScalarMultVartime(&r, a, &A, b)
+	}
+}
+
+func BenchmarkGeDoubleScalarMultPrecomputedVartime(bench *testing.B) {
+	a, b := rnd32BytesBench(bench), rnd32BytesBench(bench)
+	a[31] &= 127
+	b[31] &= 127
+	var A ExtendedGroupElement
+	GeScalarMultBase(&A, rnd32BytesBench(bench))
+	var m PreComputedMultiples
+	m.FromPoint(&A)
+	var r ProjectiveGroupElement
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		GeDoubleScalarMultPrecomputedVartime(&r, a, &m, b)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 safegcd inversion mod l
+
+package edwards25519
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// This is the Bernstein–Yang safegcd inversion, "Fast constant-time gcd
+// computation and modular inversion" (https://eprint.iacr.org/2019/266),
+// with the improvements of libsecp256k1's modinv64: batches of divsteps on
+// the low 64 bits of f and g build a 2×2 transition matrix, which is then
+// applied to the full f, g and to the Bézout coefficients d, e kept mod l.
+
+// signed62 holds a signed integer v[0]+2^62 v[1]+2^124 v[2]+2^186 v[3]+2^248
+// v[4]. The low four limbs are usually in [0, 2^62) and the top one carries
+// the sign.
+type signed62 [5]int64
+
+const mask62 = (1 << 62) - 1
+
+// scL62 is l as a signed62.
+var scL62 = signed62{0x1812631a5cf5d3ed, 0x137be77a8bde7359, 1, 0, 16}
+
+// scL62Inv is 1/l mod 2^62.
+const scL62Inv uint64 = 0x2d4ae25cedab81e5
+
+// trans2x2 is the transition matrix [u v; q r] of a batch of divsteps,
+// scaled by 2^62.
+type trans2x2 struct {
+	u, v, q, r int64
+}
+
+// int128 holds a signed 128-bit value in two's complement.
+type int128 struct {
+	lo, hi uint64
+}
+
+// mulAddS64 returns x + a·b for signed a and b, in constant time.
+func mulAddS64(x int128, a, b int64) int128 {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	// the unsigned product is off by 2^64·b if a < 0 and 2^64·a if b < 0
+	hi -= uint64(a>>63)&uint64(b) + uint64(b>>63)&uint64(a)
+	var c uint64
+	lo, c = bits.Add64(lo, x.lo, 0)
+	hi, _ = bits.Add64(hi, x.hi, c)
+	return int128{lo, hi}
+}
+
+// sar62 returns x >> 62, an arithmetic shift.
+func sar62(x int128) int128 {
+	return int128{x.lo>>62 | x.hi<<2, uint64(int64(x.hi) >> 62)}
+}
+
+func signed62FromBytes(s *signed62, b *[32]byte) {
+	w0 := binary.LittleEndian.Uint64(b[0:8])
+	w1 := binary.LittleEndian.Uint64(b[8:16])
+	w2 := binary.LittleEndian.Uint64(b[16:24])
+	w3 := binary.LittleEndian.Uint64(b[24:32])
+
+	s[0] = int64(w0 & mask62)
+	s[1] = int64((w0>>62 | w1<<2) & mask62)
+	s[2] = int64((w1>>60 | w2<<4) & mask62)
+	s[3] = int64((w2>>58 | w3<<6) & mask62)
+	s[4] = int64(w3 >> 56)
+}
+
+// signed62ToBytes encodes s, which must be normalized to [0, l).
+func signed62ToBytes(b *[32]byte, s *signed62) {
+	binary.LittleEndian.PutUint64(b[0:8], uint64(s[0])|uint64(s[1])<<62)
+	binary.LittleEndian.PutUint64(b[8:16], uint64(s[1])>>2|uint64(s[2])<<60)
+	binary.LittleEndian.PutUint64(b[16:24], uint64(s[2])>>4|uint64(s[3])<<58)
+	binary.LittleEndian.PutUint64(b[24:32], uint64(s[3])>>6|uint64(s[4])<<56)
+}
+
+// divsteps59 performs 59 divsteps on the low bits of f and g in constant
+// time, with zeta = -(delta + 1/2), and returns the new zeta and the
+// transition matrix scaled by 2^62.
+func divsteps59(zeta int64, f0, g0 uint64, t *trans2x2) int64 {
+	// the matrix starts as 8 times the identity, so that 59 doublings scale
+	// it by 2^62; the entries stay in [-2^62, 2^62]
+	u, v, q, r := uint64(8), uint64(0), uint64(0), uint64(8)
+	f, g := f0, g0
+
+	for i := 3; i < 62; i++ {
+		// masks for zeta < 0 and for g odd
+		c1 := uint64(zeta >> 63)
+		c2 := -(g & 1)
+		// conditionally negated f, u and v, added to g, q and r if g is odd
+		x := (f ^ c1) - c1
+		y := (u ^ c1) - c1
+		z := (v ^ c1) - c1
+		g += x & c2
+		q += y & c2
+		r += z & c2
+		// if both, swap: zeta becomes -zeta-2, and f, u, v get the old g,
+		// q, r back
+		c1 &= c2
+		zeta = (zeta ^ int64(c1)) - 1
+		f += g & c1
+		u += q & c1
+		v += r & c1
+		g >>= 1
+		u <<= 1
+		v <<= 1
+	}
+
+	*t = trans2x2{int64(u), int64(v), int64(q), int64(r)}
+	return zeta
+}
+
+// divsteps62Vartime performs 62 divsteps on the low bits of f and g, with
+// eta = -delta, and returns the new eta and the transition matrix scaled by
+// 2^62. It takes many divsteps at once and runs in variable time.
+func divsteps62Vartime(eta int64, f0, g0 uint64, t *trans2x2) int64 {
+	u, v, q, r := uint64(1), uint64(0), uint64(0), uint64(1)
+	f, g := f0, g0
+	i := 62
+
+	for {
+		// divsteps with g even only halve g; the sentinel bit stops at i
+		zeros := bits.TrailingZeros64(g | (^uint64(0) << uint(i)))
+		g >>= uint(zeros)
+		u <<= uint(zeros)
+		v <<= uint(zeros)
+		eta -= int64(zeros)
+		i -= zeros
+		if i == 0 {
+			break
+		}
+
+		// g is odd now; cancel as many of its low bits as possible by
+		// adding a multiple w of f, after swapping f and g if eta < 0
+		var w, m uint64
+		limit := int(eta) + 1
+		if eta < 0 {
+			eta = -eta
+			f, g = g, -f
+			u, q = q, -u
+			v, r = r, -v
+			limit = int(eta) + 1
+			if limit > i {
+				limit = i
+			}
+			// up to 6 bits, with w = -g/f mod 2^6
+			m = (^uint64(0) >> uint(64-limit)) & 63
+			w = (f * g * (f*f - 2)) & m
+		} else {
+			if limit > i {
+				limit = i
+			}
+			// up to 4 bits
+			m = (^uint64(0) >> uint(64-limit)) & 15
+			w = f + (((f + 1) & 4) << 1)
+			w = (-w * g) & m
+		}
+		g += f * w
+		q += u * w
+		r += v * w
+	}
+
+	*t = trans2x2{int64(u), int64(v), int64(q), int64(r)}
+	return eta
+}
+
+// updateDE sets [d, e] = t·[d, e]/2^62 mod l. d and e must be in (-2l, l)
+// and stay there.
+func updateDE(d, e *signed62, t *trans2x2) {
+	u, v, q, r := t.u, t.v, t.q, t.r
+
+	// add multiples md, me of l so that the results are not negative and
+	// their low 62 bits are zero
+	sd := d[4] >> 63
+	se := e[4] >> 63
+	md := (u & sd) + (v & se)
+	me := (q & sd) + (r & se)
+
+	cd := mulAddS64(mulAddS64(int128{}, u, d[0]), v, e[0])
+	ce := mulAddS64(mulAddS64(int128{}, q, d[0]), r, e[0])
+	md -= int64((scL62Inv*cd.lo + uint64(md)) & mask62)
+	me -= int64((scL62Inv*ce.lo + uint64(me)) & mask62)
+	cd = sar62(mulAddS64(cd, scL62[0], md))
+	ce = sar62(mulAddS64(ce, scL62[0], me))
+
+	// the remaining limbs, shifted down by one; l[3] is zero
+	for i := 1; i < 5; i++ {
+		cd = mulAddS64(mulAddS64(cd, u, d[i]), v, e[i])
+		ce = mulAddS64(mulAddS64(ce, q, d[i]), r, e[i])
+		if i != 3 {
+			cd = mulAddS64(cd, scL62[i], md)
+			ce = mulAddS64(ce, scL62[i], me)
+		}
+		d[i-1] = int64(cd.lo & mask62)
+		e[i-1] = int64(ce.lo & mask62)
+		cd = sar62(cd)
+		ce = sar62(ce)
+	}
+	d[4] = int64(cd.lo)
+	e[4] = int64(ce.lo)
+}
+
+// updateFG sets [f, g] = t·[f, g]/2^62, using the low n limbs of f and g.
+func updateFG(f, g *signed62, t *trans2x2, n int) {
+	u, v, q, r := t.u, t.v, t.q, t.r
+
+	cf := mulAddS64(mulAddS64(int128{}, u, f[0]), v, g[0])
+	cg := mulAddS64(mulAddS64(int128{}, q, f[0]), r, g[0])
+	cf = sar62(cf)
+	cg = sar62(cg)
+	for i := 1; i < n; i++ {
+		cf = mulAddS64(mulAddS64(cf, u, f[i]), v, g[i])
+		cg = mulAddS64(mulAddS64(cg, q, f[i]), r, g[i])
+		f[i-1] = int64(cf.lo & mask62)
+		g[i-1] = int64(cg.lo & mask62)
+		cf = sar62(cf)
+		cg = sar62(cg)
+	}
+	f[n-1] = int64(cf.lo)
+	g[n-1] = int64(cg.lo)
+}
+
+// normalize62 brings s from (-2l, l) to [0, l), negating it first if sign
+// is negative.
+func normalize62(s *signed62, sign int64) {
+	// add l if s is negative, then negate if requested
+	add := s[4] >> 63
+	neg := sign >> 63
+	for i := range s {
+		s[i] += scL62[i] & add
+		s[i] = (s[i] ^ neg) - neg
+	}
+	s.carry()
+
+	// s is in (-l, l) now; add l again if it is still negative
+	add = s[4] >> 63
+	for i := range s {
+		s[i] += scL62[i] & add
+	}
+	s.carry()
+}
+
+// carry brings the low limbs of s to [0, 2^62).
+func (s *signed62) carry() {
+	for i := 0; i < 4; i++ {
+		s[i+1] += s[i] >> 62
+		s[i] &= mask62
+	}
+}
+
+// ScInvert sets out = 1/z mod l, in time independent of z, or 0 if z is a
+// multiple of l. z may be as large as 2^256 - 1.
+func ScInvert(out, z *[32]byte) {
+	var wide [64]byte
+	var zr [32]byte
+	copy(wide[:], z[:])
+	ScReduce(&zr, &wide)
+
+	d, e := signed62{}, signed62{1}
+	f := scL62
+	var g signed62
+	signed62FromBytes(&g, &zr)
+
+	// 10 batches of 59 divsteps are enough for 256-bit inputs
+	zeta := int64(-1)
+	for i := 0; i < 10; i++ {
+		var t trans2x2
+		zeta = divsteps59(zeta, uint64(f[0]), uint64(g[0]), &t)
+		updateDE(&d, &e, &t)
+		updateFG(&f, &g, &t, 5)
+	}
+
+	// g is 0 and f is ±1, the gcd, so d is ±1/z
+	normalize62(&d, f[4])
+	signed62ToBytes(out, &d)
+}
+
+// ScInvertVartime sets out = 1/z mod l, or 0 if z is a multiple of l. z may
+// be as large as 2^256 - 1. It runs in variable time and must only be used
+// on public values.
+func ScInvertVartime(out, z *[32]byte) {
+	var wide [64]byte
+	var zr [32]byte
+	copy(wide[:], z[:])
+	ScReduce(&zr, &wide)
+
+	d, e := signed62{}, signed62{1}
+	f := scL62
+	var g signed62
+	signed62FromBytes(&g, &zr)
+
+	eta := int64(-1)
+	n := len(f)
+	for {
+		var t trans2x2
+		eta = divsteps62Vartime(eta, uint64(f[0]), uint64(g[0]), &t)
+		updateDE(&d, &e, &t)
+		updateFG(&f, &g, &t, n)
+
+		if g[0] == 0 {
+			var c int64
+			for i := 1; i < n; i++ {
+				c |= g[i]
+			}
+			if c == 0 {
+				break
+			}
+		}
+
+		// drop the top limbs of f and g once they are both 0 or -1,
+		// moving their sign into the limb below
+		fn, gn := f[n-1], g[n-1]
+		if n > 1 && fn^(fn>>63) == 0 && gn^(gn>>63) == 0 {
+			f[n-2] |= int64(uint64(fn) << 62)
+			g[n-2] |= int64(uint64(gn) << 62)
+			n--
+		}
+	}
+
+	normalize62(&d, f[n-1])
+	signed62ToBytes(out, &d)
+}
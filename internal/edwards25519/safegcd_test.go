@@ -0,0 +1,104 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 safegcd inversion unit tests
+
+package edwards25519
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScInvert(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var lBytes [32]byte
+	signed62ToBytes(&lBytes, &scL62)
+	values := [][32]byte{{}, {1}, {2}, {17}, lBytes}
+	for i := 0; i < 2000; i++ {
+		values = append(values, interestingScalar(rng))
+	}
+
+	for _, z := range values {
+		bz := new(big.Int).Mod(ToInt(z[:]), scOrder)
+		want := "0"
+		if bz.Sign() != 0 {
+			want = new(big.Int).ModInverse(bz, scOrder).String()
+		}
+
+		var ct, vartime [32]byte
+		ScInvert(&ct, &z)
+		ScInvertVartime(&vartime, &z)
+		if !assert.Equal(t, want, ToInt(ct[:]).String(), "ScInvert %x", z) ||
+			!assert.Equal(t, want, ToInt(vartime[:]).String(), "ScInvertVartime %x", z) {
+			return
+		}
+	}
+}
+
+func TestScInvertMatchesInvertModL(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		z := rnd32Bytes(t)
+		var want, ct, vartime [32]byte
+		InvertModL(&want, z)
+		ScInvert(&ct, z)
+		ScInvertVartime(&vartime, z)
+		assert.Equal(t, want, ct)
+		assert.Equal(t, want, vartime)
+	}
+
+	var x, inv [32]byte
+	x[0] = 17
+	ScInvert(&inv, &x)
+	assert.Equal(t, INV_17, ToInt(inv[:]).String())
+}
+
+func TestScInvertOverlap(t *testing.T) {
+	z := *rnd32Bytes(t)
+	var want [32]byte
+	InvertModL(&want, &z)
+	got := z
+	ScInvert(&got, &got)
+	assert.Equal(t, want, got)
+	got = z
+	ScInvertVartime(&got, &got)
+	assert.Equal(t, want, got)
+}
+
+// TestScInvertTiming checks that the running time of ScInvert does not
+// depend on the input, with the harness of TestGeScalarMultTiming. The
+// zero input makes ScInvertVartime stop after its first batch.
+func TestScInvertTiming(t *testing.T) {
+	skipUnlessTiming(t)
+	const samples = 4000
+	const threshold = 10
+
+	var out [32]byte
+	vartime := timingTest(func(a *[32]byte) { ScInvertVartime(&out, a) }, [32]byte{}, samples)
+	t.Logf("ScInvertVartime: t = %.2f", vartime)
+	assert.Greater(t, math.Abs(vartime), float64(threshold), "the harness fails to detect a leak")
+
+	ct := timingTest(func(a *[32]byte) { ScInvert(&out, a) }, [32]byte{}, samples)
+	t.Logf("ScInvert: t = %.2f", ct)
+	assert.Less(t, math.Abs(ct), float64(threshold))
+}
+
+func BenchmarkScInvert(b *testing.B) {
+	x := rnd32BytesBench(b)
+	var xInv [32]byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ScInvert(&xInv, x)
+	}
+}
+
+func BenchmarkScInvertVartime(b *testing.B) {
+	x := rnd32BytesBench(b)
+	var xInv [32]byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ScInvertVartime(&xInv, x)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 radix 2^52 scalar arithmetic
+
+package edwards25519
+
+import "encoding/binary"
+
+// sc52 represents an integer modulo l = 2^252 +
+// 27742317777372353535851937790883648493 with five 52-bit limbs: t
+// represents t[0]+2^52 t[1]+2^104 t[2]+2^156 t[3]+2^208 t[4]. Products are
+// reduced with Montgomery reduction by R = 2^260, so sc52Mul and sc52Square
+// return a·b/R mod l. The operations return values below l.
+//
+// This is the scalar arithmetic of 64-bit platforms, see scalar_64bit.go.
+// The algorithms follow the u64 backend of curve25519-dalek.
+type sc52 [5]uint64
+
+const maskLow52Bits uint64 = (1 << 52) - 1
+
+// scL is l.
+var scL = sc52{0x0002631a5cf5d3ed, 0x000dea2f79cd6581, 0x000000000014def9, 0, 0x0000100000000000}
+
+// scLFactor is -1/l mod 2^52.
+const scLFactor uint64 = 0x51da312547e1b
+
+// scR is R mod l, the Montgomery form of 1.
+var scR = sc52{0x000f48bd6721e6ed, 0x0003bab5ac67e45a, 0x000fffffeb35e51b, 0x000fffffffffffff, 0x00000fffffffffff}
+
+// scRR is R² mod l, which converts to the Montgomery form.
+var scRR = sc52{0x0009d265e952d13b, 0x000d63c715bea69f, 0x0005be65cb687604, 0x0003dceec73d217f, 0x000009411b7c309a}
+
+// sc52FromBytes sets s to the little-endian value of b, which may be as large
+// as 2^256 - 1 and is not reduced.
+func sc52FromBytes(s *sc52, b *[32]byte) {
+	w0 := binary.LittleEndian.Uint64(b[0:8])
+	w1 := binary.LittleEndian.Uint64(b[8:16])
+	w2 := binary.LittleEndian.Uint64(b[16:24])
+	w3 := binary.LittleEndian.Uint64(b[24:32])
+
+	s[0] = w0 & maskLow52Bits
+	s[1] = (w0>>52 | w1<<12) & maskLow52Bits
+	s[2] = (w1>>40 | w2<<24) & maskLow52Bits
+	s[3] = (w2>>28 | w3<<36) & maskLow52Bits
+	s[4] = w3 >> 16
+}
+
+// sc52ReduceWide sets s to the little-endian value of b reduced mod l.
+func sc52ReduceWide(s *sc52, b *[64]byte) {
+	var w [8]uint64
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+
+	// b is below R·l, so it can be Montgomery reduced as is, in nine 52-bit
+	// limbs with the last 96 bits in the top one, giving b/R. Multiplying by
+	// R² then gives b.
+	z := [9]uint128{
+		{w[0] & maskLow52Bits, 0},
+		{(w[0]>>52 | w[1]<<12) & maskLow52Bits, 0},
+		{(w[1]>>40 | w[2]<<24) & maskLow52Bits, 0},
+		{(w[2]>>28 | w[3]<<36) & maskLow52Bits, 0},
+		{(w[3]>>16 | w[4]<<48) & maskLow52Bits, 0},
+		{(w[4] >> 4) & maskLow52Bits, 0},
+		{(w[4]>>56 | w[5]<<8) & maskLow52Bits, 0},
+		{(w[5]>>44 | w[6]<<20) & maskLow52Bits, 0},
+		{w[6]>>32 | w[7]<<32, w[7] >> 32},
+	}
+	sc52MontgomeryReduce(s, &z)
+	sc52Mul(s, s, &scRR)
+}
+
+// sc52ToBytes sets b to the little-endian encoding of s, which must be
+// below 2^256.
+func sc52ToBytes(b *[32]byte, s *sc52) {
+	binary.LittleEndian.PutUint64(b[0:8], s[0]|s[1]<<52)
+	binary.LittleEndian.PutUint64(b[8:16], s[1]>>12|s[2]<<40)
+	binary.LittleEndian.PutUint64(b[16:24], s[2]>>24|s[3]<<28)
+	binary.LittleEndian.PutUint64(b[24:32], s[3]>>36|s[4]<<16)
+}
+
+// sc52Add sets s = a + b mod l. a and b must be below l.
+func sc52Add(s, a, b *sc52) {
+	var sum sc52
+	var carry uint64
+	for i := range sum {
+		carry = a[i] + b[i] + carry>>52
+		sum[i] = carry & maskLow52Bits
+	}
+	sc52Sub(s, &sum, &scL)
+}
+
+// sc52Sub sets s = a - b mod l. a and b must be below l, or a below 2l and
+// b equal to l.
+func sc52Sub(s, a, b *sc52) {
+	var d sc52
+	var borrow uint64
+	for i := range d {
+		borrow = a[i] - (b[i] + borrow>>63)
+		d[i] = borrow & maskLow52Bits
+	}
+
+	// add l back if the difference is negative
+	mask := -(borrow >> 63)
+	var carry uint64
+	for i := range d {
+		carry = carry>>52 + d[i] + scL[i]&mask
+		s[i] = carry & maskLow52Bits
+	}
+}
+
+// add128 returns v + x.
+func add128(v uint128, x uint64) uint128 {
+	lo := v.lo + x
+	if lo < x {
+		v.hi++
+	}
+	return uint128{lo, v.hi}
+}
+
+// shiftRightBy52 returns a >> 52. a is assumed to be below 2^116.
+func shiftRightBy52(a uint128) uint64 {
+	return (a.hi << (64 - 52)) | (a.lo >> 52)
+}
+
+// sc52MontgomeryReduce sets s = z/R mod l, where z is the 9-limb product of
+// two values whose product is below R·l.
+func sc52MontgomeryReduce(s *sc52, z *[9]uint128) {
+	// part1 picks n so that sum + n·l is divisible by 2^52
+	part1 := func(sum uint128) (carry, n uint64) {
+		n = (sum.lo * scLFactor) & maskLow52Bits
+		sum = addMul64(sum, n, scL[0])
+		return shiftRightBy52(sum), n
+	}
+	part2 := func(sum uint128) (carry, w uint64) {
+		return shiftRightBy52(sum), sum.lo & maskLow52Bits
+	}
+	l := &scL
+
+	// add n·l, making the low five limbs zero; l[3] is zero
+	carry, n0 := part1(z[0])
+	carry, n1 := part1(addMul64(add128(z[1], carry), n0, l[1]))
+	carry, n2 := part1(addMul64(addMul64(add128(z[2], carry), n0, l[2]), n1, l[1]))
+	carry, n3 := part1(addMul64(addMul64(add128(z[3], carry), n1, l[2]), n2, l[1]))
+	carry, n4 := part1(addMul64(addMul64(addMul64(add128(z[4], carry), n0, l[4]), n2, l[2]), n3, l[1]))
+
+	// divide by R by keeping the high limbs
+	carry, r0 := part2(addMul64(addMul64(addMul64(add128(z[5], carry), n1, l[4]), n3, l[2]), n4, l[1]))
+	carry, r1 := part2(addMul64(addMul64(add128(z[6], carry), n2, l[4]), n4, l[2]))
+	carry, r2 := part2(addMul64(add128(z[7], carry), n3, l[4]))
+	carry, r3 := part2(addMul64(add128(z[8], carry), n4, l[4]))
+	r4 := carry
+
+	// the result is below 2l
+	sc52Sub(s, &sc52{r0, r1, r2, r3, r4}, l)
+}
+
+// sc52Mul sets s = a·b/R mod l. Can overlap s with a or b.
+func sc52Mul(s, a, b *sc52) {
+	var z [9]uint128
+	z[0] = mul64(a[0], b[0])
+	z[1] = addMul64(mul64(a[0], b[1]), a[1], b[0])
+	z[2] = addMul64(addMul64(mul64(a[0], b[2]), a[1], b[1]), a[2], b[0])
+	z[3] = addMul64(addMul64(addMul64(mul64(a[0], b[3]), a[1], b[2]), a[2], b[1]), a[3], b[0])
+	z[4] = addMul64(addMul64(addMul64(addMul64(mul64(a[0], b[4]), a[1], b[3]), a[2], b[2]), a[3], b[1]), a[4], b[0])
+	z[5] = addMul64(addMul64(addMul64(mul64(a[1], b[4]), a[2], b[3]), a[3], b[2]), a[4], b[1])
+	z[6] = addMul64(addMul64(mul64(a[2], b[4]), a[3], b[3]), a[4], b[2])
+	z[7] = addMul64(mul64(a[3], b[4]), a[4], b[3])
+	z[8] = mul64(a[4], b[4])
+	sc52MontgomeryReduce(s, &z)
+}
+
+// sc52Square sets s = a·a/R mod l. Can overlap s with a.
+func sc52Square(s, a *sc52) {
+	a0_2 := a[0] * 2
+	a1_2 := a[1] * 2
+	a2_2 := a[2] * 2
+	a3_2 := a[3] * 2
+
+	var z [9]uint128
+	z[0] = mul64(a[0], a[0])
+	z[1] = mul64(a0_2, a[1])
+	z[2] = addMul64(mul64(a0_2, a[2]), a[1], a[1])
+	z[3] = addMul64(mul64(a0_2, a[3]), a1_2, a[2])
+	z[4] = addMul64(addMul64(mul64(a0_2, a[4]), a1_2, a[3]), a[2], a[2])
+	z[5] = addMul64(mul64(a1_2, a[4]), a2_2, a[3])
+	z[6] = addMul64(mul64(a2_2, a[4]), a[3], a[3])
+	z[7] = mul64(a3_2, a[4])
+	z[8] = mul64(a[4], a[4])
+	sc52MontgomeryReduce(s, &z)
+}
+
+// sc52ToMontgomery sets s = a·R mod l. a may be as large as 2^256 - 1.
+func sc52ToMontgomery(s, a *sc52) {
+	sc52Mul(s, a, &scRR)
+}
+
+// sc52FromMontgomery sets s = a/R mod l.
+func sc52FromMontgomery(s, a *sc52) {
+	sc52Mul(s, a, &sc52{1})
+}
+
+// sc52MulAdd sets s = a·b + c mod l, for a, b and c as large as 2^256 - 1.
+func sc52MulAdd(s, a, b, c *[32]byte) {
+	var x, y sc52
+	sc52FromBytes(&x, a)
+	sc52FromBytes(&y, b)
+	sc52Mul(&x, &x, &y)
+
+	// a·b/R + c/R, then times R²/R
+	var z [9]uint128
+	sc52FromBytes(&y, c)
+	for i := range y {
+		z[i].lo = y[i]
+	}
+	sc52MontgomeryReduce(&y, &z)
+	sc52Add(&x, &x, &y)
+	sc52Mul(&x, &x, &scRR)
+	sc52ToBytes(s, &x)
+}
+
+// sc52MulBytes sets s = a·b mod l, for a and b as large as 2^256 - 1.
+func sc52MulBytes(s, a, b *[32]byte) {
+	var x, y sc52
+	sc52FromBytes(&x, a)
+	sc52FromBytes(&y, b)
+	sc52Mul(&x, &x, &y)
+	sc52Mul(&x, &x, &scRR)
+	sc52ToBytes(s, &x)
+}
+
+// sc52Reduce sets out = s mod l.
+func sc52Reduce(out *[32]byte, s *[64]byte) {
+	var x sc52
+	sc52ReduceWide(&x, s)
+	sc52ToBytes(out, &x)
+}
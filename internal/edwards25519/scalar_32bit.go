@@ -0,0 +1,30 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 scalar arithmetic on 32-bit platforms
+
+//go:build !(amd64 || arm64 || ppc64 || ppc64le || riscv64 || s390x || mips64 || mips64le || loong64) || ed25519_fe32
+// +build !amd64,!arm64,!ppc64,!ppc64le,!riscv64,!s390x,!mips64,!mips64le,!loong64 ed25519_fe32
+
+package edwards25519
+
+// scalar is the representation InvertModL works in. On 32-bit platforms it
+// is the 32-byte encoding, multiplied with the ref10 sc32Mul.
+type scalar = [32]byte
+
+func scalarFromBytes(s *scalar, b *[32]byte) { *s = *b }
+func scalarToBytes(b *[32]byte, s *scalar)   { *b = *s }
+
+func squareModL(out, z *scalar)  { sc32Mul(out, z, z) }
+func multModL(out, z, w *scalar) { sc32Mul(out, z, w) }
+
+// The scalars are GF(l), where l = 2^252 + 27742317777372353535851937790883648493.
+// The inputs are little-endian and may be as large as 2^256 - 1, and the
+// outputs are reduced mod l.
+
+// ScMulAdd sets s = (ab + c) mod l.
+func ScMulAdd(s, a, b, c *[32]byte) { sc32MulAdd(s, a, b, c) }
+
+// ScMul sets s = ab mod l.
+func ScMul(s, a, b *[32]byte) { sc32Mul(s, a, b) }
+
+// ScReduce sets out = s mod l, for a 64-byte s.
+func ScReduce(out *[32]byte, s *[64]byte) { sc32Reduce(out, s) }
@@ -0,0 +1,40 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 scalar arithmetic on 64-bit platforms
+
+//go:build (amd64 || arm64 || ppc64 || ppc64le || riscv64 || s390x || mips64 || mips64le || loong64) && !ed25519_fe32
+// +build amd64 arm64 ppc64 ppc64le riscv64 s390x mips64 mips64le loong64
+// +build !ed25519_fe32
+
+package edwards25519
+
+// scalar is the representation InvertModL works in. On 64-bit platforms it
+// is an sc52 in Montgomery form, so that the chain of multiplications needs a
+// single Montgomery reduction per step.
+type scalar = sc52
+
+func scalarFromBytes(s *scalar, b *[32]byte) {
+	sc52FromBytes(s, b)
+	sc52ToMontgomery(s, s)
+}
+
+func scalarToBytes(b *[32]byte, s *scalar) {
+	var t sc52
+	sc52FromMontgomery(&t, s)
+	sc52ToBytes(b, &t)
+}
+
+func squareModL(out, z *scalar)  { sc52Square(out, z) }
+func multModL(out, z, w *scalar) { sc52Mul(out, z, w) }
+
+// The scalars are GF(l), where l = 2^252 + 27742317777372353535851937790883648493.
+// The inputs are little-endian and may be as large as 2^256 - 1, and the
+// outputs are reduced mod l.
+
+// ScMulAdd sets s = (ab + c) mod l.
+func ScMulAdd(s, a, b, c *[32]byte) { sc52MulAdd(s, a, b, c) }
+
+// ScMul sets s = ab mod l.
+func ScMul(s, a, b *[32]byte) { sc52MulBytes(s, a, b) }
+
+// ScReduce sets out = s mod l, for a 64-byte s.
+func ScReduce(out *[32]byte, s *[64]byte) { sc52Reduce(out, s) }
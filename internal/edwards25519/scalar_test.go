@@ -0,0 +1,205 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 scalar arithmetic unit tests
+
+package edwards25519
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var scOrder, _ = new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
+
+func sc52ToInt(s *sc52) *big.Int {
+	var b [32]byte
+	sc52ToBytes(&b, s)
+	return ToInt(b[:])
+}
+
+// interestingScalar returns random 32-byte values, with a bias towards the
+// edges: small values, values near l and multiples of it, and values near
+// 2^256.
+func interestingScalar(rng *rand.Rand) [32]byte {
+	var s [32]byte
+	switch rng.Intn(6) {
+	case 0:
+		s[0] = byte(rng.Intn(20))
+	case 1:
+		// k·l ± d for small k and d
+		v := new(big.Int).Mul(scOrder, big.NewInt(int64(rng.Intn(16))))
+		v.Add(v, big.NewInt(int64(rng.Intn(41)-20)))
+		if v.Sign() < 0 {
+			v.Neg(v)
+		}
+		b := v.Bytes()
+		for i := range b {
+			s[i] = b[len(b)-1-i]
+		}
+	case 2:
+		for i := range s {
+			s[i] = 0xff
+		}
+		s[0] -= byte(rng.Intn(20))
+	default:
+		rng.Read(s[:])
+	}
+	return s
+}
+
+func TestSc52Constants(t *testing.T) {
+	R := new(big.Int).Lsh(big.NewInt(1), 260)
+	assert.Equal(t, scOrder.String(), sc52ToInt(&scL).String())
+	assert.Equal(t, new(big.Int).Mod(R, scOrder).String(), sc52ToInt(&scR).String())
+	RR := new(big.Int).Mul(R, R)
+	assert.Equal(t, RR.Mod(RR, scOrder).String(), sc52ToInt(&scRR).String())
+
+	// l·scLFactor = -1 mod 2^52
+	f := new(big.Int).Mul(scOrder, new(big.Int).SetUint64(scLFactor))
+	f.Add(f, big.NewInt(1))
+	assert.Zero(t, f.Mod(f, big.NewInt(1<<52)).Sign())
+}
+
+// TestScalarAgainstBigInt checks ScMulAdd, ScMul and ScReduce against
+// math/big.
+func TestScalarAgainstBigInt(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		a, b, c := interestingScalar(rng), interestingScalar(rng), interestingScalar(rng)
+		ba, bb, bc := ToInt(a[:]), ToInt(b[:]), ToInt(c[:])
+
+		var s [32]byte
+		ScMulAdd(&s, &a, &b, &c)
+		want := new(big.Int).Mul(ba, bb)
+		want.Add(want, bc)
+		require.Equal(t, want.Mod(want, scOrder).String(), ToInt(s[:]).String(), "ScMulAdd %x %x %x", a, b, c)
+
+		ScMul(&s, &a, &b)
+		want = new(big.Int).Mul(ba, bb)
+		require.Equal(t, want.Mod(want, scOrder).String(), ToInt(s[:]).String(), "ScMul %x %x", a, b)
+
+		var wide [64]byte
+		copy(wide[:], a[:])
+		copy(wide[32:], b[:])
+		ScReduce(&s, &wide)
+		require.Equal(t, new(big.Int).Mod(ToInt(wide[:]), scOrder).String(), ToInt(s[:]).String(), "ScReduce %x", wide)
+	}
+}
+
+// TestScalarBackends checks that sc52 and the ref10 sc32 functions agree,
+// whichever of them backs the exported functions.
+func TestScalarBackends(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 2000; i++ {
+		a, b, c := interestingScalar(rng), interestingScalar(rng), interestingScalar(rng)
+
+		var s32, s52 [32]byte
+		sc32MulAdd(&s32, &a, &b, &c)
+		sc52MulAdd(&s52, &a, &b, &c)
+		require.Equal(t, s32, s52, "MulAdd %x %x %x", a, b, c)
+
+		sc32Mul(&s32, &a, &b)
+		sc52MulBytes(&s52, &a, &b)
+		require.Equal(t, s32, s52, "Mul %x %x", a, b)
+
+		var wide [64]byte
+		copy(wide[:], c[:])
+		copy(wide[32:], a[:])
+		sc32Reduce(&s32, &wide)
+		sc52Reduce(&s52, &wide)
+		require.Equal(t, s32, s52, "Reduce %x", wide)
+	}
+
+	// the largest 64-byte value
+	var wide [64]byte
+	for i := range wide {
+		wide[i] = 0xff
+	}
+	var s32, s52 [32]byte
+	sc32Reduce(&s32, &wide)
+	sc52Reduce(&s52, &wide)
+	assert.Equal(t, s32, s52)
+}
+
+func TestSc52Montgomery(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	Rinv := new(big.Int).ModInverse(new(big.Int).Lsh(big.NewInt(1), 260), scOrder)
+	for i := 0; i < 1000; i++ {
+		a, b := interestingScalar(rng), interestingScalar(rng)
+		var x, y, z sc52
+		sc52FromBytes(&x, &a)
+		sc52FromBytes(&y, &b)
+
+		// a·b/R
+		sc52Mul(&z, &x, &y)
+		want := new(big.Int).Mul(ToInt(a[:]), ToInt(b[:]))
+		want.Mul(want, Rinv)
+		assert.Equal(t, want.Mod(want, scOrder).String(), sc52ToInt(&z).String())
+
+		// a·a/R, overlapping the output
+		sc52Mul(&z, &x, &x)
+		sc52Square(&x, &x)
+		assert.Equal(t, z, x)
+
+		// the round trip through the Montgomery form
+		sc52FromBytes(&x, &a)
+		sc52ToMontgomery(&z, &x)
+		sc52FromMontgomery(&z, &z)
+		assert.Equal(t, new(big.Int).Mod(ToInt(a[:]), scOrder).String(), sc52ToInt(&z).String())
+	}
+}
+
+func TestInvertModLAgainstBigInt(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 200; i++ {
+		z := interestingScalar(rng)
+		bz := new(big.Int).Mod(ToInt(z[:]), scOrder)
+		if bz.Sign() == 0 {
+			continue
+		}
+		var out [32]byte
+		InvertModL(&out, &z)
+		assert.Equal(t, new(big.Int).ModInverse(bz, scOrder).String(), ToInt(out[:]).String(), "%x", z)
+	}
+}
+
+func BenchmarkScReduce(bench *testing.B) {
+	var s [32]byte
+	var wide [64]byte
+	copy(wide[:], rnd32BytesBench(bench)[:])
+	copy(wide[32:], rnd32BytesBench(bench)[:])
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		ScReduce(&s, &wide)
+	}
+}
+
+func BenchmarkSc32Mul(bench *testing.B) {
+	var s [32]byte
+	a := rnd32BytesBench(bench)
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		sc32Mul(&s, a, a)
+	}
+}
+
+func BenchmarkSc52Mul(bench *testing.B) {
+	var x sc52
+	sc52FromBytes(&x, rnd32BytesBench(bench))
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		sc52Mul(&x, &x, &x)
+	}
+}
+
+func BenchmarkSc52Square(bench *testing.B) {
+	var x sc52
+	sc52FromBytes(&x, rnd32BytesBench(bench))
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		sc52Square(&x, &x)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 constant-time variable-base scalar multiplication
+
+package edwards25519
+
+// Zero sets p to the identity.
+func (p *CachedGroupElement) Zero() {
+	FeOne(&p.yPlusX)
+	FeOne(&p.yMinusX)
+	FeOne(&p.Z)
+	FeZero(&p.T2d)
+}
+
+// CachedGroupElementCMove sets t = u if b == 1 and leaves t unchanged if
+// b == 0.
+//
+// Preconditions: b in {0,1}.
+func CachedGroupElementCMove(t, u *CachedGroupElement, b int32) {
+	FeCMove(&t.yPlusX, &u.yPlusX, b)
+	FeCMove(&t.yMinusX, &u.yMinusX, b)
+	FeCMove(&t.Z, &u.Z, b)
+	FeCMove(&t.T2d, &u.T2d, b)
+}
+
+// selectCached sets t = b*A given the table A, 2A, ..., 8A, reading every
+// entry so that the memory access pattern does not depend on b.
+//
+// Preconditions: -8 <= b <= 8.
+func selectCached(t *CachedGroupElement, table *[8]CachedGroupElement, b int32) {
+	var minusT CachedGroupElement
+	bNegative := negative(b)
+	bAbs := b - (((-bNegative) & b) << 1)
+
+	t.Zero()
+	for i := int32(0); i < 8; i++ {
+		CachedGroupElementCMove(t, &table[i], equal(bAbs, i+1))
+	}
+	FeCopy(&minusT.yPlusX, &t.yMinusX)
+	FeCopy(&minusT.yMinusX, &t.yPlusX)
+	FeCopy(&minusT.Z, &t.Z)
+	FeNeg(&minusT.T2d, &t.T2d)
+	CachedGroupElementCMove(t, &minusT, bNegative)
+}
+
+// GeScalarMult computes h = a*A, where a = a[0]+256*a[1]+...+256^31 a[31]
+// and A is a point on the curve, in time independent of a, unlike
+// GeScalarMultVartime. It uses a signed 4-bit fixed window over a table of
+// the cached multiples A, ..., 8A, like GeScalarMultBase.
+//
+// Preconditions: a[31] <= 127.
+func GeScalarMult(h *ExtendedGroupElement, a *[32]byte, A *ExtendedGroupElement) {
+	var table [8]CachedGroupElement
+	var r CompletedGroupElement
+	var u ExtendedGroupElement
+
+	A.ToCached(&table[0])
+	A.Double(&r)
+	r.ToExtended(&u)
+	u.ToCached(&table[1])
+	for i := 2; i < 8; i++ {
+		geAdd(&r, A, &table[i-1])
+		r.ToExtended(&u)
+		u.ToCached(&table[i])
+	}
+
+	var e [64]int8
+	for i, v := range a {
+		e[2*i] = int8(v & 15)
+		e[2*i+1] = int8((v >> 4) & 15)
+	}
+
+	// each e[i] is between 0 and 15 and e[63] is between 0 and 7.
+
+	carry := int8(0)
+	for i := 0; i < 63; i++ {
+		e[i] += carry
+		carry = (e[i] + 8) >> 4
+		e[i] -= carry << 4
+	}
+	e[63] += carry
+	// each e[i] is between -8 and 8.
+
+	var s ProjectiveGroupElement
+	var t CachedGroupElement
+	h.Zero()
+	for i := 63; i >= 0; i-- {
+		h.Double(&r)
+		r.ToProjective(&s)
+		s.Double(&r)
+		r.ToProjective(&s)
+		s.Double(&r)
+		r.ToProjective(&s)
+		s.Double(&r)
+		r.ToExtended(h)
+
+		selectCached(&t, &table, int32(e[i]))
+		geAdd(&r, h, &t)
+		r.ToExtended(h)
+	}
+}
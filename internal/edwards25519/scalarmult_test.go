@@ -0,0 +1,226 @@
+// Copyright 2019 Spacemesh Authors
+// edwards25519 constant-time scalar multiplication unit tests
+
+package edwards25519
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scalarMultBytes returns the encoding of a*A computed with GeScalarMult and
+// with GeScalarMultVartime.
+func scalarMultBytes(a *[32]byte, A *ExtendedGroupElement) (ct, vartime [32]byte) {
+	var h ExtendedGroupElement
+	GeScalarMult(&h, a, A)
+	h.ToBytes(&ct)
+
+	var p ProjectiveGroupElement
+	GeScalarMultVartime(&p, a, A)
+	p.ToBytes(&vartime)
+	return ct, vartime
+}
+
+func randomPoint(t *testing.T) *ExtendedGroupElement {
+	var wide [64]byte
+	copy(wide[:], rnd32Bytes(t)[:])
+	var a [32]byte
+	ScReduce(&a, &wide)
+	var A ExtendedGroupElement
+	GeScalarMultBase(&A, &a)
+	return &A
+}
+
+func TestGeScalarMult(t *testing.T) {
+	for i := 0; i < 64; i++ {
+		A := randomPoint(t)
+		a := rnd32Bytes(t)
+		a[31] &= 127
+		ct, vartime := scalarMultBytes(a, A)
+		assert.Equal(t, vartime, ct)
+	}
+}
+
+func TestGeScalarMultEdgeCases(t *testing.T) {
+	// l - 1
+	lMinus1 := [32]byte{
+		0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58, 0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+	}
+	var max [32]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	max[31] = 127
+	scalars := []*[32]byte{{}, {1}, {2}, {8}, {9}, {0x88}, &lMinus1, &max}
+
+	A := randomPoint(t)
+	var identity ExtendedGroupElement
+	identity.Zero()
+	for _, a := range scalars {
+		ct, vartime := scalarMultBytes(a, A)
+		assert.Equal(t, vartime, ct, "%x", a)
+
+		ct, vartime = scalarMultBytes(a, &identity)
+		assert.Equal(t, vartime, ct, "%x", a)
+	}
+
+	// (l-1)*A = -A
+	var h ExtendedGroupElement
+	GeScalarMult(&h, &lMinus1, A)
+	var got, want [32]byte
+	h.ToBytes(&got)
+	A.ToBytes(&want)
+	want[31] ^= 0x80
+	assert.Equal(t, want, got)
+}
+
+func TestGeScalarMultBase(t *testing.T) {
+	// B is 1·B
+	var B ExtendedGroupElement
+	GeScalarMultBase(&B, &[32]byte{1})
+	for i := 0; i < 16; i++ {
+		a := rnd32Bytes(t)
+		a[31] &= 127
+
+		var h, want ExtendedGroupElement
+		GeScalarMult(&h, a, &B)
+		GeScalarMultBase(&want, a)
+		var got, wantBytes [32]byte
+		h.ToBytes(&got)
+		want.ToBytes(&wantBytes)
+		assert.Equal(t, wantBytes, got)
+	}
+}
+
+func TestSelectCached(t *testing.T) {
+	A := randomPoint(t)
+	var table [8]CachedGroupElement
+	var multiples [9][32]byte
+	var identity ExtendedGroupElement
+	identity.Zero()
+	identity.ToBytes(&multiples[0])
+	for i := 1; i <= 8; i++ {
+		var P ExtendedGroupElement
+		GeScalarMult(&P, &[32]byte{byte(i)}, A)
+		P.ToCached(&table[i-1])
+		P.ToBytes(&multiples[i])
+	}
+
+	for b := int32(-8); b <= 8; b++ {
+		var c CachedGroupElement
+		selectCached(&c, &table, b)
+		var r CompletedGroupElement
+		var P ExtendedGroupElement
+		geAdd(&r, &identity, &c)
+		r.ToExtended(&P)
+		var got [32]byte
+		P.ToBytes(&got)
+
+		abs := b
+		if b < 0 {
+			abs = -b
+		}
+		want := multiples[abs]
+		if b < 0 {
+			want[31] ^= 0x80
+		}
+		assert.Equal(t, want, got, "b = %d", b)
+	}
+}
+
+// skipUnlessTiming skips timing tests unless ED25519_TIMING=1: they measure
+// wall-clock time and fail spuriously on loaded or shared machines.
+func skipUnlessTiming(t *testing.T) {
+	if os.Getenv("ED25519_TIMING") != "1" {
+		t.Skip("set ED25519_TIMING=1 to run timing tests")
+	}
+}
+
+// timingTest runs f on a fixed scalar and on random scalars in random order
+// and returns Welch's t statistic for the difference of the mean running
+// times of the two classes, after cropping the slowest 5% of each class to
+// remove preemptions and other outliers. As in dudect, |t| above 10 means f
+// almost certainly takes a time that depends on the scalar.
+func timingTest(f func(a *[32]byte), fixed [32]byte, samples int) float64 {
+	rng := rand.New(rand.NewSource(1))
+	scalars := make([][32]byte, samples)
+	classes := make([]int, samples)
+	for i := range scalars {
+		classes[i] = rng.Intn(2)
+		if classes[i] == 0 {
+			scalars[i] = fixed
+		} else {
+			rng.Read(scalars[i][:])
+			scalars[i][31] &= 127
+		}
+	}
+
+	// warm up
+	for i := 0; i < samples/10; i++ {
+		f(&scalars[i])
+	}
+
+	var times [2][]float64
+	for i := range scalars {
+		start := time.Now()
+		f(&scalars[i])
+		elapsed := time.Since(start)
+		times[classes[i]] = append(times[classes[i]], float64(elapsed))
+	}
+
+	var mean, variance, n [2]float64
+	for c := range times {
+		sort.Float64s(times[c])
+		x := times[c][:len(times[c])*95/100]
+		n[c] = float64(len(x))
+		for _, v := range x {
+			mean[c] += v
+		}
+		mean[c] /= n[c]
+		for _, v := range x {
+			variance[c] += (v - mean[c]) * (v - mean[c])
+		}
+		variance[c] /= n[c] - 1
+	}
+	return (mean[0] - mean[1]) / math.Sqrt(variance[0]/n[0]+variance[1]/n[1])
+}
+
+// TestGeScalarMultTiming checks that the running time of GeScalarMult does
+// not depend on the scalar, comparing the zero scalar with random ones. The
+// same harness run on GeScalarMultVartime must detect its early exit.
+func TestGeScalarMultTiming(t *testing.T) {
+	skipUnlessTiming(t)
+	const samples = 4000
+	const threshold = 10
+	A := randomPoint(t)
+
+	var p ProjectiveGroupElement
+	vartime := timingTest(func(a *[32]byte) { GeScalarMultVartime(&p, a, A) }, [32]byte{}, samples)
+	t.Logf("GeScalarMultVartime: t = %.2f", vartime)
+	assert.Greater(t, math.Abs(vartime), float64(threshold), "the harness fails to detect a leak")
+
+	var h ExtendedGroupElement
+	ct := timingTest(func(a *[32]byte) { GeScalarMult(&h, a, A) }, [32]byte{}, samples)
+	t.Logf("GeScalarMult: t = %.2f", ct)
+	assert.Less(t, math.Abs(ct), float64(threshold))
+}
+
+func BenchmarkGeScalarMult(bench *testing.B) {
+	var A ExtendedGroupElement
+	GeScalarMultBase(&A, rnd32BytesBench(bench))
+	a := rnd32BytesBench(bench)
+	a[31] &= 127
+	var h ExtendedGroupElement
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		GeScalarMult(&h, a, &A)
+	}
+}
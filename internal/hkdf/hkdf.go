@@ -0,0 +1,47 @@
+// Copyright 2019 Spacemesh Authors
+// HMAC-based key derivation
+
+// Package hkdf implements the HMAC-based key derivation function of RFC 5869.
+package hkdf
+
+import (
+	"crypto/hmac"
+	"errors"
+	"hash"
+)
+
+// Extract returns a pseudorandom key from secret and salt. An empty salt is
+// replaced by a string of zeros of the hash length.
+func Extract(h func() hash.Hash, secret, salt []byte) []byte {
+	if len(salt) == 0 {
+		salt = make([]byte, h().Size())
+	}
+	mac := hmac.New(h, salt)
+	mac.Write(secret)
+	return mac.Sum(nil)
+}
+
+// Expand returns length bytes of keying material derived from the
+// pseudorandom key prk and info. length is at most 255 times the hash length.
+func Expand(h func() hash.Hash, prk, info []byte, length int) ([]byte, error) {
+	mac := hmac.New(h, prk)
+	if length < 0 || length > 255*mac.Size() {
+		return nil, errors.New("hkdf: invalid output length")
+	}
+	out := make([]byte, 0, length+mac.Size())
+	var prev []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		mac.Reset()
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{counter})
+		prev = mac.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length], nil
+}
+
+// Key extracts and expands in one step.
+func Key(h func() hash.Hash, secret, salt, info []byte, length int) ([]byte, error) {
+	return Expand(h, Extract(h, secret, salt), info, length)
+}
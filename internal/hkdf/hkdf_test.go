@@ -0,0 +1,49 @@
+// Copyright 2019 Spacemesh Authors
+// HMAC-based key derivation unit tests
+
+package hkdf
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// RFC 5869, appendix A.1 and A.3
+func TestRFC5869(t *testing.T) {
+	ikm := unhex(t, "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+
+	prk := Extract(sha256.New, ikm, unhex(t, "000102030405060708090a0b0c"))
+	assert.Equal(t, "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5", hex.EncodeToString(prk))
+	okm, err := Expand(sha256.New, prk, unhex(t, "f0f1f2f3f4f5f6f7f8f9"), 42)
+	require.NoError(t, err)
+	assert.Equal(t, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865", hex.EncodeToString(okm))
+
+	okm, err = Key(sha256.New, ikm, nil, nil, 42)
+	require.NoError(t, err)
+	assert.Equal(t, "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8", hex.EncodeToString(okm))
+}
+
+func TestExpandLength(t *testing.T) {
+	prk := Extract(sha512.New, []byte("secret"), nil)
+	okm, err := Expand(sha512.New, prk, nil, 255*64)
+	require.NoError(t, err)
+	assert.Len(t, okm, 255*64)
+
+	short, err := Expand(sha512.New, prk, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, okm[:10], short)
+
+	_, err = Expand(sha512.New, prk, nil, 255*64+1)
+	assert.Error(t, err)
+}
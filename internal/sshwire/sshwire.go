@@ -0,0 +1,105 @@
+// Copyright 2019 Spacemesh Authors
+// SSH wire encoding primitives
+
+// Package sshwire implements the data type encodings of RFC 4251, section 5,
+// that are shared by the OpenSSH key, agent and signature formats.
+package sshwire
+
+import "encoding/binary"
+
+// KeyAlgoED25519 is the SSH public key algorithm name of Ed25519 keys.
+const KeyAlgoED25519 = "ssh-ed25519"
+
+// AppendUint32 appends v to b as a big-endian uint32.
+func AppendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// AppendString appends s to b as a length-prefixed string.
+func AppendString(b []byte, s []byte) []byte {
+	b = AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// Reader consumes wire encoded values from a buffer. The first decoding
+// failure is sticky: subsequent reads return zero values and Err reports it.
+type Reader struct {
+	buf []byte
+	bad bool
+}
+
+// NewReader returns a Reader consuming b.
+func NewReader(b []byte) *Reader {
+	return &Reader{buf: b}
+}
+
+// Byte reads a single byte.
+func (r *Reader) Byte() byte {
+	if r.bad || len(r.buf) < 1 {
+		r.bad = true
+		return 0
+	}
+	v := r.buf[0]
+	r.buf = r.buf[1:]
+	return v
+}
+
+// Uint32 reads a big-endian uint32.
+func (r *Reader) Uint32() uint32 {
+	if r.bad || len(r.buf) < 4 {
+		r.bad = true
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v
+}
+
+// String reads a length-prefixed string. The returned slice aliases the
+// underlying buffer.
+func (r *Reader) String() []byte {
+	n := r.Uint32()
+	if r.bad || uint64(len(r.buf)) < uint64(n) {
+		r.bad = true
+		return nil
+	}
+	v := r.buf[:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+// Rest returns the unread part of the buffer.
+func (r *Reader) Rest() []byte {
+	return r.buf
+}
+
+// Empty reports whether all input has been consumed without errors.
+func (r *Reader) Empty() bool {
+	return !r.bad && len(r.buf) == 0
+}
+
+// Ok reports whether all reads so far succeeded.
+func (r *Reader) Ok() bool {
+	return !r.bad
+}
+
+// MarshalED25519PublicKey returns the wire encoding of an Ed25519 public key:
+// the algorithm name followed by the 32-byte key, each as a string.
+func MarshalED25519PublicKey(pub []byte) []byte {
+	b := AppendString(nil, []byte(KeyAlgoED25519))
+	return AppendString(b, pub)
+}
+
+// ParseED25519PublicKey parses the wire encoding produced by
+// MarshalED25519PublicKey. It reports false if blob is not an Ed25519 key.
+func ParseED25519PublicKey(blob []byte) ([]byte, bool) {
+	r := NewReader(blob)
+	algo := r.String()
+	pub := r.String()
+	if !r.Empty() || string(algo) != KeyAlgoED25519 || len(pub) != 32 {
+		return nil, false
+	}
+	return pub, true
+}
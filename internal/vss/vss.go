@@ -0,0 +1,195 @@
+// Copyright 2019 Spacemesh Authors
+// Feldman verifiable secret sharing
+
+// Package vss implements Shamir secret sharing of scalars mod l with Feldman
+// commitments to the polynomial coefficients, for threshold signing and
+// distributed key generation. Participants are identified by nonzero
+// x-coordinates.
+package vss
+
+import (
+	"errors"
+	"io"
+
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+)
+
+// ErrInvalidCommitment is returned for commitments that do not encode curve
+// points.
+var ErrInvalidCommitment = errors.New("vss: invalid commitment")
+
+// Polynomial is a secret polynomial, its coefficients mod l from the
+// constant term up. The constant term is the shared secret.
+type Polynomial [][32]byte
+
+// Commitment holds the encoded points c·B of the coefficients c of a
+// Polynomial.
+type Commitment [][32]byte
+
+// RandomScalar returns a uniformly random scalar mod l.
+func RandomScalar(rand io.Reader) ([32]byte, error) {
+	var wide [64]byte
+	var s [32]byte
+	if _, err := io.ReadFull(rand, wide[:]); err != nil {
+		return s, err
+	}
+	edwards25519.ScReduce(&s, &wide)
+	return s, nil
+}
+
+// NewPolynomial returns a random polynomial of degree threshold-1 with
+// constant term secret, which is random if nil.
+func NewPolynomial(rand io.Reader, secret *[32]byte, threshold int) (Polynomial, error) {
+	if threshold < 1 {
+		return nil, errors.New("vss: threshold must be positive")
+	}
+	p := make(Polynomial, threshold)
+	for i := range p {
+		if i == 0 && secret != nil {
+			var wide [64]byte
+			copy(wide[:], secret[:])
+			edwards25519.ScReduce(&p[0], &wide)
+			continue
+		}
+		var err error
+		if p[i], err = RandomScalar(rand); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Scalar returns x as a scalar.
+func Scalar(x uint16) [32]byte {
+	return [32]byte{byte(x), byte(x >> 8)}
+}
+
+// Evaluate returns p(x) mod l.
+func (p Polynomial) Evaluate(x uint16) [32]byte {
+	// Horner's rule
+	xs := Scalar(x)
+	var y [32]byte
+	for i := len(p) - 1; i >= 0; i-- {
+		edwards25519.ScMulAdd(&y, &y, &xs, &p[i])
+	}
+	return y
+}
+
+// Commit returns the commitment to p.
+func (p Polynomial) Commit() Commitment {
+	c := make(Commitment, len(p))
+	var P edwards25519.ExtendedGroupElement
+	for i := range p {
+		edwards25519.GeScalarMultBase(&P, &p[i])
+		P.ToBytes(&c[i])
+	}
+	return c
+}
+
+// Evaluate returns the encoding of p(x)·B, the public counterpart of the
+// share of x, from the commitment to p.
+func (c Commitment) Evaluate(x uint16) ([32]byte, error) {
+	var out [32]byte
+	if len(c) == 0 {
+		return out, ErrInvalidCommitment
+	}
+	// Σ x^i·C_i
+	points := make([]edwards25519.ExtendedGroupElement, len(c))
+	powers := make([][32]byte, len(c))
+	xs := Scalar(x)
+	var zero [32]byte
+	for i := range c {
+		if !points[i].FromBytes(&c[i]) {
+			return out, ErrInvalidCommitment
+		}
+		if i == 0 {
+			powers[i] = [32]byte{1}
+		} else {
+			edwards25519.ScMulAdd(&powers[i], &powers[i-1], &xs, &zero)
+		}
+	}
+	var sum edwards25519.ExtendedGroupElement
+	edwards25519.GeMultiScalarMultVartime(&sum, powers, points)
+	sum.ToBytes(&out)
+	return out, nil
+}
+
+// VerifyShare reports whether share is the evaluation at x of the polynomial
+// committed to by c.
+func (c Commitment) VerifyShare(x uint16, share *[32]byte) bool {
+	if !edwards25519.ScMinimal(share) {
+		return false
+	}
+	want, err := c.Evaluate(x)
+	if err != nil {
+		return false
+	}
+	var P edwards25519.ExtendedGroupElement
+	var got [32]byte
+	edwards25519.GeScalarMultBase(&P, share)
+	P.ToBytes(&got)
+	return got == want
+}
+
+// Sum returns the commitment to the sum of the polynomials committed to by
+// cs, which must have the same degree.
+func Sum(cs []Commitment) (Commitment, error) {
+	if len(cs) == 0 {
+		return nil, ErrInvalidCommitment
+	}
+	sums := make([]edwards25519.ExtendedGroupElement, len(cs[0]))
+	for i := range sums {
+		sums[i].Zero()
+	}
+	var P edwards25519.ExtendedGroupElement
+	for _, c := range cs {
+		if len(c) != len(sums) {
+			return nil, ErrInvalidCommitment
+		}
+		for i := range c {
+			if !P.FromBytes(&c[i]) {
+				return nil, ErrInvalidCommitment
+			}
+			edwards25519.GeAdd(&sums[i], &sums[i], &P)
+		}
+	}
+	out := make(Commitment, len(sums))
+	for i := range sums {
+		sums[i].ToBytes(&out[i])
+	}
+	return out, nil
+}
+
+// Lagrange returns the coefficient of the share of x in the interpolation
+// of the secret, p(0), from the shares of xs, which must include x and be
+// distinct and nonzero.
+func Lagrange(x uint16, xs []uint16) ([32]byte, error) {
+	num, den := [32]byte{1}, [32]byte{1}
+	xi := Scalar(x)
+	var zero, d [32]byte
+	found := false
+	for _, xj := range xs {
+		if xj == 0 {
+			return zero, errors.New("vss: zero identifier")
+		}
+		if xj == x {
+			if found {
+				return zero, errors.New("vss: duplicate identifier")
+			}
+			found = true
+			continue
+		}
+		// num·xj / den·(xj - x)
+		s := Scalar(xj)
+		edwards25519.ScMulAdd(&num, &num, &s, &zero)
+		edwards25519.ScSub(&d, &s, &xi)
+		edwards25519.ScMulAdd(&den, &den, &d, &zero)
+	}
+	if !found {
+		return zero, errors.New("vss: identifier not in list")
+	}
+	// the identifiers are public
+	edwards25519.ScInvertVartime(&den, &den)
+	edwards25519.ScMulAdd(&num, &num, &den, &zero)
+	return num, nil
+}
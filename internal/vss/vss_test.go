@@ -0,0 +1,93 @@
+// Copyright 2019 Spacemesh Authors
+// Feldman verifiable secret sharing unit tests
+
+package vss
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareAndInterpolate(t *testing.T) {
+	secret, err := RandomScalar(rand.Reader)
+	require.NoError(t, err)
+	p, err := NewPolynomial(rand.Reader, &secret, 3)
+	require.NoError(t, err)
+	c := p.Commit()
+
+	shares := make(map[uint16][32]byte)
+	for x := uint16(1); x <= 5; x++ {
+		share := p.Evaluate(x)
+		assert.True(t, c.VerifyShare(x, &share))
+		shares[x] = share
+	}
+
+	// any 3 shares give the secret
+	for _, xs := range [][]uint16{{1, 2, 3}, {5, 1, 4}, {2, 3, 4, 5}} {
+		var sum [32]byte
+		for _, x := range xs {
+			lambda, err := Lagrange(x, xs)
+			require.NoError(t, err)
+			share := shares[x]
+			edwards25519.ScMulAdd(&sum, &lambda, &share, &sum)
+		}
+		assert.Equal(t, secret, sum, "%v", xs)
+	}
+
+	// 2 shares do not
+	var sum [32]byte
+	for _, x := range []uint16{1, 2} {
+		lambda, err := Lagrange(x, []uint16{1, 2})
+		require.NoError(t, err)
+		share := shares[x]
+		edwards25519.ScMulAdd(&sum, &lambda, &share, &sum)
+	}
+	assert.NotEqual(t, secret, sum)
+
+	_, err = Lagrange(1, []uint16{2, 3})
+	assert.Error(t, err)
+	_, err = Lagrange(1, []uint16{1, 1, 3})
+	assert.Error(t, err)
+	_, err = Lagrange(1, []uint16{1, 0})
+	assert.Error(t, err)
+}
+
+func TestVerifyShareRejects(t *testing.T) {
+	p, err := NewPolynomial(rand.Reader, nil, 2)
+	require.NoError(t, err)
+	c := p.Commit()
+	share := p.Evaluate(1)
+	assert.False(t, c.VerifyShare(2, &share))
+	share[0] ^= 1
+	assert.False(t, c.VerifyShare(1, &share))
+
+	bad := append(Commitment{}, c...)
+	bad[1] = [32]byte{2}
+	share = p.Evaluate(1)
+	assert.False(t, bad.VerifyShare(1, &share))
+}
+
+func TestSum(t *testing.T) {
+	p1, err := NewPolynomial(rand.Reader, nil, 3)
+	require.NoError(t, err)
+	p2, err := NewPolynomial(rand.Reader, nil, 3)
+	require.NoError(t, err)
+	c, err := Sum([]Commitment{p1.Commit(), p2.Commit()})
+	require.NoError(t, err)
+
+	for x := uint16(1); x <= 4; x++ {
+		var share [32]byte
+		s1, s2 := p1.Evaluate(x), p2.Evaluate(x)
+		edwards25519.ScAdd(&share, &s1, &s2)
+		assert.True(t, c.VerifyShare(x, &share))
+	}
+
+	short, err := NewPolynomial(rand.Reader, nil, 2)
+	require.NoError(t, err)
+	_, err = Sum([]Commitment{p1.Commit(), short.Commit()})
+	assert.Equal(t, ErrInvalidCommitment, err)
+}
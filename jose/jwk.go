@@ -0,0 +1,120 @@
+// Copyright 2019 Spacemesh Authors
+// JSON Web Keys for Ed25519
+
+package jose
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+const (
+	// KeyTypeOKP is the JWK key type of octet key pairs (RFC 8037).
+	KeyTypeOKP = "OKP"
+	// CurveEd25519 is the JWK curve name of Ed25519 keys (RFC 8037).
+	CurveEd25519 = "Ed25519"
+)
+
+// JWK is an Ed25519 JSON Web Key as specified by RFC 8037. D is only set for
+// private keys.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	D   string `json:"d,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+// NewPublicJWK returns the JWK of publicKey. If kid is empty the RFC 7638
+// thumbprint of the key is used.
+func NewPublicJWK(publicKey ed25519.PublicKey, kid string) *JWK {
+	if kid == "" {
+		kid = Thumbprint(publicKey)
+	}
+	return &JWK{
+		Kty: KeyTypeOKP,
+		Crv: CurveEd25519,
+		X:   b64.EncodeToString(publicKey),
+		Kid: kid,
+	}
+}
+
+// NewPrivateJWK returns the JWK of privateKey. Only the seed is stored in
+// the "d" member, as RFC 8037 requires.
+func NewPrivateJWK(privateKey ed25519.PrivateKey, kid string) *JWK {
+	jwk := NewPublicJWK(privateKey.Public().(ed25519.PublicKey), kid)
+	jwk.D = b64.EncodeToString(privateKey.Seed())
+	return jwk
+}
+
+// ParseJWK parses a JSON encoded Ed25519 JWK and validates its members.
+func ParseJWK(data []byte) (*JWK, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+	if _, err := jwk.PublicKey(); err != nil {
+		return nil, err
+	}
+	if jwk.D != "" {
+		if _, err := jwk.PrivateKey(); err != nil {
+			return nil, err
+		}
+	}
+	return &jwk, nil
+}
+
+// PublicKey returns the public key held by the JWK.
+func (j *JWK) PublicKey() (ed25519.PublicKey, error) {
+	if j.Kty != KeyTypeOKP || j.Crv != CurveEd25519 {
+		return nil, ErrUnsupportedKey
+	}
+	x, err := b64.DecodeString(j.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("jose: invalid JWK \"x\" member")
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// PrivateKey returns the private key held by the JWK. It fails if the JWK
+// is a public key or if "x" does not match "d".
+func (j *JWK) PrivateKey() (ed25519.PrivateKey, error) {
+	publicKey, err := j.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if j.D == "" {
+		return nil, errors.New("jose: JWK has no private key")
+	}
+	d, err := b64.DecodeString(j.D)
+	if err != nil || len(d) != ed25519.SeedSize {
+		return nil, errors.New("jose: invalid JWK \"d\" member")
+	}
+	privateKey := ed25519.NewKeyFromSeed(d)
+	if !publicKey.Equal(privateKey.Public()) {
+		return nil, errors.New("jose: JWK \"x\" does not match \"d\"")
+	}
+	return privateKey, nil
+}
+
+// Public returns a copy of the JWK without the private key.
+func (j *JWK) Public() *JWK {
+	pub := *j
+	pub.D = ""
+	return &pub
+}
+
+// Thumbprint returns the RFC 7638 JWK thumbprint of publicKey, using SHA-256.
+func Thumbprint(publicKey ed25519.PublicKey) string {
+	// the required members in lexicographic order, without whitespace
+	h := sha256.Sum256([]byte(`{"crv":"` + CurveEd25519 + `","kty":"` + KeyTypeOKP + `","x":"` + b64.EncodeToString(publicKey) + `"}`))
+	return b64.EncodeToString(h[:])
+}
+
+var b64 = base64.RawURLEncoding.Strict()
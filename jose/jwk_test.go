@@ -0,0 +1,70 @@
+// Copyright 2019 Spacemesh Authors
+// JSON Web Keys for Ed25519 unit tests
+
+package jose
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+// RFC 8037, appendix A.1 and A.2
+const rfc8037PrivateJWK = `{"kty":"OKP","crv":"Ed25519",
+   "d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+   "x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+
+func TestParseJWK(t *testing.T) {
+	jwk, err := ParseJWK([]byte(rfc8037PrivateJWK))
+	require.NoError(t, err)
+
+	privateKey, err := jwk.PrivateKey()
+	require.NoError(t, err)
+	publicKey, err := jwk.PublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, privateKey.Public())
+
+	_, err = jwk.Public().PrivateKey()
+	assert.Error(t, err)
+}
+
+// RFC 8037, appendix A.3
+func TestThumbprint(t *testing.T) {
+	jwk, err := ParseJWK([]byte(rfc8037PrivateJWK))
+	require.NoError(t, err)
+	publicKey, err := jwk.PublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", Thumbprint(publicKey))
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	privateKey := ed25519.NewDerivedKeyFromSeed(seed, 1, []byte("Spacemesh rocks"))
+
+	data, err := json.Marshal(NewPrivateJWK(privateKey, ""))
+	require.NoError(t, err)
+	jwk, err := ParseJWK(data)
+	require.NoError(t, err)
+	parsed, err := jwk.PrivateKey()
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, parsed)
+	assert.Equal(t, Thumbprint(privateKey.Public().(ed25519.PublicKey)), jwk.Kid)
+}
+
+func TestParseJWKErrors(t *testing.T) {
+	for _, data := range []string{
+		`{"kty":"EC","crv":"P-256","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+		`{"kty":"OKP","crv":"X25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHUR"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","d":"AAAA"}`,
+		// d does not match x
+		`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","d":"mWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A"}`,
+	} {
+		_, err := ParseJWK([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
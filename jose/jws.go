@@ -0,0 +1,335 @@
+// Copyright 2019 Spacemesh Authors
+// JSON Web Signatures with Ed25519
+
+// Package jose implements Ed25519 JSON Web Keys (RFC 8037) and JSON Web
+// Signatures (RFC 7515) in the compact and JSON serializations.
+//
+// Besides the standard "EdDSA" algorithm, which signs with ed25519.Sign, the
+// package supports AlgEdDSA2, a private algorithm signing with ed25519.Sign2.
+// Its headers may omit both "kid" and "jwk": the verifier recovers the
+// signer's key with ed25519.ExtractPublicKey and accepts it only if its
+// KeyResolver knows the key.
+package jose
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+const (
+	// AlgEdDSA is the JWS algorithm of standard Ed25519 signatures (RFC 8037).
+	AlgEdDSA = "EdDSA"
+	// AlgEdDSA2 is a private JWS algorithm for signatures made by
+	// ed25519.Sign2, from which the verifier extracts the signer's key.
+	AlgEdDSA2 = "X-Spacemesh-EdDSA2"
+)
+
+var (
+	// ErrMalformed is returned when a JWS cannot be parsed.
+	ErrMalformed = errors.New("jose: malformed JWS")
+	// ErrUnsupportedAlgorithm is returned for "alg" values other than
+	// AlgEdDSA and AlgEdDSA2.
+	ErrUnsupportedAlgorithm = errors.New("jose: unsupported algorithm")
+	// ErrUnsupportedKey is returned for JWKs that are not Ed25519 keys.
+	ErrUnsupportedKey = errors.New("jose: unsupported key type")
+	// ErrUnsupportedCritical is returned when a header lists "crit" extensions.
+	ErrUnsupportedCritical = errors.New("jose: unsupported critical header parameter")
+	// ErrUnknownKey is returned when the KeyResolver does not know the
+	// signer's key.
+	ErrUnknownKey = errors.New("jose: unknown key")
+	// ErrInvalidSignature is returned when a signature does not verify.
+	ErrInvalidSignature = errors.New("jose: invalid signature")
+)
+
+// Header holds the JOSE header parameters understood by this package.
+type Header struct {
+	Alg  string   `json:"alg,omitempty"`
+	Kid  string   `json:"kid,omitempty"`
+	JWK  *JWK     `json:"jwk,omitempty"`
+	Typ  string   `json:"typ,omitempty"`
+	Cty  string   `json:"cty,omitempty"`
+	Crit []string `json:"crit,omitempty"`
+}
+
+// Signer holds a private key together with the header of the signatures it
+// makes. Header.Alg selects between ed25519.Sign (AlgEdDSA, the default)
+// and ed25519.Sign2 (AlgEdDSA2).
+type Signer struct {
+	Key    ed25519.PrivateKey
+	Header Header
+}
+
+// Signature is a single signature of a JWS.
+type Signature struct {
+	// Protected is the integrity protected header.
+	Protected Header
+	// Unprotected is the per-signature unprotected header of the JSON
+	// serialization. It may only carry "kid" and "jwk".
+	Unprotected *Header
+
+	rawProtected string
+	Signature    []byte
+}
+
+// JWS is a signed payload with one or more signatures.
+type JWS struct {
+	Payload    []byte
+	Signatures []Signature
+}
+
+// Sign signs payload with each signer.
+func Sign(payload []byte, signers ...Signer) (*JWS, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("jose: no signers")
+	}
+	jws := &JWS{Payload: payload}
+	encodedPayload := b64.EncodeToString(payload)
+	for _, s := range signers {
+		header := s.Header
+		if header.Alg == "" {
+			header.Alg = AlgEdDSA
+		}
+		protected, err := json.Marshal(header)
+		if err != nil {
+			return nil, err
+		}
+		rawProtected := b64.EncodeToString(protected)
+		input := []byte(rawProtected + "." + encodedPayload)
+
+		var sig []byte
+		switch header.Alg {
+		case AlgEdDSA:
+			sig = ed25519.Sign(s.Key, input)
+		case AlgEdDSA2:
+			sig = ed25519.Sign2(s.Key, input)
+		default:
+			return nil, ErrUnsupportedAlgorithm
+		}
+		jws.Signatures = append(jws.Signatures, Signature{
+			Protected:    header,
+			rawProtected: rawProtected,
+			Signature:    sig,
+		})
+	}
+	return jws, nil
+}
+
+// SignCompact signs payload and returns the JWS in compact serialization.
+func SignCompact(payload []byte, signer Signer) (string, error) {
+	jws, err := Sign(payload, signer)
+	if err != nil {
+		return "", err
+	}
+	return jws.Compact()
+}
+
+// Compact returns the compact serialization of a JWS with a single signature
+// and no unprotected header.
+func (j *JWS) Compact() (string, error) {
+	if len(j.Signatures) != 1 || j.Signatures[0].Unprotected != nil {
+		return "", errors.New("jose: compact serialization requires a single signature with no unprotected header")
+	}
+	sig := &j.Signatures[0]
+	return sig.rawProtected + "." + b64.EncodeToString(j.Payload) + "." + b64.EncodeToString(sig.Signature), nil
+}
+
+type jsonSignature struct {
+	Protected string  `json:"protected,omitempty"`
+	Header    *Header `json:"header,omitempty"`
+	Signature string  `json:"signature"`
+}
+
+type jsonJWS struct {
+	Payload    string          `json:"payload"`
+	Signatures []jsonSignature `json:"signatures,omitempty"`
+
+	// flattened serialization
+	Protected string  `json:"protected,omitempty"`
+	Header    *Header `json:"header,omitempty"`
+	Signature string  `json:"signature,omitempty"`
+}
+
+// MarshalJSON returns the JSON serialization of the JWS: the flattened form
+// for a single signature and the general form otherwise.
+func (j *JWS) MarshalJSON() ([]byte, error) {
+	out := jsonJWS{Payload: b64.EncodeToString(j.Payload)}
+	for i := range j.Signatures {
+		s := &j.Signatures[i]
+		out.Signatures = append(out.Signatures, jsonSignature{
+			Protected: s.rawProtected,
+			Header:    s.Unprotected,
+			Signature: b64.EncodeToString(s.Signature),
+		})
+	}
+	if len(out.Signatures) == 1 {
+		out.Protected = out.Signatures[0].Protected
+		out.Header = out.Signatures[0].Header
+		out.Signature = out.Signatures[0].Signature
+		out.Signatures = nil
+	}
+	return json.Marshal(out)
+}
+
+// ParseCompact parses a JWS in compact serialization.
+func ParseCompact(s string) (*JWS, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	sig, err := parseSignature(parts[0], nil, parts[2])
+	if err != nil {
+		return nil, err
+	}
+	return &JWS{Payload: payload, Signatures: []Signature{*sig}}, nil
+}
+
+// ParseJSON parses a JWS in general or flattened JSON serialization.
+func ParseJSON(data []byte) (*JWS, error) {
+	var in jsonJWS
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, ErrMalformed
+	}
+	payload, err := b64.DecodeString(in.Payload)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	flattened := in.Signature != "" || in.Protected != "" || in.Header != nil
+	if flattened == (len(in.Signatures) > 0) {
+		return nil, ErrMalformed
+	}
+	if flattened {
+		in.Signatures = []jsonSignature{{Protected: in.Protected, Header: in.Header, Signature: in.Signature}}
+	}
+
+	jws := &JWS{Payload: payload}
+	for _, s := range in.Signatures {
+		sig, err := parseSignature(s.Protected, s.Header, s.Signature)
+		if err != nil {
+			return nil, err
+		}
+		jws.Signatures = append(jws.Signatures, *sig)
+	}
+	return jws, nil
+}
+
+func parseSignature(rawProtected string, unprotected *Header, rawSig string) (*Signature, error) {
+	protected, err := b64.DecodeString(rawProtected)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	sig, err := b64.DecodeString(rawSig)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, ErrMalformed
+	}
+	s := &Signature{rawProtected: rawProtected, Unprotected: unprotected, Signature: sig}
+	if err := json.Unmarshal(protected, &s.Protected); err != nil {
+		return nil, ErrMalformed
+	}
+	if unprotected != nil && (unprotected.Alg != "" || unprotected.Typ != "" || unprotected.Cty != "" || len(unprotected.Crit) > 0) {
+		return nil, errors.New("jose: unprotected header may only carry \"kid\" and \"jwk\"")
+	}
+	return s, nil
+}
+
+// header returns the union of the protected and unprotected headers.
+func (s *Signature) header() (*Header, error) {
+	h := s.Protected
+	if u := s.Unprotected; u != nil {
+		if (u.Kid != "" && h.Kid != "") || (u.JWK != nil && h.JWK != nil) {
+			return nil, errors.New("jose: duplicate header parameter")
+		}
+		if u.Kid != "" {
+			h.Kid = u.Kid
+		}
+		if u.JWK != nil {
+			h.JWK = u.JWK
+		}
+	}
+	return &h, nil
+}
+
+// Verify verifies every signature of the JWS and returns the signers' keys,
+// in signature order. AlgEdDSA signatures are checked against the key that
+// resolver returns for their header. For AlgEdDSA2 signatures the key is
+// extracted from the signature and must be known to resolver; if the header
+// names a key as well, it must be the extracted one.
+func (j *JWS) Verify(resolver KeyResolver) ([]ed25519.PublicKey, error) {
+	if len(j.Signatures) == 0 {
+		return nil, ErrMalformed
+	}
+	encodedPayload := b64.EncodeToString(j.Payload)
+	keys := make([]ed25519.PublicKey, 0, len(j.Signatures))
+	for i := range j.Signatures {
+		s := &j.Signatures[i]
+		h, err := s.header()
+		if err != nil {
+			return nil, err
+		}
+		if len(h.Crit) > 0 {
+			return nil, ErrUnsupportedCritical
+		}
+		input := []byte(s.rawProtected + "." + encodedPayload)
+
+		var key ed25519.PublicKey
+		switch h.Alg {
+		case AlgEdDSA:
+			key, err = resolver.ResolveKey(h)
+			if err != nil {
+				return nil, err
+			}
+			if len(key) != ed25519.PublicKeySize {
+				return nil, ErrUnknownKey
+			}
+			if !ed25519.Verify(key, input, s.Signature) {
+				return nil, ErrInvalidSignature
+			}
+		case AlgEdDSA2:
+			key, err = ed25519.ExtractPublicKey(input, s.Signature)
+			if err != nil {
+				return nil, ErrInvalidSignature
+			}
+			if !resolver.KnownKey(key) {
+				return nil, ErrUnknownKey
+			}
+			if h.Kid != "" || h.JWK != nil {
+				named, err := resolver.ResolveKey(h)
+				if err != nil {
+					return nil, err
+				}
+				if len(named) != ed25519.PublicKeySize {
+					return nil, ErrUnknownKey
+				}
+				if !named.Equal(key) {
+					return nil, ErrInvalidSignature
+				}
+			}
+			if !ed25519.Verify2(key, input, s.Signature) {
+				return nil, ErrInvalidSignature
+			}
+		default:
+			return nil, ErrUnsupportedAlgorithm
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// VerifyCompact parses and verifies a compact JWS and returns its payload.
+func VerifyCompact(s string, resolver KeyResolver) ([]byte, error) {
+	jws, err := ParseCompact(s)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := jws.Verify(resolver); err != nil {
+		return nil, err
+	}
+	return jws.Payload, nil
+}
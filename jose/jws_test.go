@@ -0,0 +1,198 @@
+// Copyright 2019 Spacemesh Authors
+// JSON Web Signatures with Ed25519 unit tests
+
+package jose
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+func testKey(t *testing.T, index uint64) ed25519.PrivateKey {
+	seed := make([]byte, ed25519.SeedSize)
+	return ed25519.NewDerivedKeyFromSeed(seed, index, []byte("jose"))
+}
+
+func publicKey(privateKey ed25519.PrivateKey) ed25519.PublicKey {
+	return privateKey.Public().(ed25519.PublicKey)
+}
+
+// RFC 8037, appendix A.4 and A.5
+func TestRFC8037Signature(t *testing.T) {
+	const expected = "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+
+	jwk, err := ParseJWK([]byte(rfc8037PrivateJWK))
+	require.NoError(t, err)
+	privateKey, err := jwk.PrivateKey()
+	require.NoError(t, err)
+
+	jws, err := SignCompact([]byte("Example of Ed25519 signing"), Signer{Key: privateKey})
+	require.NoError(t, err)
+	assert.Equal(t, expected, jws)
+
+	payload, err := VerifyCompact(expected, NewAllowlist(publicKey(privateKey)))
+	require.NoError(t, err)
+	assert.Equal(t, "Example of Ed25519 signing", string(payload))
+}
+
+func TestVerifyEdDSA(t *testing.T) {
+	alice, bob := testKey(t, 1), testKey(t, 2)
+	allowlist := NewAllowlist(publicKey(alice), publicKey(bob))
+
+	jws, err := SignCompact([]byte("payload"), Signer{Key: alice, Header: Header{Kid: Thumbprint(publicKey(alice))}})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, allowlist)
+	assert.NoError(t, err)
+
+	// the kid names the wrong key
+	jws, err = SignCompact([]byte("payload"), Signer{Key: alice, Header: Header{Kid: Thumbprint(publicKey(bob))}})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, allowlist)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// an embedded but unknown key
+	mallory := testKey(t, 3)
+	jws, err = SignCompact([]byte("payload"), Signer{Key: mallory, Header: Header{JWK: NewPublicJWK(publicKey(mallory), "")}})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, allowlist)
+	assert.Equal(t, ErrUnknownKey, err)
+
+	// a header naming no key with more than one candidate
+	jws, err = SignCompact([]byte("payload"), Signer{Key: alice})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, allowlist)
+	assert.Equal(t, ErrUnknownKey, err)
+}
+
+func TestAllowlistAdd(t *testing.T) {
+	alice := publicKey(testKey(t, 1))
+	allowlist := NewAllowlist()
+	require.NoError(t, allowlist.Add("alice", alice))
+	assert.True(t, allowlist.KnownKey(alice))
+
+	// keys of the wrong length would make verification panic
+	assert.Error(t, allowlist.Add("short", alice[:31]))
+	assert.Error(t, allowlist.Add("long", append(alice, 0)))
+	_, err := allowlist.ResolveKey(&Header{Kid: "short"})
+	assert.Equal(t, ErrUnknownKey, err)
+	assert.Panics(t, func() { NewAllowlist(alice[:31]) })
+}
+
+// badResolver resolves every header to a key of the wrong length.
+type badResolver struct{ known ed25519.PublicKey }
+
+func (r badResolver) ResolveKey(*Header) (ed25519.PublicKey, error) {
+	return r.known[:31], nil
+}
+
+func (r badResolver) KnownKey(publicKey ed25519.PublicKey) bool {
+	return r.known.Equal(publicKey)
+}
+
+// TestResolvedKeyLength checks that a resolver returning a key of the wrong
+// length fails verification rather than making it panic.
+func TestResolvedKeyLength(t *testing.T) {
+	alice := testKey(t, 1)
+	resolver := badResolver{known: publicKey(alice)}
+
+	jws, err := SignCompact([]byte("payload"), Signer{Key: alice})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, resolver)
+	assert.Equal(t, ErrUnknownKey, err)
+
+	jws, err = SignCompact([]byte("payload"), Signer{Key: alice, Header: Header{Alg: AlgEdDSA2, Kid: "alice"}})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, resolver)
+	assert.Equal(t, ErrUnknownKey, err)
+}
+
+func TestVerifyEdDSA2(t *testing.T) {
+	alice, mallory := testKey(t, 1), testKey(t, 3)
+	allowlist := NewAllowlist(publicKey(alice), publicKey(testKey(t, 2)))
+
+	// neither kid nor jwk: the key is extracted from the signature
+	jws, err := SignCompact([]byte("payload"), Signer{Key: alice, Header: Header{Alg: AlgEdDSA2}})
+	require.NoError(t, err)
+	parsed, err := ParseCompact(jws)
+	require.NoError(t, err)
+	keys, err := parsed.Verify(allowlist)
+	require.NoError(t, err)
+	assert.Equal(t, []ed25519.PublicKey{publicKey(alice)}, keys)
+
+	jws, err = SignCompact([]byte("payload"), Signer{Key: mallory, Header: Header{Alg: AlgEdDSA2}})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, allowlist)
+	assert.Equal(t, ErrUnknownKey, err)
+
+	// a tampered payload extracts some unknown key
+	parts := strings.Split(jws, ".")
+	parts[1] = b64.EncodeToString([]byte("tampered"))
+	_, err = VerifyCompact(strings.Join(parts, "."), NewAllowlist(publicKey(mallory)))
+	assert.Equal(t, ErrUnknownKey, err)
+
+	// a kid must agree with the extracted key
+	jws, err = SignCompact([]byte("payload"), Signer{Key: alice, Header: Header{Alg: AlgEdDSA2, Kid: Thumbprint(publicKey(testKey(t, 2)))}})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, allowlist)
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestJSONSerialization(t *testing.T) {
+	alice, bob := testKey(t, 1), testKey(t, 2)
+	allowlist := NewAllowlist(publicKey(alice), publicKey(bob))
+
+	jws, err := Sign([]byte("payload"),
+		Signer{Key: alice, Header: Header{Kid: Thumbprint(publicKey(alice))}},
+		Signer{Key: bob, Header: Header{Alg: AlgEdDSA2}})
+	require.NoError(t, err)
+	data, err := json.Marshal(jws)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"signatures":[`)
+
+	parsed, err := ParseJSON(data)
+	require.NoError(t, err)
+	keys, err := parsed.Verify(allowlist)
+	require.NoError(t, err)
+	assert.Equal(t, []ed25519.PublicKey{publicKey(alice), publicKey(bob)}, keys)
+	_, err = parsed.Compact()
+	assert.Error(t, err)
+
+	// flattened, with the key ID in the unprotected header
+	jws, err = Sign([]byte("payload"), Signer{Key: alice})
+	require.NoError(t, err)
+	jws.Signatures[0].Unprotected = &Header{Kid: Thumbprint(publicKey(alice))}
+	data, err = json.Marshal(jws)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"signatures"`)
+
+	parsed, err = ParseJSON(data)
+	require.NoError(t, err)
+	_, err = parsed.Verify(allowlist)
+	require.NoError(t, err)
+}
+
+func TestParseErrors(t *testing.T) {
+	alice := testKey(t, 1)
+	allowlist := NewAllowlist(publicKey(alice))
+
+	for _, s := range []string{"", "a.b", "a.b.c.d", "!.e30.AA"} {
+		_, err := ParseCompact(s)
+		assert.Equal(t, ErrMalformed, err, s)
+	}
+	_, err := ParseJSON([]byte(`{"payload":"e30"}`))
+	assert.Equal(t, ErrMalformed, err)
+
+	jws, err := SignCompact([]byte("payload"), Signer{Key: alice, Header: Header{Crit: []string{"exp"}}})
+	require.NoError(t, err)
+	_, err = VerifyCompact(jws, allowlist)
+	assert.Equal(t, ErrUnsupportedCritical, err)
+
+	_, err = SignCompact([]byte("payload"), Signer{Key: alice, Header: Header{Alg: "HS256"}})
+	assert.Equal(t, ErrUnsupportedAlgorithm, err)
+}
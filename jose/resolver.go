@@ -0,0 +1,106 @@
+// Copyright 2019 Spacemesh Authors
+// JWS verification key resolution
+
+package jose
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+// KeyResolver supplies the keys a verifier trusts.
+type KeyResolver interface {
+	// ResolveKey returns the key named by the "kid" or "jwk" parameter of h.
+	// A key embedded with "jwk" must only be returned if it is trusted.
+	ResolveKey(h *Header) (ed25519.PublicKey, error)
+	// KnownKey reports whether a key extracted from an AlgEdDSA2 signature
+	// is trusted.
+	KnownKey(publicKey ed25519.PublicKey) bool
+}
+
+// Allowlist is a KeyResolver that trusts a fixed set of keys, indexed by key
+// ID. It is safe for concurrent use.
+type Allowlist struct {
+	mu    sync.RWMutex
+	byKid map[string]ed25519.PublicKey
+	known map[string]struct{}
+}
+
+// NewAllowlist returns an Allowlist trusting publicKeys, each under its RFC
+// 7638 thumbprint as key ID. It will panic if the length of a key is not
+// ed25519.PublicKeySize.
+func NewAllowlist(publicKeys ...ed25519.PublicKey) *Allowlist {
+	a := &Allowlist{
+		byKid: make(map[string]ed25519.PublicKey),
+		known: make(map[string]struct{}),
+	}
+	for _, publicKey := range publicKeys {
+		if err := a.Add(Thumbprint(publicKey), publicKey); err != nil {
+			panic(err)
+		}
+	}
+	return a
+}
+
+// Add trusts publicKey under key ID kid. It returns an error if the length of
+// publicKey is not ed25519.PublicKeySize.
+func (a *Allowlist) Add(kid string, publicKey ed25519.PublicKey) error {
+	if l := len(publicKey); l != ed25519.PublicKeySize {
+		return errors.New("jose: bad public key length: " + strconv.Itoa(l))
+	}
+	publicKey = append(ed25519.PublicKey{}, publicKey...)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.byKid[kid] = publicKey
+	a.known[string(publicKey)] = struct{}{}
+	return nil
+}
+
+// ResolveKey implements KeyResolver. A header naming no key resolves only if
+// the allowlist holds exactly one key.
+func (a *Allowlist) ResolveKey(h *Header) (ed25519.PublicKey, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	switch {
+	case h.Kid != "":
+		publicKey, ok := a.byKid[h.Kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if h.JWK != nil {
+			embedded, err := h.JWK.PublicKey()
+			if err != nil {
+				return nil, err
+			}
+			if !embedded.Equal(publicKey) {
+				return nil, ErrUnknownKey
+			}
+		}
+		return publicKey, nil
+	case h.JWK != nil:
+		publicKey, err := h.JWK.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := a.known[string(publicKey)]; !ok {
+			return nil, ErrUnknownKey
+		}
+		return publicKey, nil
+	case len(a.byKid) == 1:
+		for _, publicKey := range a.byKid {
+			return publicKey, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// KnownKey implements KeyResolver.
+func (a *Allowlist) KnownKey(publicKey ed25519.PublicKey) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	_, ok := a.known[string(publicKey)]
+	return ok
+}
@@ -0,0 +1,378 @@
+// Copyright 2019 Spacemesh Authors
+// MuSig2 multi-signatures
+
+// Package musig2 implements MuSig2 (Nick, Ruffing and Seurin, "MuSig2: Simple
+// Two-Round Schnorr Multi-Signatures", https://eprint.iacr.org/2020/1261) over
+// Ed25519: n co-signers produce one 64-byte signature that verifies against
+// a single aggregate public key.
+//
+// Signing takes two rounds. In the first, each signer calls NewNonce and
+// sends its PublicNonce to the others; the nonces do not depend on the
+// message and can be exchanged in advance. In the second, each signer
+// aggregates the nonces, opens a Session for the message and sends the
+// partial signature from Session.Sign. Anyone holding the partial signatures
+// can combine them with Session.Aggregate, after checking each with
+// Session.VerifyPartial to find out who misbehaved.
+//
+// In ModeSign2 the signature uses the challenge of ed25519.Sign2, so it
+// verifies with ed25519.Verify2 and ed25519.ExtractPublicKey returns the
+// aggregate key. In ModeEd25519 it is a standard Ed25519 signature that
+// verifies with ed25519.Verify.
+//
+// The aggregate key is the sum of the keys weighted by coefficients that
+// hash the whole key set, which stops a signer from choosing its key as a
+// function of the others' to control the aggregate key.
+package musig2
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+)
+
+const (
+	// PublicNonceSize is the size, in bytes, of public and aggregate nonces.
+	PublicNonceSize = 64
+	// PartialSignatureSize is the size, in bytes, of partial signatures.
+	PartialSignatureSize = 32
+)
+
+// Domain separators of the hashes, besides the Ed25519 challenge.
+const (
+	keysDomain             = "spacemesh ed25519 musig2 keys v1"
+	coefficientDomain      = "spacemesh ed25519 musig2 key coefficient v1"
+	nonceDomain            = "spacemesh ed25519 musig2 nonce v1"
+	nonceCoefficientDomain = "spacemesh ed25519 musig2 nonce coefficient v1"
+)
+
+// Mode selects the challenge of the signature.
+type Mode int
+
+const (
+	// ModeSign2 hashes R || M, as ed25519.Sign2 does.
+	ModeSign2 Mode = iota
+	// ModeEd25519 hashes R || A || M, as ed25519.Sign does.
+	ModeEd25519
+)
+
+var (
+	// ErrNonceReused is returned when a SecretNonce is used a second time.
+	ErrNonceReused = errors.New("musig2: secret nonce already used")
+	// ErrUnknownKey is returned for public keys that are not in the key set.
+	ErrUnknownKey = errors.New("musig2: public key not in key set")
+	// ErrInvalidNonce is returned for nonces that do not encode curve
+	// points.
+	ErrInvalidNonce = errors.New("musig2: invalid nonce")
+	// ErrInvalidPartialSignature is returned for partial signatures that are
+	// not scalars below l.
+	ErrInvalidPartialSignature = errors.New("musig2: invalid partial signature")
+)
+
+// KeySet is the aggregation of the co-signers' public keys. The keys are
+// sorted, so the co-signers may list them in any order.
+type KeySet struct {
+	publicKeys   [][32]byte
+	coefficients [][32]byte
+	points       []edwards25519.ExtendedGroupElement
+	aggregate    [32]byte
+}
+
+// NewKeySet aggregates publicKeys.
+func NewKeySet(publicKeys []ed25519.PublicKey) (*KeySet, error) {
+	if len(publicKeys) == 0 {
+		return nil, errors.New("musig2: no public keys")
+	}
+	k := &KeySet{
+		publicKeys:   make([][32]byte, len(publicKeys)),
+		coefficients: make([][32]byte, len(publicKeys)),
+		points:       make([]edwards25519.ExtendedGroupElement, len(publicKeys)),
+	}
+	for i, publicKey := range publicKeys {
+		if l := len(publicKey); l != ed25519.PublicKeySize {
+			return nil, errors.New("musig2: bad public key length: " + strconv.Itoa(l))
+		}
+		copy(k.publicKeys[i][:], publicKey)
+	}
+	sort.Slice(k.publicKeys, func(i, j int) bool {
+		return bytes.Compare(k.publicKeys[i][:], k.publicKeys[j][:]) < 0
+	})
+
+	h := sha512.New()
+	h.Write([]byte(keysDomain))
+	for i := range k.publicKeys {
+		h.Write(k.publicKeys[i][:])
+	}
+	keysHash := h.Sum(nil)
+
+	for i := range k.publicKeys {
+		if !k.points[i].FromBytes(&k.publicKeys[i]) {
+			return nil, ed25519.ErrInvalidPublicKey
+		}
+		h.Reset()
+		h.Write([]byte(coefficientDomain))
+		h.Write(keysHash)
+		h.Write(k.publicKeys[i][:])
+		reduce(&k.coefficients[i], h)
+	}
+
+	var X edwards25519.ExtendedGroupElement
+	edwards25519.GeMultiScalarMultVartime(&X, k.coefficients, k.points)
+	X.ToBytes(&k.aggregate)
+	return k, nil
+}
+
+// PublicKey returns the aggregate public key, which verifies the signatures
+// of the key set.
+func (k *KeySet) PublicKey() ed25519.PublicKey {
+	publicKey := make([]byte, ed25519.PublicKeySize)
+	copy(publicKey, k.aggregate[:])
+	return publicKey
+}
+
+// index returns the position of publicKey in the sorted key set.
+func (k *KeySet) index(publicKey []byte) (int, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return 0, ErrUnknownKey
+	}
+	i := sort.Search(len(k.publicKeys), func(i int) bool {
+		return bytes.Compare(k.publicKeys[i][:], publicKey) >= 0
+	})
+	if i == len(k.publicKeys) || !bytes.Equal(k.publicKeys[i][:], publicKey) {
+		return 0, ErrUnknownKey
+	}
+	return i, nil
+}
+
+// PublicNonce is the R1 || R2 a signer publishes in the first round, or the
+// sum of all the signers' nonces.
+type PublicNonce [PublicNonceSize]byte
+
+// SecretNonce holds the secret r1, r2 of a PublicNonce. It can be used for
+// a single signature: Session.Sign erases it and fails when called again.
+// Reusing a nonce in two signatures reveals the private key, so a
+// SecretNonce must not be copied, stored or restored.
+type SecretNonce struct {
+	mu        sync.Mutex
+	r1, r2    [32]byte
+	publicKey [32]byte
+	used      bool
+}
+
+// NewNonce returns a fresh nonce of privateKey for one signature. The secret
+// values hash 32 bytes from rand with the private key, so they stay secret if
+// rand is weak but not if it repeats. If rand is nil, crypto/rand.Reader
+// will be used.
+func NewNonce(rand io.Reader, privateKey ed25519.PrivateKey) (*SecretNonce, *PublicNonce, error) {
+	if l := len(privateKey); l != ed25519.PrivateKeySize {
+		return nil, nil, errors.New("musig2: bad private key length: " + strconv.Itoa(l))
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	var seed [32]byte
+	if _, err := io.ReadFull(rand, seed[:]); err != nil {
+		return nil, nil, err
+	}
+
+	secret := new(SecretNonce)
+	copy(secret.publicKey[:], privateKey[ed25519.SeedSize:])
+	public := new(PublicNonce)
+	h := sha512.New()
+	for j, r := range []*[32]byte{&secret.r1, &secret.r2} {
+		h.Reset()
+		h.Write([]byte(nonceDomain))
+		h.Write(seed[:])
+		h.Write(privateKey)
+		h.Write([]byte{byte(j)})
+		reduce(r, h)
+
+		var R edwards25519.ExtendedGroupElement
+		var encoded [32]byte
+		edwards25519.GeScalarMultBase(&R, r)
+		R.ToBytes(&encoded)
+		copy(public[32*j:], encoded[:])
+	}
+	return secret, public, nil
+}
+
+// AggregateNonces returns the sum of the co-signers' public nonces.
+func AggregateNonces(nonces []PublicNonce) (*PublicNonce, error) {
+	if len(nonces) == 0 {
+		return nil, errors.New("musig2: no nonces")
+	}
+	var sum [2]edwards25519.ExtendedGroupElement
+	sum[0].Zero()
+	sum[1].Zero()
+	for i := range nonces {
+		R, err := decodeNonce(&nonces[i])
+		if err != nil {
+			return nil, err
+		}
+		edwards25519.GeAdd(&sum[0], &sum[0], &R[0])
+		edwards25519.GeAdd(&sum[1], &sum[1], &R[1])
+	}
+
+	aggregate := new(PublicNonce)
+	var encoded [32]byte
+	for j := range sum {
+		sum[j].ToBytes(&encoded)
+		copy(aggregate[32*j:], encoded[:])
+	}
+	return aggregate, nil
+}
+
+func decodeNonce(nonce *PublicNonce) (R [2]edwards25519.ExtendedGroupElement, err error) {
+	var encoded [32]byte
+	for j := range R {
+		copy(encoded[:], nonce[32*j:])
+		if !R[j].FromBytes(&encoded) {
+			return R, ErrInvalidNonce
+		}
+	}
+	return R, nil
+}
+
+// Session is the second round of signing message with the key set, once
+// the nonces are known.
+type Session struct {
+	keys *KeySet
+	// b weighs R2 in R = R1 + b·R2, the nonce of the signature, and e is the
+	// challenge
+	b, e [32]byte
+	r    [32]byte
+}
+
+// NewSession starts signing message with the aggregate nonce of all the
+// co-signers' public nonces.
+func (k *KeySet) NewSession(aggregateNonce *PublicNonce, message []byte, mode Mode) (*Session, error) {
+	R, err := decodeNonce(aggregateNonce)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{keys: k}
+
+	h := sha512.New()
+	h.Write([]byte(nonceCoefficientDomain))
+	h.Write(aggregateNonce[:])
+	h.Write(k.aggregate[:])
+	h.Write(message)
+	reduce(&s.b, h)
+
+	var sum edwards25519.ExtendedGroupElement
+	edwards25519.GeMultiScalarMultVartime(&sum, [][32]byte{{1}, s.b}, R[:])
+	sum.ToBytes(&s.r)
+
+	h.Reset()
+	h.Write(s.r[:])
+	if mode == ModeEd25519 {
+		h.Write(k.aggregate[:])
+	}
+	h.Write(message)
+	reduce(&s.e, h)
+	return s, nil
+}
+
+// Sign returns the partial signature of privateKey, a member of the key set,
+// with nonce, which must be the secret of the PublicNonce it contributed to
+// the session. nonce is erased and cannot be used again.
+func (s *Session) Sign(nonce *SecretNonce, privateKey ed25519.PrivateKey) ([]byte, error) {
+	if l := len(privateKey); l != ed25519.PrivateKeySize {
+		return nil, errors.New("musig2: bad private key length: " + strconv.Itoa(l))
+	}
+	publicKey := privateKey[ed25519.SeedSize:]
+	i, err := s.keys.index(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce.mu.Lock()
+	defer nonce.mu.Unlock()
+	if nonce.used {
+		return nil, ErrNonceReused
+	}
+	if !bytes.Equal(nonce.publicKey[:], publicKey) {
+		return nil, errors.New("musig2: nonce of another key")
+	}
+	nonce.used = true
+	r1, r2 := nonce.r1, nonce.r2
+	nonce.r1, nonce.r2 = [32]byte{}, [32]byte{}
+
+	// s = r1 + b·r2 + e·a·x
+	var x, ea, r, partial [32]byte
+	copy(x[:], ed25519.PrivateKeyToCurve25519(privateKey))
+	edwards25519.ScMul(&ea, &s.e, &s.keys.coefficients[i])
+	edwards25519.ScMulAdd(&r, &s.b, &r2, &r1)
+	edwards25519.ScMulAdd(&partial, &ea, &x, &r)
+	return partial[:], nil
+}
+
+// VerifyPartial reports whether partial is a valid partial signature of
+// publicKey, which contributed nonce to the session.
+func (s *Session) VerifyPartial(publicKey ed25519.PublicKey, nonce *PublicNonce, partial []byte) bool {
+	i, err := s.keys.index(publicKey)
+	if err != nil || len(partial) != PartialSignatureSize {
+		return false
+	}
+	var sp [32]byte
+	copy(sp[:], partial)
+	if !edwards25519.ScMinimal(&sp) {
+		return false
+	}
+	R, err := decodeNonce(nonce)
+	if err != nil {
+		return false
+	}
+
+	// s·B = R1 + b·R2 + e·a·X
+	var ea [32]byte
+	edwards25519.ScMul(&ea, &s.e, &s.keys.coefficients[i])
+	var sum, sB edwards25519.ExtendedGroupElement
+	points := []edwards25519.ExtendedGroupElement{R[0], R[1], s.keys.points[i]}
+	edwards25519.GeMultiScalarMultVartime(&sum, [][32]byte{{1}, s.b, ea}, points)
+	edwards25519.GeScalarMultBase(&sB, &sp)
+	var got, want [32]byte
+	sum.ToBytes(&want)
+	sB.ToBytes(&got)
+	return got == want
+}
+
+// Aggregate returns the signature made of the partial signatures of all the
+// co-signers, in any order.
+func (s *Session) Aggregate(partials [][]byte) ([]byte, error) {
+	if len(partials) != len(s.keys.publicKeys) {
+		return nil, errors.New("musig2: " + strconv.Itoa(len(partials)) + " partial signatures for " +
+			strconv.Itoa(len(s.keys.publicKeys)) + " keys")
+	}
+	var sum, sp [32]byte
+	for _, partial := range partials {
+		if len(partial) != PartialSignatureSize {
+			return nil, ErrInvalidPartialSignature
+		}
+		copy(sp[:], partial)
+		if !edwards25519.ScMinimal(&sp) {
+			return nil, ErrInvalidPartialSignature
+		}
+		edwards25519.ScAdd(&sum, &sum, &sp)
+	}
+
+	signature := make([]byte, ed25519.SignatureSize)
+	copy(signature, s.r[:])
+	copy(signature[32:], sum[:])
+	return signature, nil
+}
+
+// reduce sets s to the digest of h reduced mod l.
+func reduce(s *[32]byte, h hash.Hash) {
+	var digest [64]byte
+	h.Sum(digest[:0])
+	edwards25519.ScReduce(s, &digest)
+}
@@ -0,0 +1,242 @@
+// Copyright 2019 Spacemesh Authors
+// MuSig2 unit tests
+
+package musig2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signer struct {
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+	secret     *SecretNonce
+	public     *PublicNonce
+}
+
+func newSigners(t *testing.T, n int) ([]*signer, *KeySet) {
+	signers := make([]*signer, n)
+	publicKeys := make([]ed25519.PublicKey, n)
+	for i := range signers {
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		signers[i] = &signer{publicKey: publicKey, privateKey: privateKey}
+		publicKeys[i] = publicKey
+	}
+	keys, err := NewKeySet(publicKeys)
+	require.NoError(t, err)
+	return signers, keys
+}
+
+// sign runs both rounds for all the signers and returns the session and the
+// partial signatures.
+func sign(t *testing.T, signers []*signer, keys *KeySet, message []byte, mode Mode) (*Session, [][]byte) {
+	nonces := make([]PublicNonce, len(signers))
+	for i, s := range signers {
+		var err error
+		s.secret, s.public, err = NewNonce(nil, s.privateKey)
+		require.NoError(t, err)
+		nonces[i] = *s.public
+	}
+	aggregateNonce, err := AggregateNonces(nonces)
+	require.NoError(t, err)
+	session, err := keys.NewSession(aggregateNonce, message, mode)
+	require.NoError(t, err)
+
+	partials := make([][]byte, len(signers))
+	for i, s := range signers {
+		partials[i], err = session.Sign(s.secret, s.privateKey)
+		require.NoError(t, err)
+		require.True(t, session.VerifyPartial(s.publicKey, s.public, partials[i]))
+	}
+	return session, partials
+}
+
+func TestMuSig2Sign2(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 7} {
+		signers, keys := newSigners(t, n)
+		message := []byte("multi-party account transfer")
+		session, partials := sign(t, signers, keys, message, ModeSign2)
+		sig, err := session.Aggregate(partials)
+		require.NoError(t, err)
+		require.Len(t, sig, ed25519.SignatureSize)
+
+		publicKey := keys.PublicKey()
+		assert.True(t, ed25519.Verify2(publicKey, message, sig), "n = %d", n)
+		assert.False(t, ed25519.Verify2(publicKey, []byte("another message"), sig))
+		assert.False(t, ed25519.Verify(publicKey, message, sig))
+
+		extracted, err := ed25519.ExtractPublicKey(message, sig)
+		require.NoError(t, err)
+		assert.Equal(t, publicKey, extracted)
+	}
+}
+
+func TestMuSig2Ed25519(t *testing.T) {
+	signers, keys := newSigners(t, 3)
+	message := []byte("multi-party account transfer")
+	session, partials := sign(t, signers, keys, message, ModeEd25519)
+	sig, err := session.Aggregate(partials)
+	require.NoError(t, err)
+
+	publicKey := keys.PublicKey()
+	assert.True(t, ed25519.Verify(publicKey, message, sig))
+	assert.False(t, ed25519.Verify(publicKey, []byte("another message"), sig))
+	assert.False(t, ed25519.Verify2(publicKey, message, sig))
+}
+
+func TestKeySetOrder(t *testing.T) {
+	signers, keys := newSigners(t, 4)
+	reversed := make([]ed25519.PublicKey, len(signers))
+	for i, s := range signers {
+		reversed[len(signers)-1-i] = s.publicKey
+	}
+	other, err := NewKeySet(reversed)
+	require.NoError(t, err)
+	assert.Equal(t, keys.PublicKey(), other.PublicKey())
+
+	// a different key set gives a different key
+	other, err = NewKeySet(reversed[1:])
+	require.NoError(t, err)
+	assert.NotEqual(t, keys.PublicKey(), other.PublicKey())
+
+	_, err = NewKeySet(nil)
+	assert.Error(t, err)
+	invalid := make([]byte, ed25519.PublicKeySize)
+	invalid[0] = 2
+	_, err = NewKeySet([]ed25519.PublicKey{signers[0].publicKey, invalid})
+	assert.Equal(t, ed25519.ErrInvalidPublicKey, err)
+	_, err = NewKeySet([]ed25519.PublicKey{invalid[:31]})
+	assert.Error(t, err)
+}
+
+func TestNonceReuse(t *testing.T) {
+	signers, keys := newSigners(t, 2)
+	session, _ := sign(t, signers, keys, []byte("first message"), ModeSign2)
+
+	// the secret nonce is erased after its first use
+	_, err := session.Sign(signers[0].secret, signers[0].privateKey)
+	assert.Equal(t, ErrNonceReused, err)
+	assert.Equal(t, [32]byte{}, signers[0].secret.r1)
+	assert.Equal(t, [32]byte{}, signers[0].secret.r2)
+
+	// also in a session for another message with the same nonces, which
+	// would reveal the private key
+	nonces := []PublicNonce{*signers[0].public, *signers[1].public}
+	aggregateNonce, err := AggregateNonces(nonces)
+	require.NoError(t, err)
+	other, err := keys.NewSession(aggregateNonce, []byte("second message"), ModeSign2)
+	require.NoError(t, err)
+	_, err = other.Sign(signers[1].secret, signers[1].privateKey)
+	assert.Equal(t, ErrNonceReused, err)
+
+	// a nonce only signs for its own key
+	secret, _, err := NewNonce(rand.Reader, signers[0].privateKey)
+	require.NoError(t, err)
+	_, err = other.Sign(secret, signers[1].privateKey)
+	assert.Error(t, err)
+	// which must be in the key set
+	_, outsider, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	secret, _, err = NewNonce(rand.Reader, outsider)
+	require.NoError(t, err)
+	_, err = other.Sign(secret, outsider)
+	assert.Equal(t, ErrUnknownKey, err)
+}
+
+// TestRogueKey checks that a signer who picks its key as Y - X1, for a key Y
+// it controls, neither gets Y as aggregate key nor can sign alone.
+func TestRogueKey(t *testing.T) {
+	signers, _ := newSigners(t, 1)
+	rogueY, rogueYPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var X1, negX1, Y, rogue, sum edwards25519.ExtendedGroupElement
+	var x1, y, rogueKey, sumKey [32]byte
+	copy(x1[:], signers[0].publicKey)
+	copy(y[:], rogueY)
+	require.True(t, X1.FromBytes(&x1))
+	require.True(t, Y.FromBytes(&y))
+	edwards25519.GeNeg(&negX1, &X1)
+	edwards25519.GeAdd(&rogue, &Y, &negX1)
+	rogue.ToBytes(&rogueKey)
+
+	// with unit coefficients the keys would sum to Y, which the rogue
+	// signer can sign for alone
+	message := []byte("steal the funds")
+	sig := ed25519.Sign2(rogueYPrivate, message)
+	edwards25519.GeAdd(&sum, &X1, &rogue)
+	sum.ToBytes(&sumKey)
+	assert.Equal(t, y, sumKey)
+	assert.True(t, ed25519.Verify2(sumKey[:], message, sig))
+
+	keys, err := NewKeySet([]ed25519.PublicKey{signers[0].publicKey, rogueKey[:]})
+	require.NoError(t, err)
+	assert.NotEqual(t, ed25519.PublicKey(rogueY), keys.PublicKey())
+	assert.False(t, ed25519.Verify2(keys.PublicKey(), message, sig))
+
+	// the coefficient of the rogue key is not 1, and depends on the honest
+	// key, which the rogue signer had to fix its key against
+	one := [32]byte{1}
+	i, err := keys.index(rogueKey[:])
+	require.NoError(t, err)
+	assert.NotEqual(t, one, keys.coefficients[i])
+	other, _ := newSigners(t, 1)
+	otherKeys, err := NewKeySet([]ed25519.PublicKey{other[0].publicKey, rogueKey[:]})
+	require.NoError(t, err)
+	j, err := otherKeys.index(rogueKey[:])
+	require.NoError(t, err)
+	assert.NotEqual(t, keys.coefficients[i], otherKeys.coefficients[j])
+}
+
+func TestMisbehavingSigner(t *testing.T) {
+	signers, keys := newSigners(t, 3)
+	message := []byte("multi-party account transfer")
+	session, partials := sign(t, signers, keys, message, ModeSign2)
+
+	// a wrong partial signature is caught and blamed on its signer
+	bad := append([]byte{}, partials[1]...)
+	bad[0] ^= 1
+	assert.False(t, session.VerifyPartial(signers[1].publicKey, signers[1].public, bad))
+	assert.False(t, session.VerifyPartial(signers[0].publicKey, signers[0].public, partials[1]))
+	assert.False(t, session.VerifyPartial(signers[1].publicKey, signers[0].public, partials[1]))
+	assert.False(t, session.VerifyPartial(signers[1].publicKey, signers[1].public, bad[:31]))
+
+	sig, err := session.Aggregate([][]byte{partials[0], bad, partials[2]})
+	require.NoError(t, err)
+	assert.False(t, ed25519.Verify2(keys.PublicKey(), message, sig))
+
+	_, err = session.Aggregate(partials[:2])
+	assert.Error(t, err)
+	_, err = session.Aggregate([][]byte{partials[0], partials[1], bytes.Repeat([]byte{0xff}, 32)})
+	assert.Equal(t, ErrInvalidPartialSignature, err)
+
+	var invalid PublicNonce
+	invalid[0] = 2
+	_, err = AggregateNonces([]PublicNonce{*signers[0].public, invalid})
+	assert.Equal(t, ErrInvalidNonce, err)
+}
+
+func BenchmarkSign(b *testing.B) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(b, err)
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(b, err)
+	keys, err := NewKeySet([]ed25519.PublicKey{publicKey, other})
+	require.NoError(b, err)
+	message := []byte("multi-party account transfer")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		secret, public, _ := NewNonce(nil, privateKey)
+		session, _ := keys.NewSession(public, message, ModeSign2)
+		_, _ = session.Sign(secret, privateKey)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 OpenSSH key encodings
+
+package ed25519
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"strconv"
+
+	"github.com/spacemeshos/ed25519/internal/sshwire"
+)
+
+// OpenSSHPrivateKeyPEMType is the PEM block type of openssh-key-v1 private keys.
+const OpenSSHPrivateKeyPEMType = "OPENSSH PRIVATE KEY"
+
+const openSSHKeyMagic = "openssh-key-v1\x00"
+
+// MarshalOpenSSHPublicKey returns the SSH wire encoding of publicKey, as used
+// inside authorized_keys lines, agent messages and certificates.
+func MarshalOpenSSHPublicKey(publicKey PublicKey) []byte {
+	if l := len(publicKey); l != PublicKeySize {
+		panic("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+	return sshwire.MarshalED25519PublicKey(publicKey)
+}
+
+// ParseOpenSSHPublicKey parses the SSH wire encoding of an ssh-ed25519 key.
+func ParseOpenSSHPublicKey(blob []byte) (PublicKey, error) {
+	pub, ok := sshwire.ParseED25519PublicKey(blob)
+	if !ok {
+		return nil, errors.New("ed25519: not an ssh-ed25519 public key")
+	}
+	return PublicKey(append([]byte(nil), pub...)), nil
+}
+
+// MarshalAuthorizedKey returns publicKey as a single authorized_keys line,
+// "ssh-ed25519 <base64> <comment>", terminated by a newline. The comment is
+// omitted when empty.
+func MarshalAuthorizedKey(publicKey PublicKey, comment string) []byte {
+	blob := MarshalOpenSSHPublicKey(publicKey)
+	line := []byte(sshwire.KeyAlgoED25519 + " " + base64.StdEncoding.EncodeToString(blob))
+	if comment != "" {
+		line = append(line, ' ')
+		line = append(line, comment...)
+	}
+	return append(line, '\n')
+}
+
+// ParseAuthorizedKey parses the first ssh-ed25519 key in an authorized_keys
+// or .pub file, skipping blank lines and comments. Key options preceding the
+// algorithm name are not supported.
+func ParseAuthorizedKey(data []byte) (PublicKey, string, error) {
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		fields := bytes.Fields(line)
+		if len(fields) < 2 {
+			return nil, "", errors.New("ed25519: malformed authorized key line")
+		}
+		if string(fields[0]) != sshwire.KeyAlgoED25519 {
+			return nil, "", errors.New("ed25519: unsupported key type " + strconv.Quote(string(fields[0])))
+		}
+		blob, err := base64.StdEncoding.DecodeString(string(fields[1]))
+		if err != nil {
+			return nil, "", err
+		}
+		publicKey, err := ParseOpenSSHPublicKey(blob)
+		if err != nil {
+			return nil, "", err
+		}
+		comment := string(bytes.Join(fields[2:], []byte(" ")))
+		return publicKey, comment, nil
+	}
+	return nil, "", errors.New("ed25519: no key found")
+}
+
+// MarshalOpenSSHPrivateKey returns privateKey as an unencrypted, PEM encoded
+// openssh-key-v1 blob, as written by ssh-keygen.
+func MarshalOpenSSHPrivateKey(privateKey PrivateKey, comment string) ([]byte, error) {
+	if l := len(privateKey); l != PrivateKeySize {
+		return nil, errors.New("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	checkInt := binary.BigEndian.Uint32(check[:])
+
+	publicKey := privateKey[32:]
+	var priv []byte
+	priv = sshwire.AppendUint32(priv, checkInt)
+	priv = sshwire.AppendUint32(priv, checkInt)
+	priv = sshwire.AppendString(priv, []byte(sshwire.KeyAlgoED25519))
+	priv = sshwire.AppendString(priv, publicKey)
+	priv = sshwire.AppendString(priv, privateKey)
+	priv = sshwire.AppendString(priv, []byte(comment))
+	// unencrypted keys are padded to the 8 byte cipher block size of "none"
+	for i := byte(1); len(priv)%8 != 0; i++ {
+		priv = append(priv, i)
+	}
+
+	blob := []byte(openSSHKeyMagic)
+	blob = sshwire.AppendString(blob, []byte("none"))
+	blob = sshwire.AppendString(blob, []byte("none"))
+	blob = sshwire.AppendString(blob, nil)
+	blob = sshwire.AppendUint32(blob, 1)
+	blob = sshwire.AppendString(blob, MarshalOpenSSHPublicKey(PublicKey(publicKey)))
+	blob = sshwire.AppendString(blob, priv)
+
+	return pem.EncodeToMemory(&pem.Block{Type: OpenSSHPrivateKeyPEMType, Bytes: blob}), nil
+}
+
+// ParseOpenSSHPrivateKey parses a PEM encoded openssh-key-v1 private key and
+// returns the key and its comment. Encrypted keys are not supported.
+func ParseOpenSSHPrivateKey(data []byte) (PrivateKey, string, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, "", errors.New("ed25519: no PEM block found")
+	}
+	if block.Type != OpenSSHPrivateKeyPEMType {
+		return nil, "", errors.New("ed25519: unsupported PEM block type " + strconv.Quote(block.Type))
+	}
+	return parseOpenSSHPrivateKeyBlob(block.Bytes)
+}
+
+func parseOpenSSHPrivateKeyBlob(blob []byte) (PrivateKey, string, error) {
+	if !bytes.HasPrefix(blob, []byte(openSSHKeyMagic)) {
+		return nil, "", errors.New("ed25519: invalid openssh-key-v1 magic")
+	}
+	r := sshwire.NewReader(blob[len(openSSHKeyMagic):])
+	cipherName := r.String()
+	kdfName := r.String()
+	r.String() // kdf options
+	numKeys := r.Uint32()
+	pubBlob := r.String()
+	priv := r.String()
+	if !r.Empty() {
+		return nil, "", errors.New("ed25519: malformed openssh-key-v1 blob")
+	}
+	if string(cipherName) != "none" || string(kdfName) != "none" {
+		return nil, "", errors.New("ed25519: encrypted OpenSSH private keys are not supported")
+	}
+	if numKeys != 1 {
+		return nil, "", errors.New("ed25519: unsupported number of keys: " + strconv.Itoa(int(numKeys)))
+	}
+	publicKey, err := ParseOpenSSHPublicKey(pubBlob)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the private section is padded to the 8 byte block size of "none"
+	if len(priv)%8 != 0 {
+		return nil, "", errors.New("ed25519: openssh-key-v1 private section not a multiple of the block size")
+	}
+	r = sshwire.NewReader(priv)
+	check1 := r.Uint32()
+	check2 := r.Uint32()
+	keyType := r.String()
+	pub := r.String()
+	key := r.String()
+	comment := r.String()
+	padding := r.Rest()
+	if !r.Ok() {
+		return nil, "", errors.New("ed25519: malformed openssh-key-v1 private section")
+	}
+	if check1 != check2 {
+		return nil, "", errors.New("ed25519: openssh-key-v1 check values mismatch")
+	}
+	if string(keyType) != sshwire.KeyAlgoED25519 {
+		return nil, "", errors.New("ed25519: unsupported key type " + strconv.Quote(string(keyType)))
+	}
+	if len(padding) >= 8 {
+		return nil, "", errors.New("ed25519: invalid openssh-key-v1 padding")
+	}
+	for i, b := range padding {
+		if int(b) != i+1 {
+			return nil, "", errors.New("ed25519: invalid openssh-key-v1 padding")
+		}
+	}
+	if len(key) != PrivateKeySize || !bytes.Equal(pub, publicKey) || !bytes.Equal(key[32:], publicKey) {
+		return nil, "", errors.New("ed25519: inconsistent openssh-key-v1 key pair")
+	}
+
+	// the stored public half must be the one derived from the seed
+	privateKey := NewKeyFromSeed(key[:SeedSize])
+	if subtle.ConstantTimeCompare(privateKey, key) != 1 {
+		return nil, "", errors.New("ed25519: openssh-key-v1 public key does not match seed")
+	}
+	return privateKey, string(comment), nil
+}
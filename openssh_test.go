@@ -0,0 +1,148 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 OpenSSH key encodings unit tests
+
+package ed25519
+
+import (
+	"bytes"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/spacemeshos/ed25519/internal/sshwire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSSHPrivateKeyRoundTrip(t *testing.T) {
+	seed := rnd32Bytes(t)
+	privateKey := NewDerivedKeyFromSeed(seed[:], 3, []byte("Spacemesh rocks"))
+
+	data, err := MarshalOpenSSHPrivateKey(privateKey, "derived key #3")
+	require.NoError(t, err)
+
+	parsed, comment, err := ParseOpenSSHPrivateKey(data)
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, parsed)
+	assert.Equal(t, "derived key #3", comment)
+
+	parsed, err = ParsePrivateKeyPEM(data)
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, parsed)
+}
+
+func TestAuthorizedKeyRoundTrip(t *testing.T) {
+	seed := rnd32Bytes(t)
+	privateKey := NewDerivedKeyFromSeed(seed[:], 3, []byte("Spacemesh rocks"))
+	publicKey := privateKey.Public().(PublicKey)
+
+	line := MarshalAuthorizedKey(publicKey, "alice@example.com")
+	assert.True(t, bytes.HasPrefix(line, []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI")))
+
+	parsed, comment, err := ParseAuthorizedKey(append([]byte("# keys\n\n"), line...))
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, parsed)
+	assert.Equal(t, "alice@example.com", comment)
+
+	parsed, err = ParseOpenSSHPublicKey(MarshalOpenSSHPublicKey(publicKey))
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, parsed)
+}
+
+// Test an unencrypted key pair generated by ssh-keygen -t ed25519
+func TestParseSSHKeygenKeys(t *testing.T) {
+	data, err := os.ReadFile("testdata/openssh_ed25519")
+	require.NoError(t, err)
+	privateKey, comment, err := ParseOpenSSHPrivateKey(data)
+	require.NoError(t, err)
+	assert.Equal(t, "test@spacemesh", comment)
+
+	data, err = os.ReadFile("testdata/openssh_ed25519.pub")
+	require.NoError(t, err)
+	publicKey, comment, err := ParseAuthorizedKey(data)
+	require.NoError(t, err)
+	assert.Equal(t, "test@spacemesh", comment)
+
+	assert.Equal(t, publicKey, privateKey.Public())
+	assert.Equal(t, data, MarshalAuthorizedKey(publicKey, comment))
+}
+
+func TestParseOpenSSHPrivateKeyErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/openssh_ed25519")
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	require.NotNil(t, block)
+	privateKey, _, err := ParseOpenSSHPrivateKey(data)
+	require.NoError(t, err)
+
+	corrupt := func(f func(b []byte)) []byte {
+		b := append([]byte(nil), block.Bytes...)
+		f(b)
+		return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: b})
+	}
+
+	// flip the first check value
+	i := bytes.LastIndex(block.Bytes, []byte("ssh-ed25519")) - 12
+	_, _, err = ParseOpenSSHPrivateKey(corrupt(func(b []byte) { b[i] ^= 1 }))
+	assert.Error(t, err)
+
+	// flip a bit of the seed
+	j := bytes.Index(block.Bytes, privateKey)
+	require.Positive(t, j)
+	_, _, err = ParseOpenSSHPrivateKey(corrupt(func(b []byte) { b[j] ^= 1 }))
+	assert.Error(t, err)
+
+	// break the magic
+	_, _, err = ParseOpenSSHPrivateKey(corrupt(func(b []byte) { b[0] = 'x' }))
+	assert.Error(t, err)
+
+	// truncate
+	_, _, err = ParseOpenSSHPrivateKey(pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: block.Bytes[:100]}))
+	assert.Error(t, err)
+
+	_, _, err = ParseAuthorizedKey([]byte("ssh-rsa AAAAB3NzaC1yc2E= rsa\n"))
+	assert.Error(t, err)
+}
+
+// openSSHBlob builds an unencrypted openssh-key-v1 blob for privateKey whose
+// private section ends with padding bytes 1, 2, ..., n.
+func openSSHBlob(privateKey PrivateKey, comment string, n int) []byte {
+	priv := sshwire.AppendUint32(nil, 7)
+	priv = sshwire.AppendUint32(priv, 7)
+	priv = sshwire.AppendString(priv, []byte(sshwire.KeyAlgoED25519))
+	priv = sshwire.AppendString(priv, privateKey[32:])
+	priv = sshwire.AppendString(priv, privateKey)
+	priv = sshwire.AppendString(priv, []byte(comment))
+	for i := 1; i <= n; i++ {
+		priv = append(priv, byte(i))
+	}
+
+	blob := []byte(openSSHKeyMagic)
+	blob = sshwire.AppendString(blob, []byte("none"))
+	blob = sshwire.AppendString(blob, []byte("none"))
+	blob = sshwire.AppendString(blob, nil)
+	blob = sshwire.AppendUint32(blob, 1)
+	blob = sshwire.AppendString(blob, MarshalOpenSSHPublicKey(privateKey.Public().(PublicKey)))
+	return sshwire.AppendString(blob, priv)
+}
+
+func TestParseOpenSSHPrivateKeyPadding(t *testing.T) {
+	_, privateKey, err := GenerateKey(nil)
+	require.NoError(t, err)
+
+	// the comment makes the section 7 bytes short of a block: 4+4+15+36+68+10
+	_, _, err = parseOpenSSHPrivateKeyBlob(openSSHBlob(privateKey, "abcdef", 0))
+	assert.Error(t, err, "not a multiple of the block size")
+	parsed, comment, err := parseOpenSSHPrivateKeyBlob(openSSHBlob(privateKey, "abcdef", 7))
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, parsed)
+	assert.Equal(t, "abcdef", comment)
+	_, _, err = parseOpenSSHPrivateKeyBlob(openSSHBlob(privateKey, "abcdef", 15))
+	assert.Error(t, err, "a whole block of padding")
+
+	// no padding is needed for a full block
+	_, _, err = parseOpenSSHPrivateKeyBlob(openSSHBlob(privateKey, "abcde", 0))
+	require.NoError(t, err)
+	_, _, err = parseOpenSSHPrivateKeyBlob(openSSHBlob(privateKey, "abcde", 8))
+	assert.Error(t, err)
+}
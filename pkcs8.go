@@ -0,0 +1,111 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 PKCS#8, PKIX and PEM key encodings
+
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strconv"
+)
+
+const (
+	// PrivateKeyPEMType is the PEM block type of PKCS#8 private keys.
+	PrivateKeyPEMType = "PRIVATE KEY"
+	// PublicKeyPEMType is the PEM block type of PKIX public keys.
+	PublicKeyPEMType = "PUBLIC KEY"
+)
+
+// MarshalPKCS8 returns the PKCS#8, ASN.1 DER encoding of privateKey as
+// specified by RFC 8410. Only the seed is stored, as RFC 8410 requires.
+func MarshalPKCS8(privateKey PrivateKey) ([]byte, error) {
+	if l := len(privateKey); l != PrivateKeySize {
+		return nil, errors.New("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+	return x509.MarshalPKCS8PrivateKey(privateKey)
+}
+
+// ParsePKCS8 parses an Ed25519 private key in PKCS#8, ASN.1 DER form.
+func ParsePKCS8(der []byte) (PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("ed25519: PKCS#8 key is not an Ed25519 key")
+	}
+	return privateKey, nil
+}
+
+// MarshalPKIX returns the PKIX, ASN.1 DER encoding of publicKey as specified
+// by RFC 8410.
+func MarshalPKIX(publicKey PublicKey) ([]byte, error) {
+	if l := len(publicKey); l != PublicKeySize {
+		return nil, errors.New("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+	return x509.MarshalPKIXPublicKey(publicKey)
+}
+
+// ParsePKIX parses an Ed25519 public key in PKIX, ASN.1 DER form.
+func ParsePKIX(der []byte) (PublicKey, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("ed25519: PKIX key is not an Ed25519 key")
+	}
+	return publicKey, nil
+}
+
+// MarshalPrivateKeyPEM returns privateKey as a PEM encoded PKCS#8 block.
+func MarshalPrivateKeyPEM(privateKey PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS8(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PrivateKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM parses the first PEM block of data. It accepts PKCS#8
+// blocks as well as OpenSSH private keys (see ParseOpenSSHPrivateKey).
+func ParsePrivateKeyPEM(data []byte) (PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("ed25519: no PEM block found")
+	}
+	switch block.Type {
+	case PrivateKeyPEMType:
+		return ParsePKCS8(block.Bytes)
+	case OpenSSHPrivateKeyPEMType:
+		privateKey, _, err := parseOpenSSHPrivateKeyBlob(block.Bytes)
+		return privateKey, err
+	default:
+		return nil, errors.New("ed25519: unsupported PEM block type " + strconv.Quote(block.Type))
+	}
+}
+
+// MarshalPublicKeyPEM returns publicKey as a PEM encoded PKIX block.
+func MarshalPublicKeyPEM(publicKey PublicKey) ([]byte, error) {
+	der, err := MarshalPKIX(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM parses the first PEM block of data as a PKIX public key.
+func ParsePublicKeyPEM(data []byte) (PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("ed25519: no PEM block found")
+	}
+	if block.Type != PublicKeyPEMType {
+		return nil, errors.New("ed25519: unsupported PEM block type " + strconv.Quote(block.Type))
+	}
+	return ParsePKIX(block.Bytes)
+}
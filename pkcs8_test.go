@@ -0,0 +1,83 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 PKCS#8, PKIX and PEM key encodings unit tests
+
+package ed25519
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKCS8RoundTrip(t *testing.T) {
+	seed := rnd32Bytes(t)
+	privateKey := NewDerivedKeyFromSeed(seed[:], 7, []byte("Spacemesh rocks"))
+
+	der, err := MarshalPKCS8(privateKey)
+	require.NoError(t, err)
+	parsed, err := ParsePKCS8(der)
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, parsed)
+
+	pem, err := MarshalPrivateKeyPEM(privateKey)
+	require.NoError(t, err)
+	parsed, err = ParsePrivateKeyPEM(pem)
+	require.NoError(t, err)
+	assert.Equal(t, privateKey, parsed)
+}
+
+func TestPKIXRoundTrip(t *testing.T) {
+	seed := rnd32Bytes(t)
+	privateKey := NewDerivedKeyFromSeed(seed[:], 7, []byte("Spacemesh rocks"))
+	publicKey := privateKey.Public().(PublicKey)
+
+	der, err := MarshalPKIX(publicKey)
+	require.NoError(t, err)
+	parsed, err := ParsePKIX(der)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, parsed)
+
+	pem, err := MarshalPublicKeyPEM(publicKey)
+	require.NoError(t, err)
+	parsed, err = ParsePublicKeyPEM(pem)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, parsed)
+}
+
+// Test keys generated by `openssl genpkey -algorithm ed25519`
+func TestParseOpenSSLKeys(t *testing.T) {
+	data, err := os.ReadFile("testdata/pkcs8_ed25519.pem")
+	require.NoError(t, err)
+	privateKey, err := ParsePrivateKeyPEM(data)
+	require.NoError(t, err)
+
+	data, err = os.ReadFile("testdata/pkix_ed25519.pem")
+	require.NoError(t, err)
+	publicKey, err := ParsePublicKeyPEM(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, publicKey, privateKey.Public())
+
+	message := []byte("test message")
+	assert.True(t, Verify2(publicKey, message, Sign2(privateKey, message)))
+}
+
+func TestParsePEMErrors(t *testing.T) {
+	_, err := ParsePrivateKeyPEM([]byte("not a pem block"))
+	assert.Error(t, err)
+
+	data, err := os.ReadFile("testdata/pkix_ed25519.pem")
+	require.NoError(t, err)
+	_, err = ParsePrivateKeyPEM(data)
+	assert.Error(t, err, "public key block accepted as private key")
+
+	_, err = ParsePKCS8([]byte{0x30, 0x00})
+	assert.Error(t, err)
+
+	_, err = MarshalPKCS8(PrivateKey{1, 2, 3})
+	assert.Error(t, err)
+	_, err = MarshalPKIX(PublicKey{1, 2, 3})
+	assert.Error(t, err)
+}
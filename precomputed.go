@@ -0,0 +1,206 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 precomputed public keys for repeated verification
+
+package ed25519
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha512"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/spacemeshos/ed25519/internal/edwards25519"
+)
+
+// ErrInvalidPublicKey is returned for public keys that do not encode a curve
+// point.
+var ErrInvalidPublicKey = errors.New("ed25519: invalid public key")
+
+// PrecomputedPublicKey is a public key prepared for repeated verification.
+// Verify and Verify2 decompress the key and build a table of its multiples
+// on every call; a PrecomputedPublicKey does both once, with a wider table.
+// It takes about 5 KB and is safe for concurrent use.
+type PrecomputedPublicKey struct {
+	publicKey [PublicKeySize]byte
+	// multiples of -A, as the verification equations use it
+	minusA edwards25519.PreComputedMultiples
+}
+
+// NewPrecomputedPublicKey decompresses publicKey and precomputes its
+// multiples.
+func NewPrecomputedPublicKey(publicKey PublicKey) (*PrecomputedPublicKey, error) {
+	if l := len(publicKey); l != PublicKeySize {
+		return nil, errors.New("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+
+	p := new(PrecomputedPublicKey)
+	copy(p.publicKey[:], publicKey)
+	var A edwards25519.ExtendedGroupElement
+	if !A.FromBytes(&p.publicKey) {
+		return nil, ErrInvalidPublicKey
+	}
+	edwards25519.FeNeg(&A.X, &A.X)
+	edwards25519.FeNeg(&A.T, &A.T)
+	p.minusA.FromPoint(&A)
+	return p, nil
+}
+
+// PublicKey returns the public key of p.
+func (p *PrecomputedPublicKey) PublicKey() PublicKey {
+	publicKey := make([]byte, PublicKeySize)
+	copy(publicKey, p.publicKey[:])
+	return publicKey
+}
+
+// Verify reports whether sig is a valid signature of message by p, as
+// Verify does.
+func (p *PrecomputedPublicKey) Verify(message, sig []byte) bool {
+	if len(sig) != SignatureSize || sig[63]&224 != 0 {
+		return false
+	}
+
+	h := sha512.New()
+	h.Write(sig[:32])
+	h.Write(p.publicKey[:])
+	h.Write(message)
+	var digest [64]byte
+	h.Sum(digest[:0])
+	return p.verify(&digest, sig)
+}
+
+// Verify2 verifies a signature created with Sign2() by p, as Verify2 does.
+func (p *PrecomputedPublicKey) Verify2(message, sig []byte) bool {
+	if len(sig) != SignatureSize || sig[63]&224 != 0 {
+		return false
+	}
+
+	h := sha512.New()
+	h.Write(sig[:32])
+	// the public key is not part of the hash, as in Sign2
+	h.Write(message)
+	var digest [64]byte
+	h.Sum(digest[:0])
+	return p.verify(&digest, sig)
+}
+
+// verify checks that s·B - k·A encodes to R, where k is the digest reduced
+// mod l and sig is R || s.
+func (p *PrecomputedPublicKey) verify(digest *[64]byte, sig []byte) bool {
+	var kReduced [32]byte
+	edwards25519.ScReduce(&kReduced, digest)
+
+	var s [32]byte
+	copy(s[:], sig[32:])
+
+	// https://tools.ietf.org/html/rfc8032#section-5.1.7 requires that s be in
+	// the range [0, order) in order to prevent signature malleability.
+	if !edwards25519.ScMinimal(&s) {
+		return false
+	}
+
+	var R edwards25519.ProjectiveGroupElement
+	edwards25519.GeDoubleScalarMultPrecomputedVartime(&R, &kReduced, &p.minusA, &s)
+
+	var checkR [32]byte
+	R.ToBytes(&checkR)
+	return bytes.Equal(sig[:32], checkR[:])
+}
+
+// PublicKeyCache holds the PrecomputedPublicKey of up to a fixed number of
+// public keys, and evicts the least recently used one when it is full. It is
+// safe for concurrent use.
+type PublicKeyCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *PrecomputedPublicKey, most recently used first
+	keys  map[[PublicKeySize]byte]*list.Element
+}
+
+// NewPublicKeyCache returns a cache of up to size public keys. It will panic
+// if size is not positive.
+func NewPublicKeyCache(size int) *PublicKeyCache {
+	if size <= 0 {
+		panic("ed25519: bad public key cache size: " + strconv.Itoa(size))
+	}
+	return &PublicKeyCache{
+		size:  size,
+		order: list.New(),
+		keys:  make(map[[PublicKeySize]byte]*list.Element),
+	}
+}
+
+// Get returns the PrecomputedPublicKey of publicKey, computing and caching it
+// if it is not in the cache. Invalid keys are not cached.
+func (c *PublicKeyCache) Get(publicKey PublicKey) (*PrecomputedPublicKey, error) {
+	var key [PublicKeySize]byte
+	if len(publicKey) == PublicKeySize {
+		copy(key[:], publicKey)
+		c.mu.Lock()
+		e, ok := c.keys[key]
+		if ok {
+			c.order.MoveToFront(e)
+		}
+		c.mu.Unlock()
+		if ok {
+			return e.Value.(*PrecomputedPublicKey), nil
+		}
+	}
+
+	// compute the key without holding the lock
+	p, err := NewPrecomputedPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.keys[key]; ok {
+		// another caller added it meanwhile
+		c.order.MoveToFront(e)
+		return e.Value.(*PrecomputedPublicKey), nil
+	}
+	c.keys[key] = c.order.PushFront(p)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.keys, oldest.Value.(*PrecomputedPublicKey).publicKey)
+	}
+	return p, nil
+}
+
+// Len returns the number of public keys in the cache.
+func (c *PublicKeyCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Verify reports whether sig is a valid signature of message by publicKey,
+// as Verify does, with the cached PrecomputedPublicKey of publicKey. It will
+// panic if len(publicKey) is not PublicKeySize.
+func (c *PublicKeyCache) Verify(publicKey PublicKey, message, sig []byte) bool {
+	if l := len(publicKey); l != PublicKeySize {
+		panic("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+	p, err := c.Get(publicKey)
+	if err != nil {
+		return false
+	}
+	return p.Verify(message, sig)
+}
+
+// Verify2 verifies a signature created with Sign2() by publicKey, as Verify2
+// does, with the cached PrecomputedPublicKey of publicKey. It will panic if
+// len(publicKey) is not PublicKeySize.
+func (c *PublicKeyCache) Verify2(publicKey PublicKey, message, sig []byte) bool {
+	if l := len(publicKey); l != PublicKeySize {
+		panic("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+	p, err := c.Get(publicKey)
+	if err != nil {
+		return false
+	}
+	return p.Verify2(message, sig)
+}
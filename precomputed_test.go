@@ -0,0 +1,212 @@
+// Copyright 2019 Spacemesh Authors
+// ed25519 precomputed public key unit tests
+
+package ed25519
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// l, the order of the base point
+var scalarOrder = [32]byte{
+	0xed, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58, 0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+}
+
+// addOrder returns sig with l added to its s, which leaves the equation
+// valid but is rejected for malleability.
+func addOrder(sig []byte) []byte {
+	out := append([]byte{}, sig...)
+	var c uint16
+	for i := range scalarOrder {
+		c += uint16(out[32+i]) + uint16(scalarOrder[i])
+		out[32+i] = byte(c)
+		c >>= 8
+	}
+	return out
+}
+
+func TestPrecomputedPublicKey(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		public, private, err := GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		p, err := NewPrecomputedPublicKey(public)
+		require.NoError(t, err)
+		assert.Equal(t, public, p.PublicKey())
+
+		message := rnd32Bytes(t)[:]
+		wrongMessage := rnd32Bytes(t)[:]
+		sig, sig2 := Sign(private, message), Sign2(private, message)
+		assert.True(t, p.Verify(message, sig))
+		assert.True(t, p.Verify2(message, sig2))
+		assert.False(t, p.Verify(wrongMessage, sig))
+		assert.False(t, p.Verify2(wrongMessage, sig2))
+		assert.False(t, p.Verify(message, sig2))
+		assert.False(t, p.Verify2(message, sig))
+		assert.False(t, p.Verify(message, sig[:63]))
+		assert.False(t, p.Verify2(message, sig2[:63]))
+
+		// s + l is rejected when it fits in the signature
+		if s := addOrder(sig); s[63]&224 == 0 {
+			assert.False(t, p.Verify(message, s))
+			assert.False(t, Verify(public, message, s))
+		}
+
+		// any flipped bit agrees with Verify and Verify2
+		for _, bit := range []int{0, 100, 255, 256, 300, 503} {
+			bad := append([]byte{}, sig...)
+			bad[bit/8] ^= 1 << (bit % 8)
+			assert.Equal(t, Verify(public, message, bad), p.Verify(message, bad))
+			bad = append(bad[:0], sig2...)
+			bad[bit/8] ^= 1 << (bit % 8)
+			assert.Equal(t, Verify2(public, message, bad), p.Verify2(message, bad))
+		}
+	}
+}
+
+func TestPrecomputedPublicKeyEdgeCases(t *testing.T) {
+	// the identity as public key, with R the identity and s = 0, passes the
+	// cofactorless equation
+	identity := make([]byte, PublicKeySize)
+	identity[0] = 1
+	sig := make([]byte, SignatureSize)
+	sig[0] = 1
+	p, err := NewPrecomputedPublicKey(identity)
+	require.NoError(t, err)
+	message := []byte("test message")
+	assert.Equal(t, Verify(identity, message, sig), p.Verify(message, sig))
+	assert.Equal(t, Verify2(identity, message, sig), p.Verify2(message, sig))
+
+	// y = 2 is not on the curve
+	invalid := make([]byte, PublicKeySize)
+	invalid[0] = 2
+	_, err = NewPrecomputedPublicKey(invalid)
+	assert.Equal(t, ErrInvalidPublicKey, err)
+	assert.False(t, Verify(invalid, message, sig))
+
+	_, err = NewPrecomputedPublicKey(identity[:31])
+	assert.Error(t, err)
+}
+
+func TestPublicKeyCache(t *testing.T) {
+	var publics []PublicKey
+	var privates []PrivateKey
+	for i := 0; i < 3; i++ {
+		public, private, err := GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		publics = append(publics, public)
+		privates = append(privates, private)
+	}
+
+	c := NewPublicKeyCache(2)
+	p0, err := c.Get(publics[0])
+	require.NoError(t, err)
+	_, err = c.Get(publics[1])
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+
+	// the cached value is returned again
+	p, err := c.Get(publics[0])
+	require.NoError(t, err)
+	assert.Same(t, p0, p)
+
+	// publics[1] is the least recently used now and is evicted
+	_, err = c.Get(publics[2])
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+	p, err = c.Get(publics[0])
+	require.NoError(t, err)
+	assert.Same(t, p0, p)
+
+	// invalid keys are not cached
+	invalid := make([]byte, PublicKeySize)
+	invalid[0] = 2
+	_, err = c.Get(invalid)
+	assert.Equal(t, ErrInvalidPublicKey, err)
+	_, err = c.Get(invalid[:31])
+	assert.Error(t, err)
+	assert.Equal(t, 2, c.Len())
+
+	message := []byte("test message")
+	for i := range publics {
+		assert.True(t, c.Verify(publics[i], message, Sign(privates[i], message)))
+		assert.True(t, c.Verify2(publics[i], message, Sign2(privates[i], message)))
+		assert.False(t, c.Verify(publics[(i+1)%3], message, Sign(privates[i], message)))
+	}
+	assert.False(t, c.Verify(invalid, message, Sign(privates[0], message)))
+	assert.Panics(t, func() { c.Verify(invalid[:31], message, nil) })
+	assert.Panics(t, func() { NewPublicKeyCache(0) })
+}
+
+func TestPublicKeyCacheConcurrent(t *testing.T) {
+	public, private, err := GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	message := []byte("test message")
+	sig := Sign2(private, message)
+
+	c := NewPublicKeyCache(4)
+	var wg sync.WaitGroup
+	results := make([]bool, 16)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.Verify2(public, message, sig)
+		}(i)
+	}
+	wg.Wait()
+	for _, ok := range results {
+		assert.True(t, ok)
+	}
+	assert.Equal(t, 1, c.Len())
+}
+
+func BenchmarkVerificationPrecomputed(b *testing.B) {
+	var zero zeroReader
+	pub, priv, err := GenerateKey(zero)
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := NewPrecomputedPublicKey(pub)
+	if err != nil {
+		b.Fatal(err)
+	}
+	message := []byte("Hello, world!")
+	signature := Sign2(priv, message)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Verify2(message, signature)
+	}
+}
+
+func BenchmarkVerificationCached(b *testing.B) {
+	var zero zeroReader
+	pub, priv, err := GenerateKey(zero)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := NewPublicKeyCache(16)
+	message := []byte("Hello, world!")
+	signature := Sign2(priv, message)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Verify2(pub, message, signature)
+	}
+}
+
+func BenchmarkNewPrecomputedPublicKey(b *testing.B) {
+	var zero zeroReader
+	pub, _, err := GenerateKey(zero)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = NewPrecomputedPublicKey(pub)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 Spacemesh Authors
+// Public-key encryption to Ed25519 identities
+
+// Package sealedbox encrypts messages to the holder of an Ed25519 key.
+//
+// A box is sealed with a fresh ephemeral X25519 key and the recipient's
+// Ed25519 public key converted to curve25519 (see
+// ed25519.PublicKeyToCurve25519). The shared secret is expanded with
+// HKDF-SHA512 into an AES-256-GCM key. A box is encoded as
+//
+//	ephemeral public key (32) || nonce (12) || ciphertext || tag (16)
+//
+// Boxes made with Seal are anonymous: anyone can make them and the recipient
+// learns nothing about the sender. Boxes made with SealFrom also mix in the
+// X25519 secret shared by the sender's and the recipient's static keys, so
+// only the sender (or the recipient) could have made them; OpenFrom checks
+// this. This is not a signature: the recipient cannot prove to a third party
+// who made the box.
+package sealedbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/hkdf"
+)
+
+const (
+	// KeySize is the size, in bytes, of ephemeral public keys.
+	KeySize = ed25519.X25519Size
+	// NonceSize is the size, in bytes, of nonces.
+	NonceSize = 12
+	// TagSize is the size, in bytes, of authentication tags.
+	TagSize = 16
+	// Overhead is the number of bytes a box adds to its message.
+	Overhead = KeySize + NonceSize + TagSize
+
+	anonymousInfo     = "spacemesh-sealedbox-v1 anonymous"
+	authenticatedInfo = "spacemesh-sealedbox-v1 authenticated"
+)
+
+var (
+	// ErrOpen is returned when a box cannot be opened: it was sealed to a
+	// different key or by a different sender, or it was modified.
+	ErrOpen = errors.New("sealedbox: message authentication failed")
+	// ErrShort is returned for boxes shorter than Overhead.
+	ErrShort = errors.New("sealedbox: box too short")
+)
+
+// Seal encrypts message to recipient and returns the box, using entropy from
+// rand, crypto/rand.Reader if nil.
+func Seal(rand io.Reader, recipient ed25519.PublicKey, message []byte) ([]byte, error) {
+	return seal(rand, nil, recipient, message)
+}
+
+// SealFrom encrypts message from sender to recipient like Seal, so that
+// OpenFrom can check the sender.
+func SealFrom(rand io.Reader, sender ed25519.PrivateKey, recipient ed25519.PublicKey, message []byte) ([]byte, error) {
+	if l := len(sender); l != ed25519.PrivateKeySize {
+		return nil, errors.New("sealedbox: bad private key length: " + strconv.Itoa(l))
+	}
+	return seal(rand, sender, recipient, message)
+}
+
+// Open decrypts a box sealed to recipient with Seal.
+func Open(recipient ed25519.PrivateKey, box []byte) ([]byte, error) {
+	return open(recipient, nil, box)
+}
+
+// OpenFrom decrypts a box sealed to recipient with SealFrom and checks that
+// it was sealed by sender.
+func OpenFrom(recipient ed25519.PrivateKey, sender ed25519.PublicKey, box []byte) ([]byte, error) {
+	if l := len(sender); l != ed25519.PublicKeySize {
+		return nil, errors.New("sealedbox: bad public key length: " + strconv.Itoa(l))
+	}
+	return open(recipient, sender, box)
+}
+
+func seal(rand io.Reader, sender ed25519.PrivateKey, recipient ed25519.PublicKey, message []byte) ([]byte, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	recipientU, err := ed25519.PublicKeyToCurve25519(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	box := make([]byte, KeySize+NonceSize, Overhead+len(message))
+	ephemeral := make([]byte, ed25519.X25519Size)
+	if _, err := io.ReadFull(rand, ephemeral); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand, box[KeySize:]); err != nil {
+		return nil, err
+	}
+	ephemeralU, err := ed25519.X25519(ephemeral, ed25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	copy(box, ephemeralU)
+
+	secret, err := ed25519.X25519(ephemeral, recipientU)
+	if err != nil {
+		return nil, err
+	}
+	var senderPublic ed25519.PublicKey
+	if sender != nil {
+		static, err := ed25519.X25519(ed25519.PrivateKeyToCurve25519(sender), recipientU)
+		if err != nil {
+			return nil, err
+		}
+		secret = append(secret, static...)
+		senderPublic = sender.Public().(ed25519.PublicKey)
+	}
+
+	aead, err := newAEAD(secret, ephemeralU, recipientU, senderPublic, recipient)
+	if err != nil {
+		return nil, err
+	}
+	header := box[:KeySize+NonceSize]
+	return aead.Seal(box, box[KeySize:], message, header), nil
+}
+
+func open(recipient ed25519.PrivateKey, sender ed25519.PublicKey, box []byte) ([]byte, error) {
+	if l := len(recipient); l != ed25519.PrivateKeySize {
+		return nil, errors.New("sealedbox: bad private key length: " + strconv.Itoa(l))
+	}
+	if len(box) < Overhead {
+		return nil, ErrShort
+	}
+	ephemeralU, nonce := box[:KeySize], box[KeySize:KeySize+NonceSize]
+	scalar := ed25519.PrivateKeyToCurve25519(recipient)
+	recipientU, err := ed25519.X25519(scalar, ed25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ed25519.X25519(scalar, ephemeralU)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	if sender != nil {
+		senderU, err := ed25519.PublicKeyToCurve25519(sender)
+		if err != nil {
+			return nil, err
+		}
+		static, err := ed25519.X25519(scalar, senderU)
+		if err != nil {
+			return nil, ErrOpen
+		}
+		secret = append(secret, static...)
+	}
+
+	aead, err := newAEAD(secret, ephemeralU, recipientU, sender, recipient.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	header := box[:KeySize+NonceSize]
+	message, err := aead.Open(nil, nonce, box[KeySize+NonceSize:], header)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return message, nil
+}
+
+// newAEAD derives the box key from the X25519 secret and binds it to the
+// ephemeral and recipient curve25519 keys and to the Ed25519 identities.
+// sender is nil for anonymous boxes.
+func newAEAD(secret, ephemeralU, recipientU []byte, sender, recipient ed25519.PublicKey) (cipher.AEAD, error) {
+	salt := append(append([]byte(nil), ephemeralU...), recipientU...)
+	info := []byte(anonymousInfo)
+	if sender != nil {
+		info = append([]byte(authenticatedInfo), sender...)
+	}
+	info = append(info, recipient...)
+
+	key, err := hkdf.Key(sha512.New, secret, salt, info, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 Spacemesh Authors
+// Sealed box unit tests
+
+package sealedbox
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return pub, priv
+}
+
+func TestSealOpen(t *testing.T) {
+	pub, priv := newKey(t)
+	for _, message := range [][]byte{nil, []byte("hello, world"), bytes.Repeat([]byte{0xaa}, 4096)} {
+		box, err := Seal(nil, pub, message)
+		require.NoError(t, err)
+		assert.Len(t, box, len(message)+Overhead)
+
+		opened, err := Open(priv, box)
+		require.NoError(t, err)
+		assert.Equal(t, len(message), len(opened))
+		assert.True(t, bytes.Equal(message, opened))
+	}
+}
+
+func TestSealFromOpenFrom(t *testing.T) {
+	senderPub, sender := newKey(t)
+	recipientPub, recipient := newKey(t)
+	otherPub, _ := newKey(t)
+	message := []byte("hello, world")
+
+	box, err := SealFrom(nil, sender, recipientPub, message)
+	require.NoError(t, err)
+	opened, err := OpenFrom(recipient, senderPub, box)
+	require.NoError(t, err)
+	assert.Equal(t, message, opened)
+
+	// the claimed sender must match
+	_, err = OpenFrom(recipient, otherPub, box)
+	assert.Equal(t, ErrOpen, err)
+
+	// authenticated and anonymous boxes are not interchangeable
+	_, err = Open(recipient, box)
+	assert.Equal(t, ErrOpen, err)
+	anonymous, err := Seal(nil, recipientPub, message)
+	require.NoError(t, err)
+	_, err = OpenFrom(recipient, senderPub, anonymous)
+	assert.Equal(t, ErrOpen, err)
+}
+
+func TestWrongRecipient(t *testing.T) {
+	pub, _ := newKey(t)
+	_, other := newKey(t)
+	box, err := Seal(nil, pub, []byte("hello, world"))
+	require.NoError(t, err)
+	_, err = Open(other, box)
+	assert.Equal(t, ErrOpen, err)
+}
+
+func TestTampering(t *testing.T) {
+	pub, priv := newKey(t)
+	box, err := Seal(nil, pub, []byte("hello, world"))
+	require.NoError(t, err)
+
+	// flipping any bit of the ephemeral key, nonce, ciphertext or tag
+	for i := range box {
+		for bit := 0; bit < 8; bit++ {
+			tampered := append([]byte(nil), box...)
+			tampered[i] ^= 1 << uint(bit)
+			_, err := Open(priv, tampered)
+			assert.Error(t, err, "byte %d bit %d", i, bit)
+		}
+	}
+
+	_, err = Open(priv, box[:len(box)-1])
+	assert.Equal(t, ErrOpen, err)
+	_, err = Open(priv, append(box, 0))
+	assert.Equal(t, ErrOpen, err)
+	_, err = Open(priv, box[:Overhead-1])
+	assert.Equal(t, ErrShort, err)
+}
+
+// countingReader yields the bytes 0, 1, 2, ... so that the randomness used by
+// Seal can be told apart in its output.
+type countingReader struct{ n byte }
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.n
+		r.n++
+	}
+	return len(p), nil
+}
+
+func TestEncoding(t *testing.T) {
+	pub, priv := newKey(t)
+	message := []byte("hello, world")
+	box, err := Seal(&countingReader{}, pub, message)
+	require.NoError(t, err)
+
+	// the ephemeral scalar is read first, then the nonce
+	var scalar [KeySize]byte
+	for i := range scalar {
+		scalar[i] = byte(i)
+	}
+	ephemeral, err := ed25519.X25519(scalar[:], ed25519.Basepoint)
+	require.NoError(t, err)
+	assert.Equal(t, ephemeral, box[:KeySize])
+	for i := 0; i < NonceSize; i++ {
+		assert.Equal(t, byte(KeySize+i), box[KeySize+i])
+	}
+	assert.Len(t, box[KeySize+NonceSize:], len(message)+TagSize)
+
+	opened, err := Open(priv, box)
+	require.NoError(t, err)
+	assert.Equal(t, message, opened)
+
+	// the same randomness gives the same box
+	again, err := Seal(&countingReader{}, pub, message)
+	require.NoError(t, err)
+	assert.Equal(t, box, again)
+}
+
+func TestLowOrderEphemeral(t *testing.T) {
+	_, priv := newKey(t)
+	box := make([]byte, Overhead+1) // all-zero ephemeral key, u = 0
+	_, err := Open(priv, box)
+	assert.Equal(t, ErrOpen, err)
+}
+
+func TestBadKeys(t *testing.T) {
+	pub, priv := newKey(t)
+	_, err := Seal(nil, pub[:31], nil)
+	assert.Error(t, err)
+	_, err = SealFrom(nil, priv[:63], pub, nil)
+	assert.Error(t, err)
+	_, err = Open(priv[:63], make([]byte, Overhead))
+	assert.Error(t, err)
+	_, err = OpenFrom(priv, pub[:31], make([]byte, Overhead))
+	assert.Error(t, err)
+
+	// y = 2 is not on the curve
+	invalid := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	invalid[0] = 2
+	_, err = Seal(nil, invalid, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 Spacemesh Authors
+// remote signer client
+
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+var (
+	// ErrUnknownKey is returned when the server has no key of the given name.
+	ErrUnknownKey = errors.New("signer: unknown key")
+	// ErrNotAllowed is returned when a key may not perform an operation.
+	ErrNotAllowed = errors.New("signer: operation not allowed")
+	// ErrUnauthorized is returned when the server requires a token the
+	// client does not send.
+	ErrUnauthorized = errors.New("signer: missing or wrong token")
+)
+
+// Client is a client of a signing Server. It is safe for concurrent use.
+type Client struct {
+	base  string
+	http  *http.Client
+	token string
+}
+
+// NewClient returns a client of the server at addr, in the syntax of Listen.
+func NewClient(addr string) (*Client, error) {
+	network, address, err := splitAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+	base := "http://" + address
+	if network == "unix" {
+		base = "http://signer"
+	}
+	return &Client{base: base, http: &http.Client{Transport: transport}}, nil
+}
+
+// SetToken makes c send token to a server that requires it with
+// Server.RequireToken. It must be called before c is used.
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRequestSize))
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("signer: malformed response: %w", err)
+		}
+		return nil
+	case http.StatusNotFound:
+		return ErrUnknownKey
+	case http.StatusForbidden:
+		return ErrNotAllowed
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	default:
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return fmt.Errorf("signer: %s", e.Error)
+		}
+		return fmt.Errorf("signer: unexpected status %s", resp.Status)
+	}
+}
+
+// List returns the keys served by the server.
+func (c *Client) List(ctx context.Context) ([]KeyInfo, error) {
+	var resp listResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/list", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// PublicKey returns the public key of the named key.
+func (c *Client) PublicKey(ctx context.Context, name string) (ed25519.PublicKey, error) {
+	var info KeyInfo
+	if err := c.do(ctx, http.MethodGet, "/v1/pubkey?key="+url.QueryEscape(name), nil, &info); err != nil {
+		return nil, err
+	}
+	if len(info.PublicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("signer: malformed public key")
+	}
+	return info.PublicKey, nil
+}
+
+func (c *Client) sign(ctx context.Context, path, name string, message []byte) ([]byte, error) {
+	var resp signResponse
+	if err := c.do(ctx, http.MethodPost, path, signRequest{Key: name, Message: message}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Signature) != ed25519.SignatureSize {
+		return nil, errors.New("signer: malformed signature")
+	}
+	return resp.Signature, nil
+}
+
+// Sign signs message with the named key using ed25519.Sign.
+func (c *Client) Sign(ctx context.Context, name string, message []byte) ([]byte, error) {
+	return c.sign(ctx, "/v1/sign", name, message)
+}
+
+// Sign2 signs message with the named key using ed25519.Sign2.
+func (c *Client) Sign2(ctx context.Context, name string, message []byte) ([]byte, error) {
+	return c.sign(ctx, "/v1/sign2", name, message)
+}
+
+// Key returns a RemoteKey for the named key, fetching its public key.
+func (c *Client) Key(ctx context.Context, name string) (*RemoteKey, error) {
+	publicKey, err := c.PublicKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteKey{client: c, name: name, publicKey: publicKey}, nil
+}
+
+// RemoteKey is a key held by a signing server. It implements crypto.Signer
+// like ed25519.PrivateKey.
+type RemoteKey struct {
+	client    *Client
+	name      string
+	publicKey ed25519.PublicKey
+}
+
+// Name returns the name of the key on the server.
+func (k *RemoteKey) Name() string {
+	return k.name
+}
+
+// Public returns the ed25519.PublicKey of the key.
+func (k *RemoteKey) Public() crypto.PublicKey {
+	return k.publicKey
+}
+
+// Sign signs message with ed25519.Sign on the server. As with
+// ed25519.PrivateKey, rand is ignored and opts.HashFunc() must be zero.
+func (k *RemoteKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("signer: cannot sign hashed message")
+	}
+	return k.client.Sign(context.Background(), k.name, message)
+}
+
+// Sign2 signs message with ed25519.Sign2 on the server.
+func (k *RemoteKey) Sign2(message []byte) ([]byte, error) {
+	return k.client.Sign2(context.Background(), k.name, message)
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 Spacemesh Authors
+// remote signer key configuration
+
+package signer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+// KeyConfig configures one key of a server. The key is given by a hex seed or
+// a file holding the seed as raw bytes or hex; it is derived from the seed
+// with ed25519.NewDerivedKeyFromSeed when Index or Salt is set.
+type KeyConfig struct {
+	Name     string      `json:"name"`
+	Seed     string      `json:"seed,omitempty"`
+	SeedFile string      `json:"seed_file,omitempty"`
+	Index    *uint64     `json:"index,omitempty"`
+	Salt     string      `json:"salt,omitempty"`
+	Allow    []Operation `json:"allow"`
+}
+
+// Config is the configuration file of a server:
+//
+//	{"token_file": "signer.token",
+//	 "keys": [
+//		{"name": "node", "seed_file": "node.seed", "allow": ["sign2"]},
+//		{"name": "tx-3", "seed": "9d61...7f60", "index": 3, "salt": "74780a", "allow": ["sign", "sign2"]}
+//	]}
+//
+// The optional token file holds the bearer token clients must send, and must
+// not be accessible to other users.
+type Config struct {
+	TokenFile string      `json:"token_file,omitempty"`
+	Keys      []KeyConfig `json:"keys"`
+}
+
+// LoadConfig reads a JSON configuration file and returns its keys, and the
+// token for Server.RequireToken, which is empty if the configuration has no
+// token file. Relative file paths are resolved against the directory of the
+// configuration.
+func LoadConfig(path string) (keys []Key, token string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, "", fmt.Errorf("signer: %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	if cfg.TokenFile != "" {
+		if token, err = readToken(resolvePath(dir, cfg.TokenFile)); err != nil {
+			return nil, "", err
+		}
+	}
+	keys = make([]Key, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		k, err := kc.key(dir)
+		if err != nil {
+			return nil, "", err
+		}
+		keys = append(keys, k)
+	}
+	return keys, token, nil
+}
+
+// resolvePath resolves path against dir unless it is absolute.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// readToken reads a token file, which must not be accessible to other users.
+func readToken(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("signer: token file: %w", err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("signer: token file %s is accessible to other users", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("signer: token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("signer: token file %s is empty", path)
+	}
+	return token, nil
+}
+
+func (kc *KeyConfig) key(dir string) (Key, error) {
+	var seed []byte
+	switch {
+	case kc.Seed != "" && kc.SeedFile != "":
+		return Key{}, fmt.Errorf("signer: key %q: both seed and seed_file set", kc.Name)
+	case kc.Seed != "":
+		var err error
+		if seed, err = hex.DecodeString(kc.Seed); err != nil {
+			return Key{}, fmt.Errorf("signer: key %q: seed: %w", kc.Name, err)
+		}
+	case kc.SeedFile != "":
+		data, err := os.ReadFile(resolvePath(dir, kc.SeedFile))
+		if err != nil {
+			return Key{}, fmt.Errorf("signer: key %q: %w", kc.Name, err)
+		}
+		seed = data
+		if len(data) != ed25519.SeedSize {
+			if seed, err = hex.DecodeString(strings.TrimSpace(string(data))); err != nil {
+				return Key{}, fmt.Errorf("signer: key %q: seed file: %w", kc.Name, err)
+			}
+		}
+	default:
+		return Key{}, fmt.Errorf("signer: key %q: no seed", kc.Name)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return Key{}, fmt.Errorf("signer: key %q: seed is %d bytes, want %d", kc.Name, len(seed), ed25519.SeedSize)
+	}
+
+	salt, err := hex.DecodeString(kc.Salt)
+	if err != nil {
+		return Key{}, fmt.Errorf("signer: key %q: salt: %w", kc.Name, err)
+	}
+	k := Key{Name: kc.Name, Allow: kc.Allow}
+	if kc.Index != nil || kc.Salt != "" {
+		var index uint64
+		if kc.Index != nil {
+			index = *kc.Index
+		}
+		k.PrivateKey = ed25519.NewDerivedKeyFromSeed(seed, index, salt)
+	} else {
+		k.PrivateKey = ed25519.NewKeyFromSeed(seed)
+	}
+	return k, nil
+}
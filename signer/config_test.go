@@ -0,0 +1,81 @@
+package signer
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+const testSeed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+
+func writeConfig(t *testing.T, dir, config string) string {
+	path := filepath.Join(dir, "signer.json")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node.seed"), []byte(testSeed+"\n"), 0o600))
+	path := writeConfig(t, dir, `{"keys": [
+		{"name": "node", "seed_file": "node.seed", "allow": ["sign2"]},
+		{"name": "tx-3", "seed": "`+testSeed+`", "index": 3, "salt": "74780a", "allow": ["sign", "sign2"]}
+	]}`)
+
+	keys, token, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Empty(t, token)
+
+	seed, err := hex.DecodeString(testSeed)
+	require.NoError(t, err)
+	require.Equal(t, "node", keys[0].Name)
+	require.Equal(t, ed25519.NewKeyFromSeed(seed), keys[0].PrivateKey)
+	require.Equal(t, []Operation{OpSign2}, keys[0].Allow)
+	require.Equal(t, ed25519.NewDerivedKeyFromSeed(seed, 3, []byte("tx\n")), keys[1].PrivateKey)
+	require.Equal(t, []Operation{OpSign, OpSign2}, keys[1].Allow)
+
+	_, err = NewServer(keys...)
+	require.NoError(t, err)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		`{"keys": [{"name": "a"}]}`,
+		`{"keys": [{"name": "a", "seed": "00"}]}`,
+		`{"keys": [{"name": "a", "seed": "zz"}]}`,
+		`{"keys": [{"name": "a", "seed": "` + testSeed + `", "seed_file": "x"}]}`,
+		`{"keys": [{"name": "a", "seed": "` + testSeed + `", "salt": "xyz"}]}`,
+		`{"keys": [{"name": "a", "seed_file": "missing"}]}`,
+		`{"keys": [{"name": "a", "seed": "` + testSeed + `", "unknown": 1}]}`,
+	} {
+		_, _, err := LoadConfig(writeConfig(t, t.TempDir(), config))
+		require.Error(t, err, config)
+	}
+}
+
+func TestLoadConfigToken(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "signer.token"), []byte("s3cret\n"), 0o600))
+	path := writeConfig(t, dir, `{"token_file": "signer.token", "keys": [{"name": "a", "seed": "`+testSeed+`"}]}`)
+	_, token, err := LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", token)
+
+	// a token other users can read protects nothing
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Chmod(filepath.Join(dir, "signer.token"), 0o640))
+		_, _, err = LoadConfig(path)
+		require.Error(t, err)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.token"), []byte("\n"), 0o600))
+	_, _, err = LoadConfig(writeConfig(t, dir, `{"token_file": "empty.token", "keys": []}`))
+	require.Error(t, err)
+	_, _, err = LoadConfig(writeConfig(t, dir, `{"token_file": "missing.token", "keys": []}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,344 @@
+// Copyright 2019 Spacemesh Authors
+// remote signer HTTP/JSON server
+
+// Package signer implements a remote signing service that keeps Ed25519 keys
+// out of the process using them.
+//
+// A Server holds named keys and serves a small HTTP/JSON API, usually on a
+// Unix socket or a loopback address:
+//
+//	GET  /v1/list                  names, public keys and allowed operations
+//	GET  /v1/pubkey?key=<name>     public key of one key
+//	POST /v1/sign                  {"key": name, "message": base64} -> {"signature": base64}
+//	POST /v1/sign2                 same, signing with ed25519.Sign2
+//
+// Each key only performs the operations it is allowed. Over TCP, requests
+// must name the loopback address and port they reach in their Host header,
+// and POST bodies must be application/json, so that web pages cannot use the
+// API through DNS rebinding or cross-site requests. A Client talks to the
+// API, and its RemoteKey implements crypto.Signer so it can stand in for a
+// local ed25519.PrivateKey.
+//
+// Those checks only stop browsers. Access to a Unix socket is limited to
+// its owner, but a loopback TCP port is open to every local user and
+// process, so that without a token, any of them can sign with every key.
+// Server.RequireToken makes the server require a shared secret in an
+// "Authorization: Bearer" header, which Client.SetToken sends.
+package signer
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+// Operation is a signing operation a key may be allowed to perform.
+type Operation string
+
+const (
+	// OpSign signs with ed25519.Sign.
+	OpSign Operation = "sign"
+	// OpSign2 signs with ed25519.Sign2.
+	OpSign2 Operation = "sign2"
+)
+
+// maxRequestSize bounds the size of request bodies.
+const maxRequestSize = 1 << 20
+
+// Key is a named private key and the operations it may perform.
+type Key struct {
+	Name       string
+	PrivateKey ed25519.PrivateKey
+	Allow      []Operation
+}
+
+// KeyInfo describes a key served by a Server.
+type KeyInfo struct {
+	Name      string            `json:"name"`
+	PublicKey ed25519.PublicKey `json:"public_key"`
+	Allow     []Operation       `json:"allow"`
+}
+
+type signRequest struct {
+	Key     string `json:"key"`
+	Message []byte `json:"message"`
+}
+
+type signResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+type listResponse struct {
+	Keys []KeyInfo `json:"keys"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves signing requests for a fixed set of keys. It implements
+// http.Handler and is safe for concurrent use.
+type Server struct {
+	keys  map[string]*Key
+	names []string
+	mux   *http.ServeMux
+	token string
+}
+
+// NewServer returns a server for keys. Key names must be unique and
+// non-empty, private keys well formed and operations known.
+func NewServer(keys ...Key) (*Server, error) {
+	s := &Server{keys: make(map[string]*Key), mux: http.NewServeMux()}
+	for i := range keys {
+		k := keys[i]
+		if k.Name == "" {
+			return nil, errors.New("signer: empty key name")
+		}
+		if _, dup := s.keys[k.Name]; dup {
+			return nil, fmt.Errorf("signer: duplicate key name %q", k.Name)
+		}
+		if len(k.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("signer: key %q: bad private key length", k.Name)
+		}
+		for _, op := range k.Allow {
+			if op != OpSign && op != OpSign2 {
+				return nil, fmt.Errorf("signer: key %q: unknown operation %q", k.Name, op)
+			}
+		}
+		s.keys[k.Name] = &k
+		s.names = append(s.names, k.Name)
+	}
+	sort.Strings(s.names)
+
+	s.mux.HandleFunc("/v1/list", s.handleList)
+	s.mux.HandleFunc("/v1/pubkey", s.handlePubkey)
+	s.mux.HandleFunc("/v1/sign", s.handleSign(OpSign, ed25519.Sign))
+	s.mux.HandleFunc("/v1/sign2", s.handleSign(OpSign2, ed25519.Sign2))
+	return s, nil
+}
+
+// RequireToken makes s accept only requests that carry token in an
+// "Authorization: Bearer" header, over TCP and Unix sockets alike. It must
+// be called before s serves requests. An empty token requires none.
+func (s *Server) RequireToken(token string) {
+	s.token = token
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkHost(r) {
+		writeError(w, http.StatusForbidden, "bad host")
+		return
+	}
+	if s.token != "" && !checkToken(r, s.token) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "missing or wrong token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// checkToken reports whether r carries token as its bearer token.
+func checkToken(r *http.Request, token string) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(token)) == 1
+}
+
+// checkHost reports whether the Host header of r names a loopback address
+// and, if known, the port of the TCP connection. Requests over Unix sockets,
+// which browsers cannot make, are accepted whatever their Host.
+func checkHost(r *http.Request) bool {
+	local, _ := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if _, ok := local.(*net.UnixAddr); ok {
+		return true
+	}
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		return false
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return false
+		}
+	}
+	if tcp, ok := local.(*net.TCPAddr); ok && port != strconv.Itoa(tcp.Port) {
+		return false
+	}
+	return true
+}
+
+func (k *Key) info() KeyInfo {
+	allow := k.Allow
+	if allow == nil {
+		allow = []Operation{}
+	}
+	return KeyInfo{Name: k.Name, PublicKey: ed25519.PublicKey(k.PrivateKey[32:]), Allow: allow}
+}
+
+func (k *Key) allows(op Operation) bool {
+	for _, a := range k.Allow {
+		if a == op {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	resp := listResponse{Keys: make([]KeyInfo, 0, len(s.names))}
+	for _, name := range s.names {
+		resp.Keys = append(resp.Keys, s.keys[name].info())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handlePubkey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	k, ok := s.keys[r.URL.Query().Get("key")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown key")
+		return
+	}
+	writeJSON(w, http.StatusOK, k.info())
+}
+
+func (s *Server) handleSign(op Operation, sign func(ed25519.PrivateKey, []byte) []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+			return
+		}
+		var req signRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "malformed request")
+			return
+		}
+		k, ok := s.keys[req.Key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown key")
+			return
+		}
+		if !k.allows(op) {
+			writeError(w, http.StatusForbidden, "operation not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, signResponse{Signature: sign(k.PrivateKey, req.Message)})
+	}
+}
+
+// Listen listens on addr, which is either "unix:<path>" or a "host:port"
+// TCP address whose host is a loopback address or localhost. Unix sockets are
+// accessible to their owner only from the moment they appear at path.
+func Listen(addr string) (net.Listener, error) {
+	network, address, err := splitAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		return listenUnix(address)
+	}
+	return net.Listen(network, address)
+}
+
+// listenUnix creates the socket in a new directory accessible to its owner
+// only, restricts it, and then links it at path, so that it is never
+// reachable with the permissions of the umask.
+func listenUnix(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".signer-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	// unlike a rename, a link does not replace an existing file
+	if err := os.Link(tmp, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: l, addr: &net.UnixAddr{Name: path, Net: "unix"}}, nil
+}
+
+// unixListener removes its socket when first closed.
+type unixListener struct {
+	*net.UnixListener
+	addr   *net.UnixAddr
+	remove sync.Once
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.remove.Do(func() { os.Remove(l.addr.Name) })
+	return err
+}
+
+// splitAddr splits a listen or dial address into its network and address.
+func splitAddr(addr string) (network, address string, err error) {
+	if strings.HasPrefix(addr, "unix:") {
+		if len(addr) == len("unix:") {
+			return "", "", errors.New("signer: empty socket path")
+		}
+		return "unix", addr[len("unix:"):], nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", fmt.Errorf("signer: %w", err)
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return "", "", fmt.Errorf("signer: %s is not a loopback address", host)
+		}
+	}
+	return "tcp", addr, nil
+}
@@ -0,0 +1,294 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeys() []Key {
+	seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	return []Key{
+		{Name: "node", PrivateKey: ed25519.NewKeyFromSeed(seed), Allow: []Operation{OpSign, OpSign2}},
+		{Name: "derived", PrivateKey: ed25519.NewDerivedKeyFromSeed(seed, 1, []byte("salt")), Allow: []Operation{OpSign2}},
+		{Name: "locked", PrivateKey: ed25519.NewKeyFromSeed(bytes.Repeat([]byte{9}, ed25519.SeedSize))},
+	}
+}
+
+// startServer serves testKeys on a Unix socket and returns a client.
+func startServer(t *testing.T) *Client {
+	s, err := NewServer(testKeys()...)
+	require.NoError(t, err)
+	addr := "unix:" + filepath.Join(t.TempDir(), "signer.sock")
+	l, err := Listen(addr)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: s}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	c, err := NewClient(addr)
+	require.NoError(t, err)
+	return c
+}
+
+func TestSignAndSign2(t *testing.T) {
+	c := startServer(t)
+	ctx := context.Background()
+	keys := testKeys()
+	message := []byte("test message")
+
+	sig, err := c.Sign(ctx, "node", message)
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify(keys[0].PrivateKey.Public().(ed25519.PublicKey), message, sig))
+
+	sig2, err := c.Sign2(ctx, "derived", message)
+	require.NoError(t, err)
+	pub := keys[1].PrivateKey.Public().(ed25519.PublicKey)
+	require.True(t, ed25519.Verify2(pub, message, sig2))
+	extracted, err := ed25519.ExtractPublicKey(message, sig2)
+	require.NoError(t, err)
+	require.Equal(t, pub, extracted)
+}
+
+func TestAllowlist(t *testing.T) {
+	c := startServer(t)
+	ctx := context.Background()
+
+	_, err := c.Sign(ctx, "derived", []byte("m"))
+	require.ErrorIs(t, err, ErrNotAllowed)
+	_, err = c.Sign2(ctx, "locked", []byte("m"))
+	require.ErrorIs(t, err, ErrNotAllowed)
+	_, err = c.Sign(ctx, "missing", []byte("m"))
+	require.ErrorIs(t, err, ErrUnknownKey)
+	_, err = c.PublicKey(ctx, "missing")
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestList(t *testing.T) {
+	c := startServer(t)
+	infos, err := c.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, infos, 3)
+
+	byName := make(map[string]KeyInfo)
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+	for _, k := range testKeys() {
+		info := byName[k.Name]
+		require.Equal(t, k.PrivateKey.Public(), info.PublicKey)
+		if k.Allow == nil {
+			require.Empty(t, info.Allow)
+		} else {
+			require.Equal(t, k.Allow, info.Allow)
+		}
+	}
+}
+
+func TestRemoteKey(t *testing.T) {
+	c := startServer(t)
+	k, err := c.Key(context.Background(), "node")
+	require.NoError(t, err)
+	local := testKeys()[0].PrivateKey
+	require.Equal(t, local.Public(), k.Public())
+
+	var signer crypto.Signer = k
+	message := []byte("crypto.Signer message")
+	sig, err := signer.Sign(nil, message, crypto.Hash(0))
+	require.NoError(t, err)
+	want, err := local.Sign(nil, message, crypto.Hash(0))
+	require.NoError(t, err)
+	require.Equal(t, want, sig)
+
+	_, err = signer.Sign(nil, message, crypto.SHA256)
+	require.Error(t, err)
+
+	sig2, err := k.Sign2(message)
+	require.NoError(t, err)
+	require.Equal(t, ed25519.Sign2(local, message), sig2)
+}
+
+func TestLoopbackTCP(t *testing.T) {
+	s, err := NewServer(testKeys()...)
+	require.NoError(t, err)
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: s}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	c, err := NewClient(l.Addr().String())
+	require.NoError(t, err)
+	_, err = c.Sign2(context.Background(), "node", []byte("m"))
+	require.NoError(t, err)
+}
+
+func TestAddresses(t *testing.T) {
+	for _, addr := range []string{"localhost:1", "127.0.0.1:1", "[::1]:1", "unix:/tmp/s"} {
+		_, _, err := splitAddr(addr)
+		require.NoError(t, err, addr)
+	}
+	for _, addr := range []string{"0.0.0.0:1", "10.0.0.1:1", "example.com:1", "unix:", "127.0.0.1"} {
+		_, _, err := splitAddr(addr)
+		require.Error(t, err, addr)
+	}
+}
+
+func TestNewServerErrors(t *testing.T) {
+	priv := testKeys()[0].PrivateKey
+	_, err := NewServer(Key{Name: "", PrivateKey: priv})
+	require.Error(t, err)
+	_, err = NewServer(Key{Name: "a", PrivateKey: priv}, Key{Name: "a", PrivateKey: priv})
+	require.Error(t, err)
+	_, err = NewServer(Key{Name: "a", PrivateKey: priv[:32]})
+	require.Error(t, err)
+	_, err = NewServer(Key{Name: "a", PrivateKey: priv, Allow: []Operation{"encrypt"}})
+	require.Error(t, err)
+}
+
+func TestMalformedRequests(t *testing.T) {
+	s, err := NewServer(testKeys()...)
+	require.NoError(t, err)
+	const sign2 = `{"key":"node","message":"aGk="}`
+	for _, tc := range []struct {
+		method, path, body string
+		host, contentType  string
+		status             int
+	}{
+		{http.MethodGet, "/v1/sign", "", "", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/v1/list", "", "", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/v1/sign", "{", "", "", http.StatusBadRequest},
+		{http.MethodPost, "/v1/sign", `{"key":"node","message":"bm90IGJhc2U2NA","extra":1}`, "", "", http.StatusBadRequest},
+		{http.MethodPost, "/v1/sign2", `{"key":"node","message":"!"}`, "", "", http.StatusBadRequest},
+		{http.MethodPost, "/v1/sign2", sign2, "", "", http.StatusOK},
+		{http.MethodPost, "/v1/sign2", sign2, "", "application/json; charset=utf-8", http.StatusOK},
+		{http.MethodPost, "/v1/sign2", sign2, "[::1]:8080", "", http.StatusOK},
+		// what a cross-site form or no-cors fetch can send
+		{http.MethodPost, "/v1/sign2", sign2, "", "text/plain", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/v1/sign2", sign2, "", "-", http.StatusUnsupportedMediaType},
+		// a DNS rebinding page names its own host
+		{http.MethodPost, "/v1/sign2", sign2, "attacker.example:8080", "", http.StatusForbidden},
+		{http.MethodGet, "/v1/list", "", "localhost", "", http.StatusForbidden},
+		{http.MethodGet, "/v1/list", "", "10.0.0.1:8080", "", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		require.NoError(t, err)
+		req.Host = "localhost:8080"
+		if tc.host != "" {
+			req.Host = tc.host
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if tc.contentType == "-" {
+			req.Header.Del("Content-Type")
+		} else if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		require.Equal(t, tc.status, rec.Code, "%s %s %s %s", tc.method, tc.path, tc.host, tc.body)
+	}
+}
+
+func TestLoopbackHostPort(t *testing.T) {
+	s, err := NewServer(testKeys()...)
+	require.NoError(t, err)
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: s}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	// the Host must name the port the request reached
+	port := l.Addr().(*net.TCPAddr).Port
+	for host, status := range map[string]int{
+		"127.0.0.1:" + strconv.Itoa(port):      http.StatusOK,
+		"localhost:" + strconv.Itoa(port):      http.StatusOK,
+		"127.0.0.1:" + strconv.Itoa(port+1):    http.StatusForbidden,
+		"rebind.example:" + strconv.Itoa(port): http.StatusForbidden,
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://"+l.Addr().String()+"/v1/list", nil)
+		require.NoError(t, err)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode, host)
+	}
+}
+
+func TestToken(t *testing.T) {
+	s, err := NewServer(testKeys()...)
+	require.NoError(t, err)
+	s.RequireToken("s3cret")
+	l, err := Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{Handler: s}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c, err := NewClient(l.Addr().String())
+	require.NoError(t, err)
+	_, err = c.List(ctx)
+	require.Equal(t, ErrUnauthorized, err)
+	_, err = c.Sign2(ctx, "node", []byte("hi"))
+	require.Equal(t, ErrUnauthorized, err)
+	c.SetToken("wrong")
+	_, err = c.Sign2(ctx, "node", []byte("hi"))
+	require.Equal(t, ErrUnauthorized, err)
+
+	c.SetToken("s3cret")
+	sig, err := c.Sign2(ctx, "node", []byte("hi"))
+	require.NoError(t, err)
+	require.True(t, ed25519.Verify2(testKeys()[0].PrivateKey.Public().(ed25519.PublicKey), []byte("hi"), sig))
+
+	for _, auth := range []string{"", "s3cret", "Basic s3cret", "Bearer s3cre", "Bearer s3cret "} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/list", nil)
+		req.Host = "localhost:8080"
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code, auth)
+		require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestUnixSocketPermissions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "signer.sock")
+	l, err := Listen("unix:" + path)
+	require.NoError(t, err)
+	require.Equal(t, path, l.Addr().String())
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.ModeSocket, fi.Mode().Type())
+	if runtime.GOOS != "windows" {
+		require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	}
+	// the private directory the socket was created in is gone
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// an existing socket is not replaced
+	_, err = Listen("unix:" + path)
+	require.Error(t, err)
+
+	require.NoError(t, l.Close())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,323 @@
+// Copyright 2019 Spacemesh Authors
+// OpenBSD signify key and signature files
+
+// Package signify reads and writes the key and signature files of OpenBSD's
+// signify(1) and makes and checks detached signatures with ed25519.Sign and
+// ed25519.Verify.
+//
+// Each file is an "untrusted comment:" line followed by a base64 line holding
+// the "Ed" algorithm tag, an 8-byte key number and the key or signature.
+// Secret keys protected by a passphrase (bcrypt_pbkdf) are not supported;
+// create keys for use with this package with signify -G -n.
+package signify
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spacemeshos/ed25519"
+)
+
+const (
+	commentPrefix = "untrusted comment: "
+	// maxCommentLength is signify's limit on comment lengths.
+	maxCommentLength = 1024
+
+	pkAlg  = "Ed"
+	kdfAlg = "BK"
+
+	// KeyNumSize is the size, in bytes, of key numbers.
+	KeyNumSize = 8
+
+	publicKeySize  = 2 + KeyNumSize + ed25519.PublicKeySize
+	privateKeySize = 2 + 2 + 4 + 16 + 8 + KeyNumSize + ed25519.PrivateKeySize
+	signatureSize  = 2 + KeyNumSize + ed25519.SignatureSize
+)
+
+var (
+	// ErrMalformed is returned for files that cannot be parsed.
+	ErrMalformed = errors.New("signify: malformed file")
+	// ErrEncrypted is returned for passphrase protected secret keys.
+	ErrEncrypted = errors.New("signify: encrypted secret keys are not supported")
+	// ErrChecksum is returned when a secret key fails its checksum.
+	ErrChecksum = errors.New("signify: incorrect secret key checksum")
+	// ErrKeyMismatch is returned when a signature was made by another key,
+	// as told by its key number.
+	ErrKeyMismatch = errors.New("signify: signature made by a different key")
+	// ErrInvalidSignature is returned when a signature does not verify.
+	ErrInvalidSignature = errors.New("signify: invalid signature")
+)
+
+// PublicKey is a signify public key.
+type PublicKey struct {
+	KeyNum  [KeyNumSize]byte
+	Key     ed25519.PublicKey
+	Comment string
+}
+
+// PrivateKey is a signify secret key.
+type PrivateKey struct {
+	KeyNum  [KeyNumSize]byte
+	Key     ed25519.PrivateKey
+	Comment string
+}
+
+// Signature is a signify signature.
+type Signature struct {
+	KeyNum    [KeyNumSize]byte
+	Signature []byte
+	Comment   string
+}
+
+// GenerateKey generates a key pair with a random key number, using entropy
+// from rand, crypto/rand.Reader if nil. The comments are signify's defaults.
+func GenerateKey(rand io.Reader) (*PublicKey, *PrivateKey, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	_, key, err := ed25519.GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	var keyNum [KeyNumSize]byte
+	if _, err := io.ReadFull(rand, keyNum[:]); err != nil {
+		return nil, nil, err
+	}
+	priv := &PrivateKey{KeyNum: keyNum, Key: key, Comment: "signify secret key"}
+	return priv.Public(), priv, nil
+}
+
+// Public returns the public key of k.
+func (k *PrivateKey) Public() *PublicKey {
+	return &PublicKey{
+		KeyNum:  k.KeyNum,
+		Key:     append(ed25519.PublicKey(nil), k.Key[32:]...),
+		Comment: strings.Replace(k.Comment, "secret key", "public key", 1),
+	}
+}
+
+// encode returns the contents of a signify file.
+func encode(comment string, blob []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(commentPrefix)
+	buf.WriteString(comment)
+	buf.WriteByte('\n')
+	buf.WriteString(base64.StdEncoding.EncodeToString(blob))
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// decode parses the contents of a signify file holding a blob of size bytes
+// starting with the Ed algorithm tag. It returns the comment, the blob and
+// the data after it.
+func decode(data []byte, size int) (string, []byte, []byte, error) {
+	line, rest, ok := bytes.Cut(data, []byte("\n"))
+	if !ok || !bytes.HasPrefix(line, []byte(commentPrefix)) {
+		return "", nil, nil, ErrMalformed
+	}
+	comment := string(line[len(commentPrefix):])
+	if len(comment) > maxCommentLength {
+		return "", nil, nil, ErrMalformed
+	}
+	line, rest, ok = bytes.Cut(rest, []byte("\n"))
+	if !ok {
+		return "", nil, nil, ErrMalformed
+	}
+	blob, err := base64.StdEncoding.Strict().DecodeString(string(line))
+	if err != nil || len(blob) != size {
+		return "", nil, nil, ErrMalformed
+	}
+	if string(blob[:2]) != pkAlg {
+		return "", nil, nil, errors.New("signify: unsupported algorithm")
+	}
+	return comment, blob, rest, nil
+}
+
+func checkComment(comment string) error {
+	if len(comment) > maxCommentLength || strings.ContainsAny(comment, "\r\n") {
+		return errors.New("signify: invalid comment")
+	}
+	return nil
+}
+
+// Marshal returns the public key file contents.
+func (k *PublicKey) Marshal() ([]byte, error) {
+	if len(k.Key) != ed25519.PublicKeySize {
+		return nil, errors.New("signify: bad public key length")
+	}
+	if err := checkComment(k.Comment); err != nil {
+		return nil, err
+	}
+	blob := append([]byte(pkAlg), k.KeyNum[:]...)
+	return encode(k.Comment, append(blob, k.Key...)), nil
+}
+
+// ParsePublicKey parses a public key file.
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	comment, blob, rest, err := decode(data, publicKeySize)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	k := &PublicKey{Comment: comment, Key: append(ed25519.PublicKey(nil), blob[2+KeyNumSize:]...)}
+	copy(k.KeyNum[:], blob[2:])
+	return k, nil
+}
+
+// Marshal returns the contents of an unencrypted secret key file, as written
+// by signify -G -n.
+func (k *PrivateKey) Marshal() ([]byte, error) {
+	if len(k.Key) != ed25519.PrivateKeySize {
+		return nil, errors.New("signify: bad private key length")
+	}
+	if err := checkComment(k.Comment); err != nil {
+		return nil, err
+	}
+	checksum := sha512.Sum512(k.Key)
+	blob := make([]byte, 0, privateKeySize)
+	blob = append(blob, pkAlg...)
+	blob = append(blob, kdfAlg...)
+	blob = append(blob, 0, 0, 0, 0)          // kdf rounds: unencrypted
+	blob = append(blob, make([]byte, 16)...) // salt
+	blob = append(blob, checksum[:8]...)
+	blob = append(blob, k.KeyNum[:]...)
+	blob = append(blob, k.Key...)
+	return encode(k.Comment, blob), nil
+}
+
+// ParsePrivateKey parses an unencrypted secret key file and checks its
+// checksum and the consistency of its key halves.
+func ParsePrivateKey(data []byte) (*PrivateKey, error) {
+	comment, blob, rest, err := decode(data, privateKeySize)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	if string(blob[2:4]) != kdfAlg {
+		return nil, errors.New("signify: unsupported key derivation algorithm")
+	}
+	if binary.BigEndian.Uint32(blob[4:8]) != 0 {
+		return nil, ErrEncrypted
+	}
+	checksum, keyNum, key := blob[24:32], blob[32:40], blob[40:]
+	sum := sha512.Sum512(key)
+	if subtle.ConstantTimeCompare(sum[:8], checksum) != 1 {
+		return nil, ErrChecksum
+	}
+	k := &PrivateKey{Comment: comment, Key: ed25519.NewKeyFromSeed(key[:ed25519.SeedSize])}
+	if !bytes.Equal(k.Key, key) {
+		return nil, errors.New("signify: inconsistent secret key")
+	}
+	copy(k.KeyNum[:], keyNum)
+	return k, nil
+}
+
+// Marshal returns the signature file contents.
+func (s *Signature) Marshal() ([]byte, error) {
+	if len(s.Signature) != ed25519.SignatureSize {
+		return nil, errors.New("signify: bad signature length")
+	}
+	if err := checkComment(s.Comment); err != nil {
+		return nil, err
+	}
+	blob := append([]byte(pkAlg), s.KeyNum[:]...)
+	return encode(s.Comment, append(blob, s.Signature...)), nil
+}
+
+// ParseSignature parses a detached signature file.
+func ParseSignature(data []byte) (*Signature, error) {
+	comment, blob, rest, err := decode(data, signatureSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	s := &Signature{Comment: comment, Signature: append([]byte(nil), blob[2+KeyNumSize:]...)}
+	copy(s.KeyNum[:], blob[2:])
+	return s, nil
+}
+
+// Sign signs message. The signature comment is signify's default when the
+// secret key file name is unknown.
+func Sign(k *PrivateKey, message []byte) *Signature {
+	return &Signature{
+		KeyNum:    k.KeyNum,
+		Signature: ed25519.Sign(k.Key, message),
+		Comment:   "signature from " + k.Comment,
+	}
+}
+
+// SignReader signs the message read from r. Ed25519 hashes the message twice,
+// so it is read into memory.
+func SignReader(k *PrivateKey, r io.Reader) (*Signature, error) {
+	message, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Sign(k, message), nil
+}
+
+// Verify checks that sig is a signature of message by k.
+func Verify(k *PublicKey, message []byte, sig *Signature) error {
+	if k.KeyNum != sig.KeyNum {
+		return ErrKeyMismatch
+	}
+	if len(k.Key) != ed25519.PublicKeySize || !ed25519.Verify(k.Key, message, sig.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyReader checks sig over the message read from r.
+func VerifyReader(k *PublicKey, r io.Reader, sig *Signature) error {
+	message, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return Verify(k, message, sig)
+}
+
+// SignFile signs the file at path and writes the signature to path+".sig",
+// like signify -S -m path.
+func SignFile(k *PrivateKey, path string) error {
+	message, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data, err := Sign(k, message).Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".sig", data, 0o644)
+}
+
+// VerifyFile checks the file at path against the signature in path+".sig",
+// like signify -V -m path.
+func VerifyFile(k *PublicKey, path string) error {
+	sigData, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		return err
+	}
+	sig, err := ParseSignature(sigData)
+	if err != nil {
+		return err
+	}
+	message, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return Verify(k, message, sig)
+}
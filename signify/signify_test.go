@@ -0,0 +1,148 @@
+// Copyright 2019 Spacemesh Authors
+// OpenBSD signify key and signature files unit tests
+
+package signify
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 8032 section 7.1, test 1: the signature of the empty message.
+const (
+	testSeed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+	testSig  = "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+)
+
+var testKeyNum = [KeyNumSize]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func testKey(t *testing.T) *PrivateKey {
+	return &PrivateKey{
+		KeyNum:  testKeyNum,
+		Key:     ed25519.NewKeyFromSeed(mustHex(t, testSeed)),
+		Comment: "test secret key",
+	}
+}
+
+// file builds a signify file from its fields, following the layout of
+// signify.c rather than this package's encoder.
+func file(comment string, fields ...[]byte) []byte {
+	blob := bytes.Join(fields, nil)
+	return []byte("untrusted comment: " + comment + "\n" + base64.StdEncoding.EncodeToString(blob) + "\n")
+}
+
+func TestFileLayout(t *testing.T) {
+	k := testKey(t)
+	pub := k.Key[32:]
+	checksum := sha512.Sum512(k.Key)
+
+	wantPub := file("test public key", []byte("Ed"), testKeyNum[:], pub)
+	wantSec := file("test secret key", []byte("Ed"), []byte("BK"), make([]byte, 4), make([]byte, 16), checksum[:8], testKeyNum[:], k.Key)
+	wantSig := file("signature from test secret key", []byte("Ed"), testKeyNum[:], mustHex(t, testSig))
+
+	data, err := k.Public().Marshal()
+	require.NoError(t, err)
+	require.Equal(t, string(wantPub), string(data))
+	data, err = k.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, string(wantSec), string(data))
+	data, err = Sign(k, nil).Marshal()
+	require.NoError(t, err)
+	require.Equal(t, string(wantSig), string(data))
+
+	// public keys and signatures begin with "RWQ", the base64 of "Ed"
+	require.True(t, strings.HasPrefix(strings.Split(string(wantPub), "\n")[1], "RWQ"))
+
+	parsedPub, err := ParsePublicKey(wantPub)
+	require.NoError(t, err)
+	require.Equal(t, k.Public(), parsedPub)
+	parsedSec, err := ParsePrivateKey(wantSec)
+	require.NoError(t, err)
+	require.Equal(t, k, parsedSec)
+	parsedSig, err := ParseSignature(wantSig)
+	require.NoError(t, err)
+	require.NoError(t, Verify(parsedPub, nil, parsedSig))
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv, err := GenerateKey(nil)
+	require.NoError(t, err)
+	require.Equal(t, "signify public key", pub.Comment)
+
+	message := []byte("SHA256 (base.tgz) = ...\n")
+	sig, err := SignReader(priv, bytes.NewReader(message))
+	require.NoError(t, err)
+	require.NoError(t, VerifyReader(pub, bytes.NewReader(message), sig))
+	require.ErrorIs(t, Verify(pub, append(message, 'x'), sig), ErrInvalidSignature)
+
+	other, _, err := GenerateKey(nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, Verify(other, message, sig), ErrKeyMismatch)
+	other.KeyNum = pub.KeyNum
+	require.ErrorIs(t, Verify(other, message, sig), ErrInvalidSignature)
+}
+
+func TestFiles(t *testing.T) {
+	k := testKey(t)
+	path := filepath.Join(t.TempDir(), "SHA256")
+	require.NoError(t, os.WriteFile(path, []byte("SHA256 (bsd.rd) = 00\n"), 0o644))
+	require.NoError(t, SignFile(k, path))
+	require.NoError(t, VerifyFile(k.Public(), path))
+
+	require.NoError(t, os.WriteFile(path, []byte("SHA256 (bsd.rd) = 01\n"), 0o644))
+	require.ErrorIs(t, VerifyFile(k.Public(), path), ErrInvalidSignature)
+}
+
+func TestParseErrors(t *testing.T) {
+	k := testKey(t)
+	sec, err := k.Marshal()
+	require.NoError(t, err)
+	blob, err := base64.StdEncoding.DecodeString(strings.Split(string(sec), "\n")[1])
+	require.NoError(t, err)
+
+	encrypted := append([]byte(nil), blob...)
+	encrypted[7] = 42
+	_, err = ParsePrivateKey(file("c", encrypted))
+	require.ErrorIs(t, err, ErrEncrypted)
+
+	corrupt := append([]byte(nil), blob...)
+	corrupt[len(corrupt)-40] ^= 1
+	_, err = ParsePrivateKey(file("c", corrupt))
+	require.ErrorIs(t, err, ErrChecksum)
+
+	for _, data := range []string{
+		"",
+		"comment: x\nRWQ=\n",
+		"untrusted comment: x\n",
+		"untrusted comment: x\nRWQBAgMEBQYHCA==\n",
+		"untrusted comment: x\n" + base64.StdEncoding.EncodeToString(make([]byte, 42)) + "\n",
+		"untrusted comment: " + strings.Repeat("x", 1025) + "\n",
+	} {
+		_, err := ParsePublicKey([]byte(data))
+		require.Error(t, err, data)
+	}
+
+	pub, err := k.Public().Marshal()
+	require.NoError(t, err)
+	_, err = ParsePublicKey(append(pub, "trailing\n"...))
+	require.ErrorIs(t, err, ErrMalformed)
+
+	k.Comment = "two\nlines"
+	_, err = k.Marshal()
+	require.Error(t, err)
+}
@@ -0,0 +1,298 @@
+// Copyright 2019 Spacemesh Authors
+// OpenSSH allowed_signers files
+
+package sshsig
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/sshwire"
+)
+
+// ErrNoAllowedSigner is returned when no allowed signer matches a signature.
+var ErrNoAllowedSigner = errors.New("sshsig: no matching allowed signer")
+
+// AllowedSigner is an ssh-ed25519 line of an allowed_signers file.
+type AllowedSigner struct {
+	// Principals are the principal patterns of the line. Patterns may use
+	// the wildcards '*' and '?' and be negated with a leading '!'.
+	Principals []string
+	PublicKey  ed25519.PublicKey
+	// Namespaces restricts the namespaces of signatures if not empty.
+	// Entries are patterns like Principals.
+	Namespaces []string
+	// CertAuthority marks keys trusted to certify other keys. Certificates
+	// are not supported, so such lines never match a signature.
+	CertAuthority bool
+	// ValidAfter and ValidBefore bound the validity of the key if not zero.
+	ValidAfter, ValidBefore time.Time
+	Comment                 string
+}
+
+// AllowedSigners is a parsed allowed_signers file.
+type AllowedSigners []AllowedSigner
+
+// ParseAllowedSigners parses an allowed_signers file, in the format described
+// in ssh-keygen(1). Lines with keys of other types than ssh-ed25519 are
+// skipped.
+func ParseAllowedSigners(r io.Reader) (AllowedSigners, error) {
+	var signers AllowedSigners
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		signer, ok, err := parseAllowedSigner(line)
+		if err != nil {
+			return nil, fmt.Errorf("sshsig: allowed signers line %d: %w", lineNum, err)
+		}
+		if ok {
+			signers = append(signers, signer)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return signers, nil
+}
+
+// nextField splits the first whitespace separated field off line. Quoted
+// sections may contain whitespace; the quotes are kept.
+func nextField(line string) (field, rest string, err error) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ' ' || c == '\t'):
+			return line[:i], strings.TrimLeft(line[i:], " \t"), nil
+		}
+	}
+	if quoted {
+		return "", "", errors.New("unterminated quote")
+	}
+	return line, "", nil
+}
+
+// isKeyType reports whether field names an SSH public key type, which tells
+// the key apart from a preceding options field.
+func isKeyType(field string) bool {
+	return strings.HasPrefix(field, "ssh-") || strings.HasPrefix(field, "ecdsa-") ||
+		strings.HasPrefix(field, "sk-") || strings.HasPrefix(field, "rsa-")
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func parseAllowedSigner(line string) (AllowedSigner, bool, error) {
+	var signer AllowedSigner
+	principals, rest, err := nextField(line)
+	if err != nil {
+		return signer, false, err
+	}
+	signer.Principals = strings.Split(unquote(principals), ",")
+
+	field, rest, err := nextField(rest)
+	if err != nil {
+		return signer, false, err
+	}
+	if field != "" && !isKeyType(field) {
+		if err := signer.parseOptions(field); err != nil {
+			return signer, false, err
+		}
+		if field, rest, err = nextField(rest); err != nil {
+			return signer, false, err
+		}
+	}
+	keyType := field
+	keyData, comment, err := nextField(rest)
+	if err != nil {
+		return signer, false, err
+	}
+	if keyType == "" || keyData == "" {
+		return signer, false, errors.New("missing key")
+	}
+	if keyType != sshwire.KeyAlgoED25519 {
+		return signer, false, nil
+	}
+	blob, err := base64.StdEncoding.DecodeString(keyData)
+	if err != nil {
+		return signer, false, err
+	}
+	pub, ok := sshwire.ParseED25519PublicKey(blob)
+	if !ok {
+		return signer, false, errors.New("malformed ssh-ed25519 key")
+	}
+	signer.PublicKey = append(ed25519.PublicKey(nil), pub...)
+	signer.Comment = comment
+	return signer, true, nil
+}
+
+// parseOptions parses a comma separated options field.
+func (a *AllowedSigner) parseOptions(field string) error {
+	for field != "" {
+		// split off the next option at a comma outside quotes
+		end, quoted := len(field), false
+		for i := 0; i < len(field); i++ {
+			if field[i] == '"' {
+				quoted = !quoted
+			} else if field[i] == ',' && !quoted {
+				end = i
+				break
+			}
+		}
+		opt := field[:end]
+		field = strings.TrimPrefix(field[end:], ",")
+
+		name, value, hasValue := strings.Cut(opt, "=")
+		value = unquote(value)
+		var err error
+		switch strings.ToLower(name) {
+		case "cert-authority":
+			if hasValue {
+				return errors.New("cert-authority takes no value")
+			}
+			a.CertAuthority = true
+		case "namespaces":
+			if !hasValue {
+				return errors.New("namespaces needs a value")
+			}
+			a.Namespaces = strings.Split(value, ",")
+		case "valid-after":
+			a.ValidAfter, err = parseTime(value)
+		case "valid-before":
+			a.ValidBefore, err = parseTime(value)
+		default:
+			return fmt.Errorf("unsupported option %q", name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTime parses a YYYYMMDD[HHMM[SS]] time, in UTC with a trailing 'Z' and
+// in local time otherwise.
+func parseTime(s string) (time.Time, error) {
+	loc := time.Local
+	if strings.HasSuffix(s, "Z") || strings.HasSuffix(s, "z") {
+		s, loc = s[:len(s)-1], time.UTC
+	}
+	var layout string
+	switch len(s) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return time.ParseInLocation(layout, s, loc)
+}
+
+// matchPattern reports whether s matches a pattern with the wildcards '*'
+// and '?'.
+func matchPattern(s, pattern string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = pattern[1:]
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(s[i:], pattern) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		s, pattern = s[1:], pattern[1:]
+	}
+	return s == ""
+}
+
+// matchPatternList reports whether s matches a positive pattern of patterns
+// and no negated one.
+func matchPatternList(s string, patterns []string) bool {
+	matched := false
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			if matchPattern(s, p[1:]) {
+				return false
+			}
+		} else if matchPattern(s, p) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// accepts reports whether the line allows signatures by publicKey in
+// namespace at time t.
+func (a *AllowedSigner) accepts(publicKey ed25519.PublicKey, namespace string, t time.Time) bool {
+	if a.CertAuthority || !bytes.Equal(a.PublicKey, publicKey) {
+		return false
+	}
+	if len(a.Namespaces) > 0 && !matchPatternList(namespace, a.Namespaces) {
+		return false
+	}
+	if !a.ValidAfter.IsZero() && t.Before(a.ValidAfter) {
+		return false
+	}
+	if !a.ValidBefore.IsZero() && t.After(a.ValidBefore) {
+		return false
+	}
+	return true
+}
+
+// FindPrincipals returns the principal patterns, comma separated, of the
+// lines that allow signatures by publicKey in namespace at time t.
+func (s AllowedSigners) FindPrincipals(publicKey ed25519.PublicKey, namespace string, t time.Time) []string {
+	var principals []string
+	for i := range s {
+		if s[i].accepts(publicKey, namespace, t) {
+			principals = append(principals, strings.Join(s[i].Principals, ","))
+		}
+	}
+	return principals
+}
+
+// Verify verifies sig over the message read from r, like ssh-keygen -Y
+// verify: the signature must be valid for namespace and a line of s must
+// allow its key for principal at time t.
+func (s AllowedSigners) Verify(sig *Signature, r io.Reader, principal, namespace string, t time.Time) error {
+	if err := sig.Verify(r, namespace); err != nil {
+		return err
+	}
+	for i := range s {
+		if matchPatternList(principal, s[i].Principals) && s[i].accepts(sig.PublicKey, namespace, t) {
+			return nil
+		}
+	}
+	return ErrNoAllowedSigner
+}
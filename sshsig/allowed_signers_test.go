@@ -0,0 +1,90 @@
+// Copyright 2019 Spacemesh Authors
+// OpenSSH allowed_signers files unit tests
+
+package sshsig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowedSigners(t *testing.T) {
+	signers, err := ParseAllowedSigners(bytes.NewReader(readFile(t, "allowed_signers")))
+	require.NoError(t, err)
+	require.Len(t, signers, 5) // the ssh-rsa line is skipped
+
+	require.Equal(t, []string{"test@spacemesh"}, signers[0].Principals)
+	require.Equal(t, []string{"file", "git"}, signers[0].Namespaces)
+	require.Equal(t, "test key", signers[0].Comment)
+	require.Equal(t, testKey(t).Public(), signers[0].PublicKey)
+
+	require.Equal(t, []string{"*@example.com", "!mallory@example.com"}, signers[1].Principals)
+	require.Equal(t, []string{"release bot"}, signers[2].Principals)
+	require.True(t, signers[2].CertAuthority)
+
+	require.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local), signers[3].ValidBefore)
+	require.Equal(t, time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC), signers[4].ValidAfter)
+	require.Equal(t, []string{"file"}, signers[4].Namespaces)
+}
+
+// TestAllowedSignersVerify mirrors the results of ssh-keygen -Y verify and
+// -Y find-principals on the fixtures.
+func TestAllowedSignersVerify(t *testing.T) {
+	signers, err := ParseAllowedSigners(bytes.NewReader(readFile(t, "allowed_signers")))
+	require.NoError(t, err)
+	message := readFile(t, "message.txt")
+	sig, err := ParseArmored(readFile(t, "message.txt.sha512.sig"))
+	require.NoError(t, err)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, signers.Verify(sig, bytes.NewReader(message), "test@spacemesh", "file", now))
+	for _, principal := range []string{"expired@spacemesh", "future@spacemesh", "nobody"} {
+		err := signers.Verify(sig, bytes.NewReader(message), principal, "file", now)
+		require.ErrorIs(t, err, ErrNoAllowedSigner, principal)
+	}
+	// the expired key was valid in 2019
+	past := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, signers.Verify(sig, bytes.NewReader(message), "expired@spacemesh", "file", past))
+
+	require.Equal(t, []string{"test@spacemesh"}, signers.FindPrincipals(sig.PublicKey, "file", now))
+	require.Empty(t, signers.FindPrincipals(sig.PublicKey, "email", now))
+
+	sig256, err := ParseArmored(readFile(t, "message.txt.sha256.sig"))
+	require.NoError(t, err)
+	require.NoError(t, signers.Verify(sig256, bytes.NewReader(message), "test@spacemesh", "git", now))
+}
+
+func TestPrincipalPatterns(t *testing.T) {
+	patterns := []string{"*@example.com", "!mallory@example.com", "ops-?"}
+	for s, want := range map[string]bool{
+		"alice@example.com":   true,
+		"@example.com":        true,
+		"mallory@example.com": false,
+		"alice@example.org":   false,
+		"ops-1":               true,
+		"ops-12":              false,
+		"":                    false,
+	} {
+		require.Equal(t, want, matchPatternList(s, patterns), s)
+	}
+}
+
+func TestParseAllowedSignersErrors(t *testing.T) {
+	key := strings.TrimSpace(string(readFile(t, "id_ed25519.pub")))
+	for _, line := range []string{
+		"a",
+		"a unknown-option " + key,
+		`a namespaces="file ` + key,
+		"a valid-after=2020 " + key,
+		"a cert-authority=yes " + key,
+		"a ssh-ed25519 !!!",
+		"a ssh-ed25519 AAAAC3NzaC1lZDI1NTE5",
+	} {
+		_, err := ParseAllowedSigners(strings.NewReader(line))
+		require.Error(t, err, line)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2019 Spacemesh Authors
+// OpenSSH SSHSIG signatures
+
+// Package sshsig implements the SSHSIG signature format of OpenSSH
+// (PROTOCOL.sshsig), as produced by ssh-keygen -Y sign, for ssh-ed25519 keys,
+// and the allowed_signers files used by ssh-keygen -Y verify.
+//
+// A signature covers a hash of the message together with a namespace, such
+// as "file" or "git", that prevents signatures from being reused across
+// applications.
+package sshsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/spacemeshos/ed25519"
+	"github.com/spacemeshos/ed25519/internal/sshwire"
+)
+
+const (
+	// HashSHA256 selects SHA-256 message hashing.
+	HashSHA256 = "sha256"
+	// HashSHA512 selects SHA-512 message hashing, the ssh-keygen default.
+	HashSHA512 = "sha512"
+
+	// PEMType is the armor type of SSHSIG signatures.
+	PEMType = "SSH SIGNATURE"
+
+	magic   = "SSHSIG"
+	version = 1
+
+	// armorWidth is the line width of ssh-keygen's base64 armor.
+	armorWidth = 70
+)
+
+var (
+	// ErrMalformed is returned for signatures that cannot be parsed.
+	ErrMalformed = errors.New("sshsig: malformed signature")
+	// ErrUnsupportedKey is returned for keys other than ssh-ed25519.
+	ErrUnsupportedKey = errors.New("sshsig: unsupported key type")
+	// ErrUnsupportedHash is returned for hash algorithms other than sha256
+	// and sha512.
+	ErrUnsupportedHash = errors.New("sshsig: unsupported hash algorithm")
+	// ErrNamespaceMismatch is returned when a signature was made for another
+	// namespace.
+	ErrNamespaceMismatch = errors.New("sshsig: namespace mismatch")
+	// ErrInvalidSignature is returned when a signature does not verify.
+	ErrInvalidSignature = errors.New("sshsig: invalid signature")
+)
+
+// Signature is a decoded SSHSIG signature.
+type Signature struct {
+	PublicKey     ed25519.PublicKey
+	Namespace     string
+	HashAlgorithm string
+	// Signature is the raw Ed25519 signature.
+	Signature []byte
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	default:
+		return nil, ErrUnsupportedHash
+	}
+}
+
+// hashMessage returns the hash of the message read from r.
+func hashMessage(algorithm string, r io.Reader) ([]byte, error) {
+	h, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// signedData returns the blob that is signed for a message hash.
+func signedData(namespace, algorithm string, digest []byte) []byte {
+	b := []byte(magic)
+	b = sshwire.AppendString(b, []byte(namespace))
+	b = sshwire.AppendString(b, nil) // reserved
+	b = sshwire.AppendString(b, []byte(algorithm))
+	return sshwire.AppendString(b, digest)
+}
+
+// Sign signs the message read from r for namespace, hashing it with
+// algorithm, HashSHA512 if empty.
+func Sign(privateKey ed25519.PrivateKey, r io.Reader, namespace, algorithm string) (*Signature, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errors.New("sshsig: bad private key length")
+	}
+	if namespace == "" {
+		return nil, errors.New("sshsig: empty namespace")
+	}
+	if algorithm == "" {
+		algorithm = HashSHA512
+	}
+	digest, err := hashMessage(algorithm, r)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{
+		PublicKey:     append(ed25519.PublicKey(nil), privateKey[32:]...),
+		Namespace:     namespace,
+		HashAlgorithm: algorithm,
+		Signature:     ed25519.Sign(privateKey, signedData(namespace, algorithm, digest)),
+	}, nil
+}
+
+// SignMessage signs message like Sign.
+func SignMessage(privateKey ed25519.PrivateKey, message []byte, namespace, algorithm string) (*Signature, error) {
+	return Sign(privateKey, bytes.NewReader(message), namespace, algorithm)
+}
+
+// Verify verifies the signature of the message read from r. The signature
+// must have been made for namespace. Verify does not check that the signer
+// is trusted; see AllowedSigners.
+func (s *Signature) Verify(r io.Reader, namespace string) error {
+	if s.Namespace != namespace {
+		return ErrNamespaceMismatch
+	}
+	if len(s.PublicKey) != ed25519.PublicKeySize || len(s.Signature) != ed25519.SignatureSize {
+		return ErrInvalidSignature
+	}
+	digest, err := hashMessage(s.HashAlgorithm, r)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(s.PublicKey, signedData(s.Namespace, s.HashAlgorithm, digest), s.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyMessage verifies the signature of message like Verify.
+func (s *Signature) VerifyMessage(message []byte, namespace string) error {
+	return s.Verify(bytes.NewReader(message), namespace)
+}
+
+// Marshal returns the binary SSHSIG encoding of the signature.
+func (s *Signature) Marshal() []byte {
+	sig := sshwire.AppendString(nil, []byte(sshwire.KeyAlgoED25519))
+	sig = sshwire.AppendString(sig, s.Signature)
+
+	b := []byte(magic)
+	b = sshwire.AppendUint32(b, version)
+	b = sshwire.AppendString(b, sshwire.MarshalED25519PublicKey(s.PublicKey))
+	b = sshwire.AppendString(b, []byte(s.Namespace))
+	b = sshwire.AppendString(b, nil) // reserved
+	b = sshwire.AppendString(b, []byte(s.HashAlgorithm))
+	return sshwire.AppendString(b, sig)
+}
+
+// Armor returns the signature in the armored form written by ssh-keygen.
+func (s *Signature) Armor() []byte {
+	enc := base64.StdEncoding.EncodeToString(s.Marshal())
+	var buf bytes.Buffer
+	buf.WriteString("-----BEGIN " + PEMType + "-----\n")
+	for len(enc) > armorWidth {
+		buf.WriteString(enc[:armorWidth])
+		buf.WriteByte('\n')
+		enc = enc[armorWidth:]
+	}
+	buf.WriteString(enc)
+	buf.WriteString("\n-----END " + PEMType + "-----\n")
+	return buf.Bytes()
+}
+
+// Parse parses a binary SSHSIG signature.
+func Parse(blob []byte) (*Signature, error) {
+	if !bytes.HasPrefix(blob, []byte(magic)) {
+		return nil, ErrMalformed
+	}
+	r := sshwire.NewReader(blob[len(magic):])
+	v := r.Uint32()
+	publicKey := r.String()
+	namespace := r.String()
+	_ = r.String() // reserved
+	algorithm := r.String()
+	sig := r.String()
+	if !r.Empty() {
+		return nil, ErrMalformed
+	}
+	if v != version {
+		return nil, errors.New("sshsig: unsupported version")
+	}
+
+	pub, ok := sshwire.ParseED25519PublicKey(publicKey)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	sr := sshwire.NewReader(sig)
+	sigAlgo := sr.String()
+	rawSig := sr.String()
+	if !sr.Empty() {
+		return nil, ErrMalformed
+	}
+	if string(sigAlgo) != sshwire.KeyAlgoED25519 {
+		return nil, ErrUnsupportedKey
+	}
+	if len(rawSig) != ed25519.SignatureSize {
+		return nil, ErrMalformed
+	}
+	if _, err := newHash(string(algorithm)); err != nil {
+		return nil, err
+	}
+	return &Signature{
+		PublicKey:     append(ed25519.PublicKey(nil), pub...),
+		Namespace:     string(namespace),
+		HashAlgorithm: string(algorithm),
+		Signature:     append([]byte(nil), rawSig...),
+	}, nil
+}
+
+// ParseArmored parses an armored signature as written by ssh-keygen.
+func ParseArmored(data []byte) (*Signature, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEMType {
+		return nil, ErrMalformed
+	}
+	return Parse(block.Bytes)
+}